@@ -0,0 +1,221 @@
+// Command keyctl manages API keys. Keys are only ever stored as hashes, so a
+// new key is printed once when it is created and cannot be shown again.
+//
+// Usage:
+//
+//	keyctl generate
+//	keyctl hash < key
+//	keyctl create --id ID --scopes SCOPES [--name NAME] [--expires DURATION] [--database PATH]
+//	keyctl list   [--database PATH]
+//	keyctl revoke [--database PATH] ID
+//
+// generate and hash serve the file store: paste the printed hash into the
+// keys file. create, list and revoke manage the database of the sqlite
+// store, auth.databasePath of the server's config file (CONFIG_FILE) and
+// environment unless --database is given.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/apikeys"
+	"github.com/jilani-go/glofox/internal/config"
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/repository"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	switch command {
+	case "generate":
+		key, err := apikeys.Generate()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("key:  %s\nhash: %s\n", key, models.HashAPIKey(key))
+
+	case "hash":
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if key := strings.TrimSpace(scanner.Text()); key != "" {
+				fmt.Println(models.HashAPIKey(key))
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Fatalf("Failed to read keys: %v", err)
+		}
+
+	case "create":
+		flags := flag.NewFlagSet("create", flag.ExitOnError)
+		id := flags.String("id", "", "key ID, used to revoke the key")
+		name := flags.String("name", "", "description of the key's owner")
+		scopes := flags.String("scopes", "", "comma separated scopes: "+scopeList())
+		expires := flags.Duration("expires", 0, "lifetime of the key, 0 never expires")
+		database := flags.String("database", "", "SQLite API key database path")
+		flags.Parse(args)
+
+		key, err := newKey(*id, *name, *scopes, *expires)
+		if err != nil {
+			log.Fatal(err)
+		}
+		secret, err := apikeys.Generate()
+		if err != nil {
+			log.Fatal(err)
+		}
+		key.Hash = models.HashAPIKey(secret)
+
+		repo := openRepository(*database)
+		defer repo.Close()
+		if _, err := repo.Create(context.Background(), key); err != nil {
+			log.Fatalf("Failed to create API key: %v", err)
+		}
+		fmt.Printf("Created API key %s, it will not be shown again:\n%s\n", key.ID, secret)
+
+	case "list":
+		flags := flag.NewFlagSet("list", flag.ExitOnError)
+		database := flags.String("database", "", "SQLite API key database path")
+		flags.Parse(args)
+
+		repo := openRepository(*database)
+		defer repo.Close()
+		keys, err := repo.FindAll(context.Background())
+		if err != nil {
+			log.Fatal(err)
+		}
+		printKeys(keys)
+
+	case "revoke":
+		flags := flag.NewFlagSet("revoke", flag.ExitOnError)
+		database := flags.String("database", "", "SQLite API key database path")
+		flags.Parse(args)
+		if flags.NArg() != 1 {
+			usage()
+		}
+
+		repo := openRepository(*database)
+		defer repo.Close()
+		revoked, err := repo.Revoke(context.Background(), flags.Arg(0), time.Now().UTC())
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !revoked {
+			log.Fatalf("No API key with ID %q", flags.Arg(0))
+		}
+		fmt.Printf("Revoked API key %s\n", flags.Arg(0))
+
+	default:
+		usage()
+	}
+}
+
+// usage prints the commands and exits
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: keyctl generate|hash|create|list|revoke [flags]")
+	fmt.Fprintln(os.Stderr, "  generate  print a new random key and its hash")
+	fmt.Fprintln(os.Stderr, "  hash      print the hash of every key read from stdin")
+	fmt.Fprintln(os.Stderr, "  create    add a new key to the database and print it")
+	fmt.Fprintln(os.Stderr, "  list      list the keys in the database")
+	fmt.Fprintln(os.Stderr, "  revoke    revoke the key with the given ID")
+	os.Exit(2)
+}
+
+// newKey validates the create flags
+func newKey(id, name, scopes string, expires time.Duration) (*models.APIKey, error) {
+	var errs []error
+	id = strings.TrimSpace(id)
+	if id == "" {
+		errs = append(errs, errors.New("--id: must not be empty"))
+	}
+	if expires < 0 {
+		errs = append(errs, errors.New("--expires: must not be negative"))
+	}
+
+	key := &models.APIKey{ID: id, Name: strings.TrimSpace(name)}
+	for _, scope := range strings.Split(scopes, ",") {
+		scope := models.Scope(strings.TrimSpace(scope))
+		if scope == "" {
+			continue
+		}
+		if !models.ValidScope(scope) {
+			errs = append(errs, fmt.Errorf("--scopes: unknown scope %q, must be one of %s", scope, scopeList()))
+		}
+		key.Scopes = append(key.Scopes, scope)
+	}
+	if len(key.Scopes) == 0 {
+		errs = append(errs, errors.New("--scopes: must grant at least one scope"))
+	}
+
+	key.CreatedAt = time.Now().UTC()
+	if expires > 0 {
+		key.ExpiresAt = key.CreatedAt.Add(expires)
+	}
+	return key, errors.Join(errs...)
+}
+
+// openRepository opens the API key database, by default the server's
+func openRepository(database string) *repository.SQLiteAPIKeyRepository {
+	if database == "" {
+		cfg, _, err := config.Load(nil)
+		if err != nil {
+			log.Fatalf("Failed to load configuration: %v", err)
+		}
+		database = cfg.Auth.DatabasePath
+	}
+
+	repo, err := repository.NewSQLiteAPIKeyRepository(repository.SQLiteAPIKeyConfig{
+		DatabasePath: database,
+	})
+	if err != nil {
+		log.Fatalf("Failed to open API key database: %v", err)
+	}
+	return repo
+}
+
+// printKeys prints the keys and their state
+func printKeys(keys []models.APIKey) {
+	now := time.Now()
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tNAME\tSCOPES\tCREATED\tEXPIRES\tSTATE\t")
+	for _, key := range keys {
+		scopes := make([]string, len(key.Scopes))
+		for i, scope := range key.Scopes {
+			scopes[i] = string(scope)
+		}
+		expires := "never"
+		if !key.ExpiresAt.IsZero() {
+			expires = key.ExpiresAt.Format(time.RFC3339)
+		}
+		state := "active"
+		switch {
+		case key.Revoked(now):
+			state = "revoked"
+		case key.Expired(now):
+			state = "expired"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t\n", key.ID, key.Name, strings.Join(scopes, ","),
+			key.CreatedAt.Format(time.RFC3339), expires, state)
+	}
+	writer.Flush()
+}
+
+// scopeList lists the known scopes for messages
+func scopeList() string {
+	names := make([]string, len(models.Scopes))
+	for i, scope := range models.Scopes {
+		names[i] = string(scope)
+	}
+	return strings.Join(names, ", ")
+}
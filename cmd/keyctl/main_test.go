@@ -0,0 +1,85 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+func TestNewKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         string
+		keyName    string
+		scopes     string
+		expires    time.Duration
+		wantScopes []models.Scope
+		wantExpiry bool
+		// wantErrs lists the flags the error must mention
+		wantErrs []string
+	}{
+		{
+			name:       "one scope",
+			id:         "checkout",
+			scopes:     "orders:write",
+			wantScopes: []models.Scope{models.ScopeOrdersWrite},
+		},
+		{
+			name:       "scopes with spaces and an expiry",
+			id:         " ops ",
+			keyName:    " Operations ",
+			scopes:     "catalog:admin, promo:admin,",
+			expires:    24 * time.Hour,
+			wantScopes: []models.Scope{models.ScopeCatalogAdmin, models.ScopePromoAdmin},
+			wantExpiry: true,
+		},
+		{
+			name:     "unknown scope",
+			id:       "checkout",
+			scopes:   "orders:delete",
+			wantErrs: []string{`--scopes: unknown scope "orders:delete"`},
+		},
+		{
+			name:     "everything missing",
+			scopes:   " , ",
+			expires:  -time.Hour,
+			wantErrs: []string{"--id", "--expires", "--scopes: must grant"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := newKey(tt.id, tt.keyName, tt.scopes, tt.expires)
+			if tt.wantErrs != nil {
+				if err == nil {
+					t.Fatal("newKey() succeeded, want an error")
+				}
+				for _, want := range tt.wantErrs {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("newKey() error = %v, want it to mention %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newKey() unexpected error: %v", err)
+			}
+
+			if key.ID != strings.TrimSpace(tt.id) || key.Name != strings.TrimSpace(tt.keyName) {
+				t.Errorf("newKey() = ID %q name %q, want them trimmed", key.ID, key.Name)
+			}
+			if !slices.Equal(key.Scopes, tt.wantScopes) {
+				t.Errorf("newKey() scopes = %v, want %v", key.Scopes, tt.wantScopes)
+			}
+			if key.ExpiresAt.IsZero() == tt.wantExpiry {
+				t.Errorf("newKey() expires at %v, want expiry %v", key.ExpiresAt, tt.wantExpiry)
+			}
+			if tt.wantExpiry && !key.ExpiresAt.Equal(key.CreatedAt.Add(tt.expires)) {
+				t.Errorf("newKey() expires at %v, want %v after %v", key.ExpiresAt, tt.expires, key.CreatedAt)
+			}
+		})
+	}
+}
@@ -2,64 +2,301 @@ package main
 
 import (
 	"context"
-	"log"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
-	"runtime"
 	"syscall"
 	"time"
 
 	"github.com/jilani-go/glofox/internal/api"
+	"github.com/jilani-go/glofox/internal/apikeys"
+	"github.com/jilani-go/glofox/internal/campaign"
+	"github.com/jilani-go/glofox/internal/catalog"
 	"github.com/jilani-go/glofox/internal/config"
 	"github.com/jilani-go/glofox/internal/handlers"
+	"github.com/jilani-go/glofox/internal/logging"
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/promotion"
 	"github.com/jilani-go/glofox/internal/repository"
 	"github.com/jilani-go/glofox/internal/services"
+	"github.com/jilani-go/glofox/internal/tracing"
 )
 
+// errCatalogLoading fails the readiness check until the catalog file is loaded
+var errCatalogLoading = errors.New("catalog is still loading")
+
 func main() {
-	// Load configuration
-	cfg := config.Load()
+	// Load configuration: defaults < config file < environment < flags
+	cfg, options, err := config.Load(os.Args[1:])
+	if err != nil {
+		fatal("Failed to load configuration", err)
+	}
 
-	// Create repositories
-	productRepo := repository.NewInMemoryProductRepository()
-	orderRepo := repository.NewInMemoryOrderRepository(productRepo)
-
-	// Create SQLite promo repository with optimized configuration
-	promoRepo, err := repository.NewSQLitePromoRepository(repository.SQLitePromoConfig{
-		DatabasePath:  "data/promo_codes.db",
-		BatchSize:     50000, // Insert 50k records per transaction
-		WorkerCount:   runtime.NumCPU(),
-		CreateIndexes: true, // Create indexes for faster lookups
+	// Print the effective configuration and exit if requested
+	if options.PrintConfig {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(cfg.Redacted()); err != nil {
+			fatal("Failed to print configuration", err)
+		}
+		return
+	}
+
+	// Log at the configured level and format from here on
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fatal("Failed to configure logging", err)
+	}
+	slog.SetDefault(logger)
+
+	// Trace requests through the handlers, services and repositories
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		FilePath:    cfg.Tracing.FilePath,
+		SampleRatio: cfg.Tracing.SampleRatio,
 	})
 	if err != nil {
-		log.Fatalf("Failed to initialize promo repository: %v", err)
+		fatal("Failed to configure tracing", err)
+	}
+
+	// Resources to release on shutdown
+	var closers []io.Closer
+	// Checks that must pass before the server reports ready
+	var checks []services.HealthCheck
+
+	// Create repositories
+	var productRepo repository.ProductRepository
+	var orderRepo repository.OrderRepository
+	var idempotencyRepo repository.IdempotencyRepository
+	switch cfg.Storage.Backend {
+	case config.BackendMemory:
+		// Seed the default catalog unless a catalog file is configured
+		productRepo = repository.NewInMemoryProductRepository(cfg.Catalog.SeedFile == "")
+		orderRepo = repository.NewInMemoryOrderRepository(productRepo)
+		idempotencyRepo = repository.NewInMemoryIdempotencyRepository()
+
+	case config.BackendSQLite:
+		// Create SQLite product repository, seeded with the default catalog
+		// on first start unless a catalog file is configured
+		sqliteProductRepo, err := repository.NewSQLiteProductRepository(repository.SQLiteProductConfig{
+			DatabasePath: cfg.Storage.ProductDatabasePath,
+			QueryTimeout: time.Duration(cfg.Storage.QueryTimeout),
+			Seed:         cfg.Catalog.SeedFile == "",
+		})
+		if err != nil {
+			fatal("Failed to initialize product repository", err)
+		}
+		closers = append(closers, sqliteProductRepo)
+		checks = append(checks, services.HealthCheck{Name: "product_database", Check: sqliteProductRepo.Ping})
+		productRepo = sqliteProductRepo
+
+		// Create SQLite order repository so orders survive restarts
+		sqliteOrderRepo, err := repository.NewSQLiteOrderRepository(repository.SQLiteOrderConfig{
+			DatabasePath: cfg.Storage.OrderDatabasePath,
+			QueryTimeout: time.Duration(cfg.Storage.QueryTimeout),
+		})
+		if err != nil {
+			fatal("Failed to initialize order repository", err)
+		}
+		closers = append(closers, sqliteOrderRepo)
+		checks = append(checks, services.HealthCheck{Name: "order_database", Check: sqliteOrderRepo.Ping})
+		orderRepo = sqliteOrderRepo
+
+		// Keep idempotency keys next to the orders they protect
+		sqliteIdempotencyRepo, err := repository.NewSQLiteIdempotencyRepository(repository.SQLiteIdempotencyConfig{
+			DatabasePath: cfg.Storage.OrderDatabasePath,
+			QueryTimeout: time.Duration(cfg.Storage.QueryTimeout),
+		})
+		if err != nil {
+			fatal("Failed to initialize idempotency repository", err)
+		}
+		closers = append(closers, sqliteIdempotencyRepo)
+		checks = append(checks, services.HealthCheck{Name: "idempotency_database", Check: sqliteIdempotencyRepo.Ping})
+		idempotencyRepo = sqliteIdempotencyRepo
+	}
+
+	// Load the promotions, falling back to the built-in ones
+	promotions := repository.DefaultPromotions()
+	if cfg.Promo.PromotionsFile != "" {
+		promotions, err = promotion.Load(cfg.Promo.PromotionsFile)
+		if err != nil {
+			fatal("Failed to load promotions", err)
+		}
+		slog.Info("Loaded promotions", "count", len(promotions), "file", cfg.Promo.PromotionsFile)
+	}
+	promotionRepo := repository.NewInMemoryPromotionRepository(promotions)
+
+	// Load the promo campaigns, if any
+	var campaigns []models.Campaign
+	if cfg.Promo.CampaignsFile != "" {
+		campaigns, err = campaign.Load(cfg.Promo.CampaignsFile)
+		if err != nil {
+			fatal("Failed to load promo campaigns", err)
+		}
+		slog.Info("Loaded promo campaigns", "count", len(campaigns), "file", cfg.Promo.CampaignsFile)
+	}
+	campaignRepo, err := repository.NewInMemoryCampaignRepository(campaigns)
+	if err != nil {
+		fatal("Failed to initialize campaign repository", err)
 	}
 
+	// Create the API key repository for the configured store
+	var apiKeyRepo repository.APIKeyRepository
+	switch cfg.Auth.Store {
+	case config.AuthStoreConfig:
+		if cfg.Auth.APIKey == "" || cfg.Auth.AdminAPIKey == "" {
+			err = fmt.Errorf("%w: auth.apiKey and auth.adminApiKey must both be set, e.g. with AUTH_API_KEY and AUTH_ADMIN_API_KEY",
+				apikeys.ErrNoUsableKey)
+			break
+		}
+		apiKeyRepo, err = repository.NewInMemoryAPIKeyRepository(apikeys.Static(cfg.Auth.APIKey, cfg.Auth.AdminAPIKey))
+	case config.AuthStoreFile:
+		var keys []models.APIKey
+		keys, err = apikeys.Load(cfg.Auth.KeysFile)
+		if err == nil {
+			slog.Info("Loaded API keys", "count", len(keys), "file", cfg.Auth.KeysFile)
+			apiKeyRepo, err = repository.NewInMemoryAPIKeyRepository(keys)
+		}
+	case config.AuthStoreSQLite:
+		var sqliteAPIKeyRepo *repository.SQLiteAPIKeyRepository
+		sqliteAPIKeyRepo, err = repository.NewSQLiteAPIKeyRepository(repository.SQLiteAPIKeyConfig{
+			DatabasePath: cfg.Auth.DatabasePath,
+			QueryTimeout: time.Duration(cfg.Storage.QueryTimeout),
+		})
+		if err == nil {
+			closers = append(closers, sqliteAPIKeyRepo)
+			checks = append(checks, services.HealthCheck{Name: "auth_database", Check: sqliteAPIKeyRepo.Ping})
+			apiKeyRepo = sqliteAPIKeyRepo
+		}
+	}
+	if err != nil {
+		fatal("Failed to initialize API key repository", err)
+	}
+	keys, err := apiKeyRepo.FindAll(context.Background())
+	if err == nil {
+		err = apikeys.CheckUsable(keys, time.Now())
+	}
+	if err != nil {
+		fatal("Refusing to start without a private API key; set auth.apiKey and auth.adminApiKey, list keys in auth.keysFile or create one with keyctl", err)
+	}
+
+	// Create the promo repository for the configured backend
+	promoSources := make([]repository.PromoSource, 0, len(cfg.Promo.Sources))
+	promoQuorum := services.PromoQuorum{
+		Mode:     services.QuorumMode(cfg.Promo.Quorum.Mode),
+		Required: cfg.Promo.Quorum.Required,
+		Weights:  make(map[string]int, len(cfg.Promo.Sources)),
+	}
+	for _, source := range cfg.Promo.Sources {
+		promoSources = append(promoSources, repository.PromoSource{Name: source.Name, Path: source.Path})
+		promoQuorum.Weights[source.Name] = source.Weight
+	}
+
+	// The sources load in the background so the server listens meanwhile;
+	// promo lookups fail with 503 until they are in
+	promoProgress := repository.NewPromoLoadProgress(promoSources)
+	promoRepo := repository.NewAsyncPromoRepository(promoSources, func() (repository.ReloadablePromoRepository, error) {
+		switch cfg.Promo.Backend {
+		case config.BackendMemory:
+			return repository.NewInMemoryPromoRepository(promoSources, promoProgress)
+		case config.BackendIndex:
+			return repository.NewIndexedPromoRepository(repository.IndexedPromoConfig{
+				IndexPath:       cfg.Promo.IndexPath,
+				Sources:         promoSources,
+				BloomBitsPerKey: cfg.Promo.BloomBitsPerKey,
+				ReadOnly:        cfg.Promo.ReadOnly,
+				Progress:        promoProgress,
+			})
+		default:
+			return repository.NewSQLitePromoRepository(repository.SQLitePromoConfig{
+				DatabasePath:  cfg.Promo.DatabasePath,
+				BatchSize:     cfg.Promo.BatchSize,
+				WorkerCount:   cfg.Promo.WorkerCount,
+				CreateIndexes: cfg.Promo.CreateIndexes,
+				Sources:       promoSources,
+				ReadOnly:      cfg.Promo.ReadOnly,
+				Progress:      promoProgress,
+			})
+		}
+	})
+	go func() {
+		<-promoRepo.Done()
+		if err := promoRepo.Ready(); err != nil {
+			slog.Error("Failed to initialize promo repository, the server stays not ready", "error", err)
+		}
+	}()
+	closers = append(closers, promoRepo)
+	checks = append(checks, services.HealthCheck{Name: "promo", Check: promoRepo.Ping})
+
 	// Create services
 	productService := services.NewProductService(productRepo)
-	promoService := services.NewPromoService(promoRepo)
-	orderService := services.NewOrderService(orderRepo, productRepo)
+	promoService := services.NewPromoService(promoRepo, promotionRepo, campaignRepo, productRepo, promoQuorum, models.RedemptionLimits{
+		MaxRedemptions: cfg.Promo.Limits.MaxRedemptions,
+		MaxPerCustomer: cfg.Promo.Limits.MaxPerCustomer,
+		SingleUse:      cfg.Promo.Limits.SingleUse,
+	}, time.Duration(cfg.Promo.LookupTimeout))
+	pricingService := services.NewPricingService(productRepo)
+	orderService := services.NewOrderService(orderRepo, productRepo, pricingService, promoService)
+	promoReloadService := services.NewPromoReloadService(promoRepo)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, time.Duration(cfg.Storage.IdempotencyTTL))
+	authService := services.NewAuthService(apiKeyRepo)
+
+	// Load the catalog file into the product repository in the background.
+	// A failure keeps the server not ready, with the reason in the readiness check.
+	catalogLoaded := make(chan struct{})
+	var catalogErr error // set before catalogLoaded is closed
+	go func() {
+		defer close(catalogLoaded)
+		if cfg.Catalog.SeedFile == "" {
+			return
+		}
+		products, err := catalog.Load(cfg.Catalog.SeedFile)
+		if err != nil {
+			slog.Error("Failed to load catalog, the server stays not ready", "error", err)
+			catalogErr = fmt.Errorf("failed to load catalog: %w", err)
+			return
+		}
+		created, updated, err := catalog.Populate(context.Background(), productService, products)
+		if err != nil {
+			slog.Error("Failed to populate catalog, the server stays not ready", "error", err)
+			catalogErr = fmt.Errorf("failed to populate catalog: %w", err)
+			return
+		}
+		slog.Info("Loaded catalog", "file", cfg.Catalog.SeedFile, "created", created, "updated", updated)
+	}()
+	checks = append(checks, services.HealthCheck{Name: "catalog", Check: func(ctx context.Context) error {
+		select {
+		case <-catalogLoaded:
+			return catalogErr
+		default:
+			return errCatalogLoading
+		}
+	}})
+	healthService := services.NewHealthService(checks, promoProgress)
 
 	// Create handlers
 	productHandler := handlers.NewProductHandler(productService)
-	orderHandler := handlers.NewOrderHandler(orderService, productService, promoService)
+	orderHandler := handlers.NewOrderHandler(orderService, productService, idempotencyService)
+	promoHandler := handlers.NewPromoHandler(orderService)
+	adminHandler := handlers.NewAdminHandler(promoReloadService)
+	healthHandler := handlers.NewHealthHandler(healthService)
+	authMiddleware := handlers.NewAuthMiddleware(authService)
 
 	// Setup routes
-	router := api.SetupRoutes(productHandler, orderHandler)
-
-	// Override port from environment if provided
-	if envPort := os.Getenv("PORT"); envPort != "" {
-		cfg.Server.Port = envPort
-	}
+	router := api.SetupRoutes(authMiddleware, productHandler, orderHandler, promoHandler, adminHandler, healthHandler)
 
 	// Configure HTTP server
 	server := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
-		Handler:      router,
-		ReadTimeout:  cfg.Server.ReadTimeout,
-		WriteTimeout: cfg.Server.WriteTimeout,
-		IdleTimeout:  cfg.Server.IdleTimeout,
+		Handler:      handlers.RequestTimeout(time.Duration(cfg.Server.WriteTimeout))(router),
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout),
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout),
+		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout),
 	}
 
 	// Channel to listen for errors coming from the listener
@@ -67,7 +304,7 @@ func main() {
 
 	// Start the server in a goroutine
 	go func() {
-		log.Printf("Server starting on port %s", cfg.Server.Port)
+		slog.Info("Server starting", "port", cfg.Server.Port)
 		serverErrors <- server.ListenAndServe()
 	}()
 
@@ -75,38 +312,72 @@ func main() {
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
 
+	// Reload promo codes on SIGHUP
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := promoReloadService.StartReload("signal"); err != nil {
+				slog.Warn("Promo reload not started", "error", err)
+			}
+		}
+	}()
+
+	// Watch the promo source files for new coupon drops if configured
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	if cfg.Promo.WatchInterval > 0 {
+		paths := make([]string, 0, len(cfg.Promo.Sources))
+		for _, source := range cfg.Promo.Sources {
+			paths = append(paths, source.Path)
+		}
+		go promoReloadService.WatchSources(watchCtx, paths, time.Duration(cfg.Promo.WatchInterval))
+	}
+
 	// Block until an os.Signal or an error is received
 	select {
 	case err := <-serverErrors:
-		log.Fatalf("Error starting server: %v", err)
+		fatal("Error starting server", err)
 
 	case sig := <-shutdown:
-		log.Printf("Shutdown signal received: %v", sig)
+		slog.Info("Shutdown signal received", "signal", sig.String())
+		stopWatching()
 
 		// Create a deadline context for graceful shutdown
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout))
 		defer cancel()
 
 		// Gracefully shutdown connections
-		log.Println("Shutting down server...")
+		slog.Info("Shutting down server")
+
+		// Shut down HTTP server so in-flight requests finish before storage closes
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Error("Error during server shutdown", "error", err)
+			server.Close()
+		}
 
 		// Shut down database connections
-		if err := promoRepo.Close(); err != nil {
-			log.Printf("Error closing SQLite connection: %v", err)
+		for _, closer := range closers {
+			if err := closer.Close(); err != nil {
+				slog.Error("Error closing SQLite connection", "error", err)
+			}
 		}
 
-		// Shut down HTTP server
-		if err := server.Shutdown(ctx); err != nil {
-			log.Printf("Error during server shutdown: %v", err)
-			server.Close()
+		// Flush the spans of the last requests
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("Error flushing traces", "error", err)
 		}
 
-		// Verify if the server shutdown gracefully
-		<-ctx.Done()
 		if ctx.Err() == context.DeadlineExceeded {
-			log.Println("Shutdown deadline exceeded, forcing exit")
+			slog.Warn("Shutdown deadline exceeded, forcing exit")
 		} else {
-			log.Println("Server gracefully stopped")
+			slog.Info("Server gracefully stopped")
 		}
 	}
 }
+
+// fatal logs an error that keeps the server from running and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
@@ -0,0 +1,139 @@
+// Command promoctl builds and verifies promo code stores offline, so the
+// server can open them read-only instead of loading coupon files on startup.
+//
+// Usage:
+//
+//	promoctl build  [config flags]
+//	promoctl verify [config flags]
+//
+// The config flags, file and environment are the server's (see --help), so
+// promoctl builds exactly the database or index the server will open:
+// promo.backend picks the format, promo.sources the coupon files (plain or
+// gzipped) and promo.databasePath or promo.indexPath the artifact.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/jilani-go/glofox/internal/config"
+	"github.com/jilani-go/glofox/internal/logging"
+	"github.com/jilani-go/glofox/internal/repository"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	command := os.Args[1]
+	if command != "build" && command != "verify" {
+		usage()
+	}
+
+	if err := run(command, os.Args[2:], os.Stdout); err != nil {
+		slog.Error("promoctl "+command+" failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run builds and verifies, or only verifies, the promo store configured by
+// args and the environment, printing the results to out
+func run(command string, args []string, out io.Writer) error {
+	cfg, _, err := config.Load(args)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	// Progress is logged at the server's configured level and format
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		return fmt.Errorf("failed to configure logging: %w", err)
+	}
+	slog.SetDefault(logger)
+
+	sources := make([]repository.PromoSource, 0, len(cfg.Promo.Sources))
+	for _, source := range cfg.Promo.Sources {
+		sources = append(sources, repository.PromoSource{Name: source.Name, Path: source.Path})
+	}
+
+	switch cfg.Promo.Backend {
+	case config.BackendIndex:
+		if command == "build" {
+			stats, err := repository.BuildPromoIndex(sources, cfg.Promo.IndexPath, repository.PromoIndexOptions{
+				BloomBitsPerKey: cfg.Promo.BloomBitsPerKey,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to build promo index: %w", err)
+			}
+			printSourceStats(out, stats.Sources)
+		}
+		info, err := repository.VerifyPromoIndex(cfg.Promo.IndexPath)
+		if err != nil {
+			return fmt.Errorf("promo index verification failed: %w", err)
+		}
+		fmt.Fprintf(out, "\nVerified %s: %d distinct codes, %d bytes, Bloom filter %t\n",
+			cfg.Promo.IndexPath, info.Records, info.Size, info.Bloom)
+		printCodeCounts(out, info.Sources, info.Codes)
+
+	case config.BackendSQLite:
+		if command == "build" {
+			stats, err := repository.BuildSQLitePromoDatabase(repository.SQLitePromoConfig{
+				DatabasePath:  cfg.Promo.DatabasePath,
+				BatchSize:     cfg.Promo.BatchSize,
+				WorkerCount:   cfg.Promo.WorkerCount,
+				CreateIndexes: cfg.Promo.CreateIndexes,
+				Sources:       sources,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to build promo database: %w", err)
+			}
+			printSourceStats(out, stats)
+		}
+		counts, err := repository.VerifySQLitePromoDatabase(cfg.Promo.DatabasePath, sources)
+		if err != nil {
+			return fmt.Errorf("promo database verification failed: %w", err)
+		}
+		fmt.Fprintf(out, "\nVerified %s\n", cfg.Promo.DatabasePath)
+		names := make([]string, 0, len(sources))
+		for _, source := range sources {
+			names = append(names, source.Name)
+		}
+		printCodeCounts(out, names, counts)
+
+	default:
+		return fmt.Errorf("the %q promo backend has nothing to build, use %q or %q",
+			cfg.Promo.Backend, config.BackendIndex, config.BackendSQLite)
+	}
+	return nil
+}
+
+// usage prints the commands and exits
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: promoctl build|verify [config flags]")
+	fmt.Fprintln(os.Stderr, "  build   ingest the promo sources and write the promo database or index, then verify it")
+	fmt.Fprintln(os.Stderr, "  verify  check an existing promo database or index")
+	os.Exit(2)
+}
+
+// printSourceStats prints what was read from every source
+func printSourceStats(out io.Writer, stats []repository.PromoSourceStats) {
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "SOURCE\tLINES\tCODES\tDUPLICATES\tINVALID LENGTH\t")
+	for _, source := range stats {
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%d\t\n", source.Name, source.Lines, source.Codes, source.Duplicates, source.Skipped)
+	}
+	writer.Flush()
+}
+
+// printCodeCounts prints the number of codes of every source, in source order
+func printCodeCounts(out io.Writer, names []string, counts map[string]int) {
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "SOURCE\tCODES\t")
+	for _, name := range names {
+		fmt.Fprintf(writer, "%s\t%d\t\n", name, counts[name])
+	}
+	writer.Flush()
+}
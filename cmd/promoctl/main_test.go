@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	alpha := filepath.Join(dir, "alpha.txt")
+	beta := filepath.Join(dir, "beta.txt")
+	for path, content := range map[string]string{
+		alpha: "HAPPYHRS\nFIFTYOFF10\nHAPPYHRS\nSHORT\n",
+		beta:  "FIFTYOFF10\nBETAONLY1\n",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write promo source: %v", err)
+		}
+	}
+	sources := "alpha=" + alpha + ",beta=" + beta
+	missing := "alpha=" + alpha + ",gamma=" + filepath.Join(dir, "gamma.txt")
+
+	tests := []struct {
+		name    string
+		command string
+		args    []string
+		// wantOut lists lines the output must contain, with spaces collapsed
+		wantOut []string
+		wantErr string
+	}{
+		{
+			name:    "build an index",
+			command: "build",
+			args:    []string{"--promo-backend", "index", "--promo-index", filepath.Join(dir, "build.idx"), "--promo-sources", sources},
+			wantOut: []string{
+				"SOURCE LINES CODES DUPLICATES INVALID LENGTH",
+				"alpha 4 2 1 1",
+				"beta 2 2 0 0",
+				"3 distinct codes",
+				"Bloom filter true",
+			},
+		},
+		{
+			name:    "build an index without a Bloom filter",
+			command: "build",
+			args: []string{"--promo-backend", "index", "--promo-index", filepath.Join(dir, "nobloom.idx"),
+				"--promo-sources", sources, "--promo-bloom-bits", "0"},
+			wantOut: []string{"Bloom filter false"},
+		},
+		{
+			name:    "build a database",
+			command: "build",
+			args:    []string{"--promo-backend", "sqlite", "--promo-database", filepath.Join(dir, "build.db"), "--promo-sources", sources},
+			wantOut: []string{"alpha 4 2 1 1", "beta 2 2 0 0", "Verified " + filepath.Join(dir, "build.db")},
+		},
+		{
+			name:    "verify a built index",
+			command: "verify",
+			args:    []string{"--promo-backend", "index", "--promo-index", filepath.Join(dir, "build.idx"), "--promo-sources", sources},
+			wantOut: []string{"SOURCE CODES", "alpha 2", "beta 2"},
+		},
+		{
+			name:    "verify a missing index",
+			command: "verify",
+			args:    []string{"--promo-backend", "index", "--promo-index", filepath.Join(dir, "missing.idx"), "--promo-sources", sources},
+			wantErr: "promo index verification failed",
+		},
+		{
+			name:    "missing source",
+			command: "build",
+			args:    []string{"--promo-backend", "index", "--promo-index", filepath.Join(dir, "missing-source.idx"), "--promo-sources", missing},
+			wantErr: "failed to build promo index",
+		},
+		{
+			name:    "nothing to build in memory",
+			command: "build",
+			args:    []string{"--promo-backend", "memory", "--promo-sources", sources},
+			wantErr: `"memory" promo backend has nothing to build`,
+		},
+		{
+			name:    "invalid configuration",
+			command: "build",
+			args:    []string{"--promo-backend", "postgres"},
+			wantErr: "failed to load configuration",
+		},
+	}
+
+	t.Setenv("CONFIG_FILE", "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := run(tt.command, tt.args, &out)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("run() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("run() unexpected error: %v", err)
+			}
+
+			// Compare lines without the tabwriter's padding
+			lines := strings.Split(out.String(), "\n")
+			for i, line := range lines {
+				lines[i] = strings.Join(strings.Fields(line), " ")
+			}
+			output := strings.Join(lines, "\n")
+			for _, want := range tt.wantOut {
+				if !strings.Contains(output, want) {
+					t.Errorf("run() output =\n%s\nwant it to contain %q", output, want)
+				}
+			}
+		})
+	}
+}
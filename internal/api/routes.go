@@ -5,19 +5,56 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/jilani-go/glofox/internal/handlers"
+	"github.com/jilani-go/glofox/internal/metrics"
+	"github.com/jilani-go/glofox/internal/models"
 )
 
-// SetupRoutes initializes the API routes
-func SetupRoutes(productHandler *handlers.ProductHandler, orderHandler *handlers.OrderHandler) http.Handler {
+// SetupRoutes initializes the API routes. Every route except the product
+// listing, the health probes and the metrics requires an API key granting
+// the route's scope.
+// Every request is tagged with a request ID, traced, logged and measured.
+func SetupRoutes(auth *handlers.AuthMiddleware, productHandler *handlers.ProductHandler, orderHandler *handlers.OrderHandler, promoHandler *handlers.PromoHandler, adminHandler *handlers.AdminHandler, healthHandler *handlers.HealthHandler) http.Handler {
 	// Create router
 	router := mux.NewRouter()
 
+	// protected wraps a handler so it requires the scope
+	protected := func(scope models.Scope, handler http.HandlerFunc) http.Handler {
+		return auth.Require(scope)(handler)
+	}
+
 	// Product routes
 	router.HandleFunc("/product", productHandler.ListProducts).Methods("GET")
 	router.HandleFunc("/product/{productId}", productHandler.GetProduct).Methods("GET")
+	router.Handle("/product", protected(models.ScopeCatalogAdmin, productHandler.CreateProduct)).Methods("POST")
+	router.Handle("/product/{productId}", protected(models.ScopeCatalogAdmin, productHandler.UpdateProduct)).Methods("PUT")
+	router.Handle("/product/{productId}", protected(models.ScopeCatalogAdmin, productHandler.DeleteProduct)).Methods("DELETE")
 
 	// Order routes
-	router.HandleFunc("/order", orderHandler.PlaceOrder).Methods("POST")
+	router.Handle("/order", protected(models.ScopeOrdersWrite, orderHandler.PlaceOrder)).Methods("POST")
+	router.Handle("/order/quote", protected(models.ScopeOrdersWrite, orderHandler.QuoteOrder)).Methods("POST")
+	router.Handle("/order", protected(models.ScopeOrdersRead, orderHandler.ListOrders)).Methods("GET")
+	router.Handle("/order/{orderId}", protected(models.ScopeOrdersRead, orderHandler.GetOrder)).Methods("GET")
+	router.Handle("/order/{orderId}/status", protected(models.ScopeOrdersWrite, orderHandler.UpdateOrderStatus)).Methods("PATCH")
+	router.Handle("/order/{orderId}/{action}", protected(models.ScopeOrdersWrite, orderHandler.ApplyOrderAction)).Methods("POST")
+
+	// Promo routes
+	router.Handle("/promo/validate", protected(models.ScopeOrdersWrite, promoHandler.ValidatePromoCode)).Methods("POST")
+	router.Handle("/promo/validate/batch", protected(models.ScopeOrdersWrite, promoHandler.ValidatePromoCodes)).Methods("POST")
+
+	// Admin routes
+	router.Handle("/admin/promo/reload", protected(models.ScopePromoAdmin, adminHandler.ReloadPromoCodes)).Methods("POST")
+	router.Handle("/admin/promo/reload", protected(models.ScopePromoAdmin, adminHandler.GetPromoReloadStatus)).Methods("GET")
+
+	// Health routes
+	router.HandleFunc("/healthz", healthHandler.Liveness).Methods("GET")
+	router.HandleFunc("/readyz", healthHandler.Readiness).Methods("GET")
+	router.HandleFunc("/status", healthHandler.GetStatus).Methods("GET")
+
+	// Prometheus metrics
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
 
-	return router
+	// Wrap the whole router rather than its routes, so requests matching no
+	// route or method are traced, logged and measured too
+	handler := handlers.Tracing(handlers.AccessLog(handlers.Metrics(router)))
+	return handlers.RequestID(handlers.MatchRoute(router)(handler))
 }
@@ -0,0 +1,140 @@
+package apikeys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Errors for API key loading
+var (
+	ErrUnsupportedFormat = errors.New("unsupported API keys format")
+)
+
+// hashPattern matches a hex SHA-256 as produced by models.HashAPIKey
+var hashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// entry is an API key before validation
+type entry struct {
+	ID        string   `json:"id" yaml:"id"`
+	Name      string   `json:"name" yaml:"name"`
+	Hash      string   `json:"hash" yaml:"hash"`
+	Scopes    []string `json:"scopes" yaml:"scopes"`
+	ExpiresAt string   `json:"expiresAt" yaml:"expiresAt"`
+	RevokedAt string   `json:"revokedAt" yaml:"revokedAt"`
+}
+
+// Load reads and validates the API keys of a JSON or YAML file, chosen by
+// extension. Keys are listed by hash, never in plain text. Every problem is
+// reported at once.
+func Load(path string) ([]models.APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys: %w", err)
+	}
+
+	var entries []entry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &entries)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &entries)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse API keys %s: %w", path, err)
+	}
+
+	keys, err := validate(entries)
+	if err != nil {
+		return nil, fmt.Errorf("API keys %s are invalid:\n%w", path, err)
+	}
+	return keys, nil
+}
+
+// validate converts entries into keys, collecting every problem
+func validate(entries []entry) ([]models.APIKey, error) {
+	var errs []error
+	keys := make([]models.APIKey, 0, len(entries))
+	ids := make(map[string]bool, len(entries))
+	hashes := make(map[string]bool, len(entries))
+
+	for i, e := range entries {
+		fail := func(field, format string, args ...interface{}) {
+			errs = append(errs, fmt.Errorf("keys[%d].%s: %s", i, field, fmt.Sprintf(format, args...)))
+		}
+
+		id := strings.TrimSpace(e.ID)
+		if id == "" {
+			fail("id", "must not be empty")
+		} else if ids[id] {
+			fail("id", "duplicate key %q", id)
+		}
+		ids[id] = true
+
+		hash := strings.ToLower(strings.TrimSpace(e.Hash))
+		if !hashPattern.MatchString(hash) {
+			fail("hash", "must be the hex SHA-256 of the key (see keyctl hash)")
+		} else if hashes[hash] {
+			fail("hash", "duplicate of another key")
+		}
+		hashes[hash] = true
+
+		scopes := make([]models.Scope, 0, len(e.Scopes))
+		for _, scope := range e.Scopes {
+			scope := models.Scope(strings.TrimSpace(scope))
+			if !models.ValidScope(scope) {
+				fail("scopes", "unknown scope %q", scope)
+			}
+			scopes = append(scopes, scope)
+		}
+		if len(scopes) == 0 {
+			fail("scopes", "must grant at least one scope")
+		}
+
+		expiresAt, err := parseTime(e.ExpiresAt)
+		if err != nil {
+			fail("expiresAt", "%v", err)
+		}
+		revokedAt, err := parseTime(e.RevokedAt)
+		if err != nil {
+			fail("revokedAt", "%v", err)
+		}
+
+		keys = append(keys, models.APIKey{
+			ID:        id,
+			Name:      strings.TrimSpace(e.Name),
+			Hash:      hash,
+			Scopes:    scopes,
+			ExpiresAt: expiresAt,
+			RevokedAt: revokedAt,
+		})
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return keys, nil
+}
+
+// parseTime parses an optional RFC 3339 time
+func parseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time", value)
+	}
+	return parsed, nil
+}
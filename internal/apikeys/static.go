@@ -0,0 +1,74 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+// Errors for API key checks
+var (
+	ErrNoUsableKey = errors.New("no usable API key is configured")
+	ErrPublicKey   = errors.New("API key is publicly known")
+)
+
+// publicKeys were published with the service, as former defaults or in the
+// examples. Anyone can use them, so they are never accepted.
+var publicKeys = []string{"apitest", "admintest", "checkout-demo-key", "ops-demo-key"}
+
+// CheckUsable fails with ErrNoUsableKey unless a key is neither expired nor
+// revoked at the given time, and with ErrPublicKey if such a key is publicly known
+func CheckUsable(keys []models.APIKey, at time.Time) error {
+	usable := 0
+	for _, key := range keys {
+		if key.Expired(at) || key.Revoked(at) {
+			continue
+		}
+		for _, public := range publicKeys {
+			if key.Hash == models.HashAPIKey(public) {
+				return fmt.Errorf("%w: %s", ErrPublicKey, key.ID)
+			}
+		}
+		usable++
+	}
+	if usable == 0 {
+		return ErrNoUsableKey
+	}
+	return nil
+}
+
+// Static returns the keys of the config store: apiKey places and reads
+// orders, adminAPIKey administers the catalog and promo codes. A key given
+// for both roles gets every scope of both.
+func Static(apiKey, adminAPIKey string) []models.APIKey {
+	client := models.APIKey{
+		ID:     "api",
+		Name:   "auth.apiKey",
+		Hash:   models.HashAPIKey(apiKey),
+		Scopes: []models.Scope{models.ScopeOrdersRead, models.ScopeOrdersWrite},
+	}
+	admin := models.APIKey{
+		ID:     "admin",
+		Name:   "auth.adminApiKey",
+		Hash:   models.HashAPIKey(adminAPIKey),
+		Scopes: []models.Scope{models.ScopeCatalogAdmin, models.ScopePromoAdmin},
+	}
+	if apiKey == adminAPIKey {
+		client.Scopes = append(client.Scopes, admin.Scopes...)
+		return []models.APIKey{client}
+	}
+	return []models.APIKey{client, admin}
+}
+
+// Generate returns a new random API key
+func Generate() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
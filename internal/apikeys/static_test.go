@@ -0,0 +1,80 @@
+package apikeys
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+func TestStatic(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiKey     string
+		adminKey   string
+		wantScopes map[string]int
+	}{
+		{
+			name:       "separate keys",
+			apiKey:     "client-key",
+			adminKey:   "admin-key",
+			wantScopes: map[string]int{"api": 2, "admin": 2},
+		},
+		{
+			name:       "one key for both roles",
+			apiKey:     "shared-key",
+			adminKey:   "shared-key",
+			wantScopes: map[string]int{"api": 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := Static(tt.apiKey, tt.adminKey)
+			if len(keys) != len(tt.wantScopes) {
+				t.Fatalf("Static() = %d keys, want %d", len(keys), len(tt.wantScopes))
+			}
+			for _, key := range keys {
+				if len(key.Scopes) != tt.wantScopes[key.ID] {
+					t.Errorf("key %s scopes = %v, want %d scopes", key.ID, key.Scopes, tt.wantScopes[key.ID])
+				}
+				if key.Hash == tt.apiKey || key.Hash == tt.adminKey {
+					t.Errorf("key %s is stored in plain text", key.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckUsable(t *testing.T) {
+	now := time.Now()
+	key := func(id, secret string) models.APIKey {
+		return models.APIKey{ID: id, Hash: models.HashAPIKey(secret), Scopes: []models.Scope{models.ScopeOrdersRead}}
+	}
+	expired := key("expired", "old-secret")
+	expired.ExpiresAt = now.Add(-time.Hour)
+	revokedPublic := key("revoked", "apitest")
+	revokedPublic.RevokedAt = now.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		keys    []models.APIKey
+		wantErr error
+	}{
+		{name: "private key", keys: []models.APIKey{key("checkout", "a-private-secret")}},
+		{name: "no keys", keys: nil, wantErr: ErrNoUsableKey},
+		{name: "only expired keys", keys: []models.APIKey{expired}, wantErr: ErrNoUsableKey},
+		{name: "former default", keys: []models.APIKey{key("api", "apitest")}, wantErr: ErrPublicKey},
+		{name: "example key", keys: []models.APIKey{key("checkout", "a-private-secret"), key("ops", "ops-demo-key")}, wantErr: ErrPublicKey},
+		{name: "revoked public key", keys: []models.APIKey{key("checkout", "a-private-secret"), revokedPublic}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckUsable(tt.keys, now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("CheckUsable() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package campaign
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Errors for campaign loading
+var (
+	ErrUnsupportedFormat = errors.New("unsupported campaigns format")
+)
+
+// currencyPattern matches ISO 4217 style currency codes
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// localLayouts are the accepted window times without a UTC offset,
+// interpreted in the campaign's timezone
+var localLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// entry is a campaign before validation. Times and the minimum order value
+// are kept as text so they can be parsed in the campaign's timezone and currency.
+type entry struct {
+	Name          string      `json:"name" yaml:"name"`
+	Timezone      string      `json:"timezone" yaml:"timezone"`
+	StartsAt      string      `json:"startsAt" yaml:"startsAt"`
+	EndsAt        string      `json:"endsAt" yaml:"endsAt"`
+	Categories    []string    `json:"categories" yaml:"categories"`
+	ProductIDs    []string    `json:"productIds" yaml:"productIds"`
+	MinOrderValue interface{} `json:"minOrderValue" yaml:"minOrderValue"`
+	Currency      string      `json:"currency" yaml:"currency"`
+	Codes         []string    `json:"codes" yaml:"codes"`
+}
+
+// Load reads and validates the campaigns of a JSON or YAML file, chosen by extension.
+// Every problem is reported at once.
+func Load(path string) ([]models.Campaign, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read campaigns: %w", err)
+	}
+
+	var entries []entry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&entries)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &entries)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse campaigns %s: %w", path, err)
+	}
+
+	campaigns, err := validate(entries)
+	if err != nil {
+		return nil, fmt.Errorf("campaigns %s are invalid:\n%w", path, err)
+	}
+	return campaigns, nil
+}
+
+// validate converts entries into campaigns, collecting every problem
+func validate(entries []entry) ([]models.Campaign, error) {
+	var errs []error
+	campaigns := make([]models.Campaign, 0, len(entries))
+	names := make(map[string]bool, len(entries))
+	owners := make(map[string]string)
+
+	for i, e := range entries {
+		fail := func(field, format string, args ...interface{}) {
+			errs = append(errs, fmt.Errorf("campaigns[%d].%s: %s", i, field, fmt.Sprintf(format, args...)))
+		}
+
+		name := strings.TrimSpace(e.Name)
+		if name == "" {
+			fail("name", "must not be empty")
+		} else if names[name] {
+			fail("name", "duplicate campaign %q", name)
+		}
+		names[name] = true
+
+		timezone := strings.TrimSpace(e.Timezone)
+		if timezone == "" {
+			timezone = "UTC"
+		}
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			fail("timezone", "unknown timezone %q", timezone)
+			location = time.UTC
+		}
+
+		startsAt, err := parseTime(e.StartsAt, location)
+		if err != nil {
+			fail("startsAt", "%v", err)
+		}
+		endsAt, err := parseTime(e.EndsAt, location)
+		if err != nil {
+			fail("endsAt", "%v", err)
+		}
+		if !startsAt.IsZero() && !endsAt.IsZero() && !startsAt.Before(endsAt) {
+			fail("endsAt", "must be after startsAt")
+		}
+
+		currency := strings.TrimSpace(e.Currency)
+		if currency == "" {
+			currency = models.DefaultCurrency
+		} else if !currencyPattern.MatchString(currency) {
+			fail("currency", "must be an ISO 4217 code")
+		}
+		var minOrderValue models.Money
+		if e.MinOrderValue != nil {
+			parsed, err := models.ParseMoney(fmt.Sprint(e.MinOrderValue), currency)
+			if err != nil {
+				fail("minOrderValue", "%v", err)
+			} else if parsed.IsNegative() {
+				fail("minOrderValue", "must not be negative")
+			} else {
+				minOrderValue = parsed
+			}
+		}
+
+		codes := trimAll(e.Codes)
+		if len(codes) == 0 {
+			fail("codes", "must list at least one promo code")
+		}
+		for _, code := range codes {
+			if owner, taken := owners[code]; taken {
+				fail("codes", "%s already belongs to campaign %q", code, owner)
+			}
+			owners[code] = name
+		}
+
+		campaigns = append(campaigns, models.Campaign{
+			Name:          name,
+			StartsAt:      startsAt,
+			EndsAt:        endsAt,
+			Timezone:      timezone,
+			Categories:    trimAll(e.Categories),
+			ProductIDs:    trimAll(e.ProductIDs),
+			MinOrderValue: minOrderValue,
+			Codes:         codes,
+		})
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return campaigns, nil
+}
+
+// parseTime parses an RFC 3339 time, or a local date or time in the given
+// location. An empty value is the zero time.
+func parseTime(value string, location *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	for _, layout := range localLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, location); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date, local time or RFC 3339 time", value)
+}
+
+// trimAll trims every value and drops the empty ones
+func trimAll(values []string) []string {
+	trimmed := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			trimmed = append(trimmed, value)
+		}
+	}
+	return trimmed
+}
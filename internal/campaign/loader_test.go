@@ -0,0 +1,181 @@
+package campaign
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+func TestLoad(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []models.Campaign
+		// wantFields lists the fields the error must report, as campaigns[i].field
+		wantFields []string
+		wantErr    error
+	}{
+		{
+			name: "yaml",
+			file: "campaigns.yaml",
+			content: `- name: happy-hours
+  timezone: Europe/London
+  startsAt: 2026-07-01
+  endsAt: 2026-07-01 18:30
+  minOrderValue: "10.00"
+  codes: [HAPPYHOURS]
+- name: waffle-week
+  timezone: America/New_York
+  startsAt: 2026-06-01T08:00
+  categories: [" Waffle ", ""]
+  productIds: [brulee]
+  minOrderValue: 1500
+  currency: JPY
+  codes: [BUYGETONE]
+`,
+			want: []models.Campaign{
+				{
+					Name:          "happy-hours",
+					Timezone:      "Europe/London",
+					StartsAt:      time.Date(2026, 7, 1, 0, 0, 0, 0, london),
+					EndsAt:        time.Date(2026, 7, 1, 18, 30, 0, 0, london),
+					Categories:    []string{},
+					ProductIDs:    []string{},
+					MinOrderValue: models.NewMoney(1000, "USD"),
+					Codes:         []string{"HAPPYHOURS"},
+				},
+				{
+					Name:          "waffle-week",
+					Timezone:      "America/New_York",
+					StartsAt:      time.Date(2026, 6, 1, 8, 0, 0, 0, newYork),
+					Categories:    []string{"Waffle"},
+					ProductIDs:    []string{"brulee"},
+					MinOrderValue: models.NewMoney(1500, "JPY"),
+					Codes:         []string{"BUYGETONE"},
+				},
+			},
+		},
+		{
+			name:    "json with an offset",
+			file:    "campaigns.json",
+			content: `[{"name": "launch", "startsAt": "2026-06-01T08:00:00+02:00", "minOrderValue": 12.5, "codes": ["LAUNCH2026"]}]`,
+			want: []models.Campaign{
+				{
+					Name:          "launch",
+					Timezone:      "UTC",
+					StartsAt:      time.Date(2026, 6, 1, 6, 0, 0, 0, time.UTC),
+					Categories:    []string{},
+					ProductIDs:    []string{},
+					MinOrderValue: models.NewMoney(1250, "USD"),
+					Codes:         []string{"LAUNCH2026"},
+				},
+			},
+		},
+		{
+			name: "every problem reported",
+			file: "campaigns.yaml",
+			content: `- name: ""
+  timezone: Mars/Olympus
+  startsAt: next week
+  codes: []
+- name: a
+  startsAt: 2026-07-01
+  endsAt: 2026-06-01
+  currency: usd
+  minOrderValue: -1
+  codes: [SHAREDCODE]
+- name: a
+  minOrderValue: "1.005"
+  codes: [SHAREDCODE]
+`,
+			wantFields: []string{
+				"campaigns[0].name",
+				"campaigns[0].timezone",
+				"campaigns[0].startsAt",
+				"campaigns[0].codes",
+				"campaigns[1].endsAt",
+				"campaigns[1].currency",
+				"campaigns[1].minOrderValue",
+				"campaigns[2].name",
+				"campaigns[2].minOrderValue",
+				"campaigns[2].codes",
+			},
+		},
+		{
+			name:       "malformed yaml",
+			file:       "campaigns.yml",
+			content:    "- name: [",
+			wantFields: []string{"failed to parse"},
+		},
+		{
+			name:    "unsupported format",
+			file:    "campaigns.toml",
+			content: "name = 'a'",
+			wantErr: ErrUnsupportedFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write campaigns: %v", err)
+			}
+
+			campaigns, err := Load(path)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case tt.wantFields != nil:
+				if err == nil {
+					t.Fatal("Load() succeeded, want an error")
+				}
+				for _, field := range tt.wantFields {
+					if !strings.Contains(err.Error(), field) {
+						t.Errorf("Load() error = %v, want it to report %s", err, field)
+					}
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			if len(campaigns) != len(tt.want) {
+				t.Fatalf("Load() = %+v, want %+v", campaigns, tt.want)
+			}
+			for i := range campaigns {
+				if !equalCampaigns(campaigns[i], tt.want[i]) {
+					t.Errorf("campaign %d = %+v, want %+v", i, campaigns[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// equalCampaigns compares two campaigns, times by instant
+func equalCampaigns(a, b models.Campaign) bool {
+	return a.Name == b.Name && a.Timezone == b.Timezone &&
+		a.StartsAt.Equal(b.StartsAt) && a.EndsAt.Equal(b.EndsAt) &&
+		slices.Equal(a.Categories, b.Categories) && slices.Equal(a.ProductIDs, b.ProductIDs) &&
+		a.MinOrderValue == b.MinOrderValue && slices.Equal(a.Codes, b.Codes)
+}
@@ -0,0 +1,360 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Errors for catalog loading
+var (
+	ErrUnsupportedFormat = errors.New("unsupported catalog format")
+)
+
+// currencyPattern matches ISO 4217 style currency codes
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// RowError describes a problem with one catalog entry
+type RowError struct {
+	Line    int
+	Field   string
+	Message string
+}
+
+// Error implements the error interface
+func (e RowError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Field, e.Message)
+}
+
+// ValidationError collects every problem found in a catalog file
+type ValidationError struct {
+	Path   string
+	Errors []RowError
+}
+
+// Error implements the error interface, listing every row error
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "catalog %s has %d error(s):", e.Path, len(e.Errors))
+	for _, rowErr := range e.Errors {
+		b.WriteString("\n  ")
+		b.WriteString(rowErr.Error())
+	}
+	return b.String()
+}
+
+// entry is a catalog row before validation. Prices are kept as text so
+// they can be parsed exactly in the row's currency.
+type entry struct {
+	line int
+	// invalid lists why the entry could not be decoded, empty if it could
+	invalid     []RowError
+	ID          string `yaml:"id"`
+	Name        string `yaml:"name"`
+	Price       string `yaml:"price"`
+	Currency    string `yaml:"currency"`
+	Category    string `yaml:"category"`
+	Description string `yaml:"description"`
+	ImageURL    string `yaml:"imageUrl"`
+}
+
+// Load reads and validates the products of a catalog file.
+// The format is chosen from the extension: .json, .yaml/.yml or .csv.
+// Every invalid row is reported in a single *ValidationError.
+func Load(path string) ([]models.Product, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+
+	var entries []entry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		entries, err = parseJSON(data)
+	case ".yaml", ".yml":
+		entries, err = parseYAML(data)
+	case ".csv":
+		entries, err = parseCSV(data)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse catalog %s: %w", path, err)
+	}
+
+	return validate(path, entries)
+}
+
+// validate converts entries into products, collecting every row error
+func validate(path string, entries []entry) ([]models.Product, error) {
+	var rowErrors []RowError
+	products := make([]models.Product, 0, len(entries))
+	firstLine := make(map[string]int, len(entries))
+
+	for _, e := range entries {
+		if len(e.invalid) > 0 {
+			rowErrors = append(rowErrors, e.invalid...)
+			continue
+		}
+
+		valid := true
+		fail := func(field, message string) {
+			rowErrors = append(rowErrors, RowError{Line: e.line, Field: field, Message: message})
+			valid = false
+		}
+
+		id := strings.TrimSpace(e.ID)
+		if id == "" {
+			fail("id", "must not be empty")
+		} else if line, seen := firstLine[id]; seen {
+			fail("id", fmt.Sprintf("duplicate of line %d", line))
+		} else {
+			firstLine[id] = e.line
+		}
+
+		name := strings.TrimSpace(e.Name)
+		if name == "" {
+			fail("name", "must not be empty")
+		}
+		category := strings.TrimSpace(e.Category)
+		if category == "" {
+			fail("category", "must not be empty")
+		}
+
+		currency := strings.TrimSpace(e.Currency)
+		if currency == "" {
+			currency = models.DefaultCurrency
+		} else if !currencyPattern.MatchString(currency) {
+			fail("currency", "must be an ISO 4217 code")
+		}
+
+		var price models.Money
+		if strings.TrimSpace(e.Price) == "" {
+			fail("price", "must not be empty")
+		} else if parsed, err := models.ParseMoney(e.Price, currency); err != nil {
+			fail("price", err.Error())
+		} else if parsed.IsNegative() || parsed.IsZero() {
+			fail("price", "must be greater than zero")
+		} else {
+			price = parsed
+		}
+
+		if valid {
+			products = append(products, models.Product{
+				ID:          id,
+				Name:        name,
+				Price:       price,
+				Category:    category,
+				Description: strings.TrimSpace(e.Description),
+				ImageURL:    strings.TrimSpace(e.ImageURL),
+			})
+		}
+	}
+
+	if len(rowErrors) > 0 {
+		return nil, &ValidationError{Path: path, Errors: rowErrors}
+	}
+	return products, nil
+}
+
+// parseJSON reads a JSON array of products, remembering the line each one
+// starts on. Only malformed JSON stops parsing; an element that is not a
+// product is kept as an invalid entry.
+func parseJSON(data []byte) ([]entry, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("expected a JSON array of products")
+	}
+
+	var entries []entry
+	for decoder.More() {
+		line := lineAt(data, decoder.InputOffset())
+
+		var element json.RawMessage
+		if err := decoder.Decode(&element); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		var raw struct {
+			ID          interface{} `json:"id"`
+			Name        string      `json:"name"`
+			Price       interface{} `json:"price"`
+			Currency    string      `json:"currency"`
+			Category    string      `json:"category"`
+			Description string      `json:"description"`
+			ImageURL    string      `json:"imageUrl"`
+		}
+		elementDecoder := json.NewDecoder(bytes.NewReader(element))
+		elementDecoder.UseNumber()
+		if err := elementDecoder.Decode(&raw); err != nil {
+			entries = append(entries, entry{line: line, invalid: []RowError{jsonDecodeError(line, err)}})
+			continue
+		}
+
+		entries = append(entries, entry{
+			line:        line,
+			ID:          scalarString(raw.ID),
+			Name:        raw.Name,
+			Price:       scalarString(raw.Price),
+			Currency:    raw.Currency,
+			Category:    raw.Category,
+			Description: raw.Description,
+			ImageURL:    raw.ImageURL,
+		})
+	}
+
+	return entries, nil
+}
+
+// jsonDecodeError describes why a JSON element could not be decoded into a product
+func jsonDecodeError(line int, err error) RowError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if typeErr.Field == "" {
+			return RowError{Line: line, Message: fmt.Sprintf("expected a product object, got %s", typeErr.Value)}
+		}
+		return RowError{Line: line, Field: typeErr.Field, Message: fmt.Sprintf("must not be %s", typeErr.Value)}
+	}
+	return RowError{Line: line, Message: err.Error()}
+}
+
+// yamlDecodeError describes why a YAML node could not be decoded into a
+// product, using the lines yaml reports for the offending values
+func yamlDecodeError(line int, err error) []RowError {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return []RowError{{Line: line, Message: err.Error()}}
+	}
+	rowErrors := make([]RowError, 0, len(typeErr.Errors))
+	for _, message := range typeErr.Errors {
+		rowErr := RowError{Line: line, Message: message}
+		if prefix, rest, ok := strings.Cut(message, ": "); ok {
+			if _, err := fmt.Sscanf(prefix, "line %d", &rowErr.Line); err == nil {
+				rowErr.Message = rest
+			}
+		}
+		rowErrors = append(rowErrors, rowErr)
+	}
+	return rowErrors
+}
+
+// scalarString accepts both string and numeric JSON values, so IDs can be
+// numbers and prices can be quoted
+func scalarString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// lineAt returns the line of the first value at or after offset,
+// skipping the whitespace and comma separating array elements
+func lineAt(data []byte, offset int64) int {
+	for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,", data[offset]) >= 0 {
+		offset++
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// parseYAML reads a YAML sequence of products
+func parseYAML(data []byte) ([]entry, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: expected a YAML list of products", root.Line)
+	}
+
+	entries := make([]entry, 0, len(root.Content))
+	for _, node := range root.Content {
+		var e entry
+		if node.Kind != yaml.MappingNode {
+			e.invalid = []RowError{{Line: node.Line, Message: "expected a product mapping"}}
+		} else if err := node.Decode(&e); err != nil {
+			e = entry{invalid: yamlDecodeError(node.Line, err)}
+		}
+		e.line = node.Line
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// parseCSV reads products from a CSV file with a header row.
+// Columns are matched by name; id, name, price and category are required.
+func parseCSV(data []byte) ([]entry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"id", "name", "price", "category"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("line 1: missing column %q", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var entries []entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		entries = append(entries, entry{
+			line:        line,
+			ID:          field(record, "id"),
+			Name:        field(record, "name"),
+			Price:       field(record, "price"),
+			Currency:    field(record, "currency"),
+			Category:    field(record, "category"),
+			Description: field(record, "description"),
+			ImageURL:    field(record, "imageurl"),
+		})
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,213 @@
+package catalog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []models.Product
+		// wantRows lists the line and field of every expected row error
+		wantRows []RowError
+		// wantParseErr expects the file to be rejected before validation
+		wantParseErr bool
+		wantErr      error
+	}{
+		{
+			name: "json",
+			file: "catalog.json",
+			content: `[
+  {"id": "waffle", "name": "Waffle", "price": 6.5, "category": "Waffle"},
+  {"id": 7, "name": " Tea ", "price": "250", "currency": "JPY", "category": "Drink", "imageUrl": "tea.png"}
+]`,
+			want: []models.Product{
+				{ID: "waffle", Name: "Waffle", Price: models.NewMoney(650, "USD"), Category: "Waffle"},
+				{ID: "7", Name: "Tea", Price: models.NewMoney(250, "JPY"), Category: "Drink", ImageURL: "tea.png"},
+			},
+		},
+		{
+			name: "yaml",
+			file: "catalog.yml",
+			content: `- id: waffle
+  name: Waffle
+  price: "6.50"
+  category: Waffle
+  description: Crispy
+- id: brulee
+  name: Crème Brûlée
+  price: 7
+  currency: EUR
+  category: Dessert
+`,
+			want: []models.Product{
+				{ID: "waffle", Name: "Waffle", Price: models.NewMoney(650, "USD"), Category: "Waffle", Description: "Crispy"},
+				{ID: "brulee", Name: "Crème Brûlée", Price: models.NewMoney(700, "EUR"), Category: "Dessert"},
+			},
+		},
+		{
+			name: "csv",
+			file: "catalog.csv",
+			content: `ID, Name, Price, Category, Currency
+waffle, Waffle, 6.50, Waffle,
+tea, Tea, 2.5, Drink, GBP
+`,
+			want: []models.Product{
+				{ID: "waffle", Name: "Waffle", Price: models.NewMoney(650, "USD"), Category: "Waffle"},
+				{ID: "tea", Name: "Tea", Price: models.NewMoney(250, "GBP"), Category: "Drink"},
+			},
+		},
+		{
+			name:    "empty yaml",
+			file:    "catalog.yaml",
+			content: "",
+			want:    []models.Product{},
+		},
+		{
+			name: "json row errors",
+			file: "catalog.json",
+			content: `[
+  {"id": "a", "name": "A", "price": "1.001", "category": "Waffle"},
+  {"id": "a", "name": "", "price": -1, "category": ""},
+  "not a product",
+  {"id": "b", "name": "B", "price": "1", "category": "Waffle", "currency": "usd"},
+  {"id": "c", "name": ["C"], "price": "1", "category": "Waffle"}
+]`,
+			wantRows: []RowError{
+				{Line: 2, Field: "price"},
+				{Line: 3, Field: "id"},
+				{Line: 3, Field: "name"},
+				{Line: 3, Field: "category"},
+				{Line: 3, Field: "price"},
+				{Line: 4},
+				{Line: 5, Field: "currency"},
+				{Line: 6, Field: "name"},
+			},
+		},
+		{
+			name: "yaml row errors",
+			file: "catalog.yaml",
+			content: `- id: a
+  name: A
+  category: Waffle
+- just a string
+- id: b
+  name: B
+  price: 0
+  category: Waffle
+- id: c
+  name: C
+  price: 1
+  category: [Waffle]
+`,
+			wantRows: []RowError{
+				{Line: 1, Field: "price"},
+				{Line: 4},
+				{Line: 5, Field: "price"},
+				{Line: 12},
+			},
+		},
+		{
+			name: "csv row errors",
+			file: "catalog.csv",
+			content: `id,name,price,category
+a,A,free,Waffle
+a,B,1,Waffle
+,C,1,
+`,
+			wantRows: []RowError{
+				{Line: 2, Field: "price"},
+				{Line: 3, Field: "id"},
+				{Line: 4, Field: "id"},
+				{Line: 4, Field: "category"},
+			},
+		},
+		{
+			name:         "csv missing column",
+			file:         "catalog.csv",
+			content:      "id,name,category\na,A,Waffle\n",
+			wantParseErr: true,
+		},
+		{
+			name:         "json not an array",
+			file:         "catalog.json",
+			content:      `{"id": "a"}`,
+			wantParseErr: true,
+		},
+		{
+			name:         "malformed json",
+			file:         "catalog.json",
+			content:      `[{"id": "a",]`,
+			wantParseErr: true,
+		},
+		{
+			name:    "unsupported format",
+			file:    "catalog.txt",
+			content: "a",
+			wantErr: ErrUnsupportedFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write catalog: %v", err)
+			}
+
+			products, err := Load(path)
+			switch {
+			case tt.wantParseErr:
+				if err == nil {
+					t.Fatal("Load() succeeded, want an error")
+				}
+				return
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case tt.wantRows != nil:
+				checkRowErrors(t, err, tt.wantRows)
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			if len(products) != len(tt.want) {
+				t.Fatalf("Load() = %+v, want %+v", products, tt.want)
+			}
+			for i := range products {
+				if products[i] != tt.want[i] {
+					t.Errorf("product %d = %+v, want %+v", i, products[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// checkRowErrors compares the line and field of every row error of err with want
+func checkRowErrors(t *testing.T, err error, want []RowError) {
+	t.Helper()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Load() error = %v, want a *ValidationError", err)
+	}
+	got := validationErr.Errors
+	if len(got) != len(want) {
+		t.Fatalf("row errors = %v, want %d errors at %v", got, len(want), want)
+	}
+	for i := range got {
+		if got[i].Line != want[i].Line || got[i].Field != want[i].Field {
+			t.Errorf("row error %d = %v, want line %d field %q", i, got[i], want[i].Line, want[i].Field)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/services"
+)
+
+// Populate creates or updates every product in the configured product store.
+// It goes through the product service so creation and update times are maintained.
+// It returns how many products were created and updated.
+func Populate(ctx context.Context, productService services.ProductService, products []models.Product) (created int, updated int, err error) {
+	for i := range products {
+		product := products[i]
+
+		existing, err := productService.GetProductByID(ctx, product.ID)
+		if err != nil {
+			return created, updated, fmt.Errorf("failed to look up product %s: %w", product.ID, err)
+		}
+
+		if existing == nil {
+			if _, err := productService.CreateProduct(ctx, &product); err != nil {
+				return created, updated, fmt.Errorf("failed to create product %s: %w", product.ID, err)
+			}
+			created++
+			continue
+		}
+
+		if _, err := productService.UpdateProduct(ctx, &product); err != nil {
+			return created, updated, fmt.Errorf("failed to update product %s: %w", product.ID, err)
+		}
+		updated++
+	}
+
+	return created, updated, nil
+}
@@ -1,29 +1,347 @@
 package config
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/logging"
+	"github.com/jilani-go/glofox/internal/tracing"
+)
+
+// Storage backends
+const (
+	BackendMemory = "memory"
+	BackendSQLite = "sqlite"
+	// BackendIndex is a memory-mapped promo index file; promo codes only
+	BackendIndex = "index"
+)
+
+// API key stores
+const (
+	// AuthStoreConfig serves the two keys of the auth config
+	AuthStoreConfig = "config"
+	// AuthStoreFile serves the hashed keys of a JSON or YAML file
+	AuthStoreFile = "file"
+	// AuthStoreSQLite serves hashed keys managed with keyctl
+	AuthStoreSQLite = "sqlite"
+)
+
+// Promo quorum modes
+const (
+	// QuorumNOfM requires a code in at least Required sources
+	QuorumNOfM = "n_of_m"
+	// QuorumAll requires a code in every source
+	QuorumAll = "all"
+	// QuorumAny requires a code in at least one source
+	QuorumAny = "any"
+	// QuorumWeighted requires the weights of the sources holding a code to add up to Required
+	QuorumWeighted = "weighted"
+)
+
+// maxIndexSources is the number of sources a promo index can hold
+const maxIndexSources = 64
+
+// sourceNamePattern matches promo source names, which are also used in table names
+var sourceNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
 
 // ServerConfig holds http server config.
 type ServerConfig struct {
-	Port         string        `json:"port"`
-	ReadTimeout  time.Duration `json:"readTimeout"`
-	WriteTimeout time.Duration `json:"writeTimeout"`
-	IdleTimeout  time.Duration `json:"idleTimeout"`
+	Port            string   `json:"port" yaml:"port"`
+	ReadTimeout     Duration `json:"readTimeout" yaml:"readTimeout"`
+	WriteTimeout    Duration `json:"writeTimeout" yaml:"writeTimeout"`
+	IdleTimeout     Duration `json:"idleTimeout" yaml:"idleTimeout"`
+	ShutdownTimeout Duration `json:"shutdownTimeout" yaml:"shutdownTimeout"`
+}
+
+// StorageConfig holds product and order persistence config.
+type StorageConfig struct {
+	// Backend is either "sqlite" or "memory"
+	Backend             string `json:"backend" yaml:"backend"`
+	ProductDatabasePath string `json:"productDatabasePath" yaml:"productDatabasePath"`
+	OrderDatabasePath   string `json:"orderDatabasePath" yaml:"orderDatabasePath"`
+	// IdempotencyTTL is how long order responses are kept for replay to
+	// requests retried with the same Idempotency-Key
+	IdempotencyTTL Duration `json:"idempotencyTTL" yaml:"idempotencyTTL"`
+	// QueryTimeout bounds every SQLite product, order, idempotency and API
+	// key operation; 0 leaves them to the request deadline
+	QueryTimeout Duration `json:"queryTimeout" yaml:"queryTimeout"`
+}
+
+// PromoConfig holds promo code source and storage config.
+type PromoConfig struct {
+	// Backend is "sqlite", "memory" or "index"
+	Backend      string `json:"backend" yaml:"backend"`
+	DatabasePath string `json:"databasePath" yaml:"databasePath"`
+	// IndexPath is the promo index file used by the index backend
+	IndexPath string `json:"indexPath" yaml:"indexPath"`
+	// BloomBitsPerKey sizes the index's Bloom filter; 0 builds none
+	BloomBitsPerKey int `json:"bloomBitsPerKey" yaml:"bloomBitsPerKey"`
+	// BatchSize is the number of codes inserted per SQLite transaction
+	BatchSize int `json:"batchSize" yaml:"batchSize"`
+	// WorkerCount is the number of parallel loading workers
+	WorkerCount int `json:"workerCount" yaml:"workerCount"`
+	// CreateIndexes is accepted for older config files; promo codes are
+	// always looked up through the primary key of a single table
+	CreateIndexes bool `json:"createIndexes" yaml:"createIndexes"`
+	// Sources are the coupon feeds a code is looked up in
+	Sources []PromoSourceConfig `json:"sources" yaml:"sources"`
+	// Quorum decides how many sources a code must appear in
+	Quorum QuorumConfig `json:"quorum" yaml:"quorum"`
+	// WatchInterval is how often source files are checked for changes; 0 disables watching
+	WatchInterval Duration `json:"watchInterval" yaml:"watchInterval"`
+	// ReadOnly serves a database or index built offline by promoctl instead of loading sources
+	ReadOnly bool `json:"readOnly" yaml:"readOnly"`
+	// Limits cap the redemptions of codes whose promotion sets no limits of its own
+	Limits RedemptionLimitsConfig `json:"limits" yaml:"limits"`
+	// CampaignsFile is a JSON or YAML file of campaigns with validity windows
+	// and order constraints for their codes; codes in no campaign are always valid
+	CampaignsFile string `json:"campaignsFile" yaml:"campaignsFile"`
+	// PromotionsFile is a JSON or YAML file of the discounts granted by codes;
+	// without one the built-in HAPPYHOURS and BUYGETONE promotions are used
+	PromotionsFile string `json:"promotionsFile" yaml:"promotionsFile"`
+	// LookupTimeout bounds looking a code up in every source; 0 leaves it
+	// to the request deadline
+	LookupTimeout Duration `json:"lookupTimeout" yaml:"lookupTimeout"`
+}
+
+// RedemptionLimitsConfig caps how often a promo code can be redeemed; 0 means unlimited.
+type RedemptionLimitsConfig struct {
+	// MaxRedemptions caps the redemptions across all customers
+	MaxRedemptions int `json:"maxRedemptions" yaml:"maxRedemptions"`
+	// MaxPerCustomer caps the redemptions by one customer; orders then need a customer ID
+	MaxPerCustomer int `json:"maxPerCustomer" yaml:"maxPerCustomer"`
+	// SingleUse allows a single redemption of each code
+	SingleUse bool `json:"singleUse" yaml:"singleUse"`
+}
+
+// PromoSourceConfig is a named coupon feed file.
+type PromoSourceConfig struct {
+	// Name identifies the source; lowercase letters, digits and underscores
+	Name string `json:"name" yaml:"name"`
+	Path string `json:"path" yaml:"path"`
+	// Weight counts towards a weighted quorum, defaults to 1
+	Weight int `json:"weight,omitempty" yaml:"weight,omitempty"`
+}
+
+// QuorumConfig is the rule a promo code must satisfy across sources.
+type QuorumConfig struct {
+	// Mode is one of n_of_m, all, any or weighted
+	Mode string `json:"mode" yaml:"mode"`
+	// Required is the number of sources (n_of_m) or total weight (weighted) needed
+	Required int `json:"required,omitempty" yaml:"required,omitempty"`
+}
+
+// AuthConfig holds API authentication config.
+type AuthConfig struct {
+	// Store is where API keys come from: "config", "file" or "sqlite"
+	Store string `json:"store" yaml:"store"`
+	// APIKey grants the orders:read and orders:write scopes; config store only
+	APIKey string `json:"apiKey" yaml:"apiKey"`
+	// AdminAPIKey grants the catalog:admin and promo:admin scopes; config store only
+	AdminAPIKey string `json:"adminApiKey" yaml:"adminApiKey"`
+	// KeysFile is the JSON or YAML file of hashed keys used by the file store
+	KeysFile string `json:"keysFile" yaml:"keysFile"`
+	// DatabasePath is the SQLite database used by the sqlite store
+	DatabasePath string `json:"databasePath" yaml:"databasePath"`
+}
+
+// CatalogConfig holds product catalog config.
+type CatalogConfig struct {
+	// SeedFile is a JSON, YAML or CSV file of products loaded on startup
+	SeedFile string `json:"seedFile" yaml:"seedFile"`
+}
+
+// LogConfig holds logging config.
+type LogConfig struct {
+	// Level is the minimum level logged: debug, info, warn or error
+	Level string `json:"level" yaml:"level"`
+	// Format is "text" for key=value lines or "json" for one object per line
+	Format string `json:"format" yaml:"format"`
+}
+
+// TracingConfig holds OpenTelemetry tracing config.
+type TracingConfig struct {
+	// Exporter is where spans go: none, stdout or file
+	Exporter string `json:"exporter" yaml:"exporter"`
+	// FilePath is the file the file exporter appends OTLP/JSON lines to
+	FilePath string `json:"filePath" yaml:"filePath"`
+	// SampleRatio is the share of new traces recorded, from 0 to 1
+	SampleRatio float64 `json:"sampleRatio" yaml:"sampleRatio"`
 }
 
 // Config holds the application's config.
 type Config struct {
-	Server ServerConfig `json:"server"`
+	Server  ServerConfig  `json:"server" yaml:"server"`
+	Storage StorageConfig `json:"storage" yaml:"storage"`
+	Promo   PromoConfig   `json:"promo" yaml:"promo"`
+	Auth    AuthConfig    `json:"auth" yaml:"auth"`
+	Catalog CatalogConfig `json:"catalog" yaml:"catalog"`
+	Log     LogConfig     `json:"log" yaml:"log"`
+	Tracing TracingConfig `json:"tracing" yaml:"tracing"`
 }
 
-// Load creates and returns a new config with default values.
-func Load() *Config {
-	cfg := &Config{
+// Default returns a new config with default values.
+func Default() *Config {
+	return &Config{
 		Server: ServerConfig{
-			Port:         "8080", // Default HTTP port
-			ReadTimeout:  5 * time.Second,
-			WriteTimeout: 10 * time.Second,
-			IdleTimeout:  15 * time.Second,
+			Port:            "8080", // Default HTTP port
+			ReadTimeout:     Duration(5 * time.Second),
+			WriteTimeout:    Duration(10 * time.Second),
+			IdleTimeout:     Duration(15 * time.Second),
+			ShutdownTimeout: Duration(30 * time.Second),
+		},
+		Storage: StorageConfig{
+			Backend:             BackendSQLite,
+			ProductDatabasePath: "data/products.db",
+			OrderDatabasePath:   "data/orders.db",
+			IdempotencyTTL:      Duration(24 * time.Hour),
+			QueryTimeout:        Duration(5 * time.Second),
+		},
+		Promo: PromoConfig{
+			Backend:         BackendSQLite,
+			DatabasePath:    "data/promo_codes.db",
+			IndexPath:       "data/promo_codes.idx",
+			BloomBitsPerKey: 10,    // About 1% false positives
+			BatchSize:       50000, // Insert 50k records per transaction
+			WorkerCount:     runtime.NumCPU(),
+			CreateIndexes:   true,
+			Sources: []PromoSourceConfig{
+				{Name: "couponbase1", Path: "internal/repository/promofiles/couponbase1", Weight: 1},
+				{Name: "couponbase2", Path: "internal/repository/promofiles/couponbase2", Weight: 1},
+				{Name: "couponbase3", Path: "internal/repository/promofiles/couponbase3", Weight: 1},
+			},
+			// A code must appear in at least two of the three coupon bases
+			Quorum:        QuorumConfig{Mode: QuorumNOfM, Required: 2},
+			LookupTimeout: Duration(2 * time.Second),
 		},
+		Auth: AuthConfig{
+			Store:        AuthStoreConfig,
+			DatabasePath: "data/auth.db",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: logging.FormatText,
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracing.ExporterNone,
+			FilePath:    "data/traces.jsonl",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Validate checks the config and returns every problem found.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port > 0 && port < 65536, "server.port: %q is not a valid TCP port", c.Server.Port)
+	check(c.Server.ReadTimeout > 0, "server.readTimeout: must be positive")
+	check(c.Server.WriteTimeout > 0, "server.writeTimeout: must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idleTimeout: must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdownTimeout: must be positive")
+
+	check(validBackend(c.Storage.Backend), "storage.backend: must be %q or %q", BackendSQLite, BackendMemory)
+	if c.Storage.Backend == BackendSQLite {
+		check(c.Storage.ProductDatabasePath != "", "storage.productDatabasePath: must not be empty")
+		check(c.Storage.OrderDatabasePath != "", "storage.orderDatabasePath: must not be empty")
+	}
+	check(c.Storage.IdempotencyTTL > 0, "storage.idempotencyTTL: must be positive")
+	check(c.Storage.QueryTimeout >= 0, "storage.queryTimeout: must not be negative")
+
+	check(validBackend(c.Promo.Backend) || c.Promo.Backend == BackendIndex,
+		"promo.backend: must be %q, %q or %q", BackendSQLite, BackendMemory, BackendIndex)
+	switch c.Promo.Backend {
+	case BackendSQLite:
+		check(c.Promo.DatabasePath != "", "promo.databasePath: must not be empty")
+		check(c.Promo.BatchSize > 0, "promo.batchSize: must be positive")
+	case BackendIndex:
+		check(c.Promo.IndexPath != "", "promo.indexPath: must not be empty")
+		check(c.Promo.BloomBitsPerKey >= 0, "promo.bloomBitsPerKey: must not be negative")
+		check(len(c.Promo.Sources) <= maxIndexSources, "promo.sources: the index backend supports at most %d sources", maxIndexSources)
+	}
+	check(c.Promo.WorkerCount > 0, "promo.workerCount: must be positive")
+	check(len(c.Promo.Sources) > 0, "promo.sources: must list at least one source")
+	names := make(map[string]bool, len(c.Promo.Sources))
+	totalWeight := 0
+	for i, source := range c.Promo.Sources {
+		check(sourceNamePattern.MatchString(source.Name), "promo.sources[%d].name: %q must be lowercase letters, digits and underscores", i, source.Name)
+		check(!names[source.Name], "promo.sources[%d].name: duplicate source %q", i, source.Name)
+		check(source.Path != "", "promo.sources[%d].path: must not be empty", i)
+		check(source.Weight > 0, "promo.sources[%d].weight: must be positive", i)
+		names[source.Name] = true
+		totalWeight += source.Weight
+	}
+	check(c.Promo.WatchInterval >= 0, "promo.watchInterval: must not be negative")
+	check(c.Promo.LookupTimeout >= 0, "promo.lookupTimeout: must not be negative")
+	check(!c.Promo.ReadOnly || c.Promo.Backend != BackendMemory, "promo.readOnly: not supported by the %q backend", BackendMemory)
+	check(c.Promo.Limits.MaxRedemptions >= 0, "promo.limits.maxRedemptions: must not be negative")
+	check(c.Promo.Limits.MaxPerCustomer >= 0, "promo.limits.maxPerCustomer: must not be negative")
+	switch c.Promo.Quorum.Mode {
+	case QuorumAll, QuorumAny:
+	case QuorumNOfM:
+		check(c.Promo.Quorum.Required > 0 && c.Promo.Quorum.Required <= len(c.Promo.Sources),
+			"promo.quorum.required: must be between 1 and the number of sources (%d)", len(c.Promo.Sources))
+	case QuorumWeighted:
+		check(c.Promo.Quorum.Required > 0 && c.Promo.Quorum.Required <= totalWeight,
+			"promo.quorum.required: must be between 1 and the total source weight (%d)", totalWeight)
+	default:
+		check(false, "promo.quorum.mode: must be one of %q, %q, %q or %q", QuorumNOfM, QuorumAll, QuorumAny, QuorumWeighted)
 	}
-	return cfg
+
+	switch c.Auth.Store {
+	case AuthStoreConfig:
+		// The keys are checked when the server starts, so tools sharing
+		// the config such as promoctl do not need them
+	case AuthStoreFile:
+		check(c.Auth.KeysFile != "", "auth.keysFile: must not be empty")
+	case AuthStoreSQLite:
+		check(c.Auth.DatabasePath != "", "auth.databasePath: must not be empty")
+	default:
+		check(false, "auth.store: must be %q, %q or %q", AuthStoreConfig, AuthStoreFile, AuthStoreSQLite)
+	}
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level: %q must be debug, info, warn or error", c.Log.Level)
+	check(c.Log.Format == logging.FormatText || c.Log.Format == logging.FormatJSON,
+		"log.format: must be %q or %q", logging.FormatText, logging.FormatJSON)
+
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout:
+	case tracing.ExporterFile:
+		check(c.Tracing.FilePath != "", "tracing.filePath: must not be empty")
+	default:
+		check(false, "tracing.exporter: must be %q, %q or %q", tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterFile)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sampleRatio: must be between 0 and 1")
+
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy of the config with secrets masked, for printing.
+func (c *Config) Redacted() *Config {
+	redacted := *c
+	redacted.Promo.Sources = append([]PromoSourceConfig(nil), c.Promo.Sources...)
+	if redacted.Auth.APIKey != "" {
+		redacted.Auth.APIKey = "********"
+	}
+	if redacted.Auth.AdminAPIKey != "" {
+		redacted.Auth.AdminAPIKey = "********"
+	}
+	return &redacted
+}
+
+// validBackend reports whether a storage backend name is supported
+func validBackend(backend string) bool {
+	return backend == BackendSQLite || backend == BackendMemory
 }
@@ -0,0 +1,349 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Duration is a time.Duration written as a Go duration string (e.g. "5s") in config files.
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes a duration string (e.g. "5s")
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"5s\": %w", err)
+	}
+	return d.set(value)
+}
+
+// UnmarshalYAML decodes a duration string (e.g. "5s")
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	return d.set(node.Value)
+}
+
+// set parses a duration string
+func (d *Duration) set(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Options are command line settings that control loading rather than the service.
+type Options struct {
+	// ConfigFile is the JSON or YAML file the config was read from, if any
+	ConfigFile string
+	// PrintConfig asks for the effective config to be printed instead of starting
+	PrintConfig bool
+}
+
+// setting is a config value that can be overridden by an environment variable and a flag
+type setting struct {
+	env   []string
+	flag  string
+	usage string
+	apply func(cfg *Config, value string) error
+}
+
+// settings lists every overridable config value
+var settings = []setting{
+	{[]string{"PORT", "SERVER_PORT"}, "port", "HTTP port", func(c *Config, v string) error {
+		c.Server.Port = v
+		return nil
+	}},
+	{[]string{"SERVER_READ_TIMEOUT"}, "read-timeout", "HTTP read timeout", durationSetter(func(c *Config) *Duration { return &c.Server.ReadTimeout })},
+	{[]string{"SERVER_WRITE_TIMEOUT"}, "write-timeout", "HTTP write timeout", durationSetter(func(c *Config) *Duration { return &c.Server.WriteTimeout })},
+	{[]string{"SERVER_IDLE_TIMEOUT"}, "idle-timeout", "HTTP idle timeout", durationSetter(func(c *Config) *Duration { return &c.Server.IdleTimeout })},
+	{[]string{"SERVER_SHUTDOWN_TIMEOUT"}, "shutdown-timeout", "graceful shutdown timeout", durationSetter(func(c *Config) *Duration { return &c.Server.ShutdownTimeout })},
+	{[]string{"STORAGE_BACKEND"}, "storage-backend", "product and order storage: sqlite or memory", func(c *Config, v string) error {
+		c.Storage.Backend = v
+		return nil
+	}},
+	{[]string{"STORAGE_PRODUCT_DATABASE"}, "product-database", "SQLite product database path", func(c *Config, v string) error {
+		c.Storage.ProductDatabasePath = v
+		return nil
+	}},
+	{[]string{"STORAGE_ORDER_DATABASE"}, "order-database", "SQLite order database path", func(c *Config, v string) error {
+		c.Storage.OrderDatabasePath = v
+		return nil
+	}},
+	{[]string{"STORAGE_QUERY_TIMEOUT"}, "query-timeout", "SQLite product, order and API key operation timeout, 0 disables", durationSetter(func(c *Config) *Duration { return &c.Storage.QueryTimeout })},
+	{[]string{"STORAGE_IDEMPOTENCY_TTL"}, "idempotency-ttl", "how long order responses are kept for Idempotency-Key retries", durationSetter(func(c *Config) *Duration { return &c.Storage.IdempotencyTTL })},
+	{[]string{"PROMO_BACKEND"}, "promo-backend", "promo code storage: sqlite, memory or index", func(c *Config, v string) error {
+		c.Promo.Backend = v
+		return nil
+	}},
+	{[]string{"PROMO_DATABASE"}, "promo-database", "SQLite promo code database path", func(c *Config, v string) error {
+		c.Promo.DatabasePath = v
+		return nil
+	}},
+	{[]string{"PROMO_INDEX"}, "promo-index", "promo index file path", func(c *Config, v string) error {
+		c.Promo.IndexPath = v
+		return nil
+	}},
+	{[]string{"PROMO_BLOOM_BITS_PER_KEY"}, "promo-bloom-bits", "promo index Bloom filter bits per code, 0 disables", intSetter(func(c *Config) *int { return &c.Promo.BloomBitsPerKey })},
+	{[]string{"PROMO_BATCH_SIZE"}, "promo-batch-size", "promo codes inserted per transaction", intSetter(func(c *Config) *int { return &c.Promo.BatchSize })},
+	{[]string{"PROMO_WORKER_COUNT"}, "promo-workers", "parallel promo loading workers", intSetter(func(c *Config) *int { return &c.Promo.WorkerCount })},
+	{[]string{"PROMO_CREATE_INDEXES"}, "promo-create-indexes", "create promo code indexes after loading (true/false)", func(c *Config, v string) error {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		c.Promo.CreateIndexes = parsed
+		return nil
+	}},
+	{[]string{"PROMO_READ_ONLY"}, "promo-read-only", "serve a promo database or index built by promoctl (true/false)", func(c *Config, v string) error {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		c.Promo.ReadOnly = parsed
+		return nil
+	}},
+	{[]string{"PROMO_SOURCES", "PROMO_FILES"}, "promo-sources", "comma separated coupon feeds as name=path or path", func(c *Config, v string) error {
+		c.Promo.Sources = parseSources(splitList(v))
+		return nil
+	}},
+	{[]string{"PROMO_QUORUM"}, "promo-quorum", "promo quorum mode: n_of_m, all, any or weighted", func(c *Config, v string) error {
+		c.Promo.Quorum.Mode = v
+		return nil
+	}},
+	{[]string{"PROMO_QUORUM_REQUIRED"}, "promo-quorum-required", "sources or weight a promo code needs", intSetter(func(c *Config) *int { return &c.Promo.Quorum.Required })},
+	{[]string{"PROMO_WATCH_INTERVAL"}, "promo-watch-interval", "how often to check promo sources for changes, 0 disables", durationSetter(func(c *Config) *Duration { return &c.Promo.WatchInterval })},
+	{[]string{"PROMO_LOOKUP_TIMEOUT"}, "promo-lookup-timeout", "promo code source lookup timeout, 0 disables", durationSetter(func(c *Config) *Duration { return &c.Promo.LookupTimeout })},
+	{[]string{"PROMO_MAX_REDEMPTIONS"}, "promo-max-redemptions", "redemptions allowed per promo code, 0 is unlimited", intSetter(func(c *Config) *int { return &c.Promo.Limits.MaxRedemptions })},
+	{[]string{"PROMO_MAX_PER_CUSTOMER"}, "promo-max-per-customer", "redemptions allowed per promo code and customer, 0 is unlimited", intSetter(func(c *Config) *int { return &c.Promo.Limits.MaxPerCustomer })},
+	{[]string{"PROMO_SINGLE_USE"}, "promo-single-use", "allow each promo code to be redeemed once (true/false)", func(c *Config, v string) error {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		c.Promo.Limits.SingleUse = parsed
+		return nil
+	}},
+	{[]string{"PROMO_CAMPAIGNS_FILE"}, "promo-campaigns", "JSON or YAML file of promo campaigns", func(c *Config, v string) error {
+		c.Promo.CampaignsFile = v
+		return nil
+	}},
+	{[]string{"PROMO_PROMOTIONS_FILE"}, "promo-promotions", "JSON or YAML file of promotions granted by promo codes", func(c *Config, v string) error {
+		c.Promo.PromotionsFile = v
+		return nil
+	}},
+	{[]string{"AUTH_STORE"}, "auth-store", "API key store: config, file or sqlite", func(c *Config, v string) error {
+		c.Auth.Store = v
+		return nil
+	}},
+	{[]string{"AUTH_API_KEY"}, "api-key", "API key for order requests", func(c *Config, v string) error {
+		c.Auth.APIKey = v
+		return nil
+	}},
+	{[]string{"AUTH_ADMIN_API_KEY"}, "admin-api-key", "API key for catalog administration", func(c *Config, v string) error {
+		c.Auth.AdminAPIKey = v
+		return nil
+	}},
+	{[]string{"AUTH_KEYS_FILE"}, "auth-keys-file", "JSON or YAML file of hashed API keys", func(c *Config, v string) error {
+		c.Auth.KeysFile = v
+		return nil
+	}},
+	{[]string{"AUTH_DATABASE"}, "auth-database", "SQLite API key database path", func(c *Config, v string) error {
+		c.Auth.DatabasePath = v
+		return nil
+	}},
+	{[]string{"CATALOG_FILE"}, "catalog-file", "JSON, YAML or CSV product file loaded on startup", func(c *Config, v string) error {
+		c.Catalog.SeedFile = v
+		return nil
+	}},
+	{[]string{"LOG_LEVEL"}, "log-level", "minimum log level: debug, info, warn or error", func(c *Config, v string) error {
+		c.Log.Level = v
+		return nil
+	}},
+	{[]string{"LOG_FORMAT"}, "log-format", "log output format: text or json", func(c *Config, v string) error {
+		c.Log.Format = v
+		return nil
+	}},
+	{[]string{"TRACING_EXPORTER"}, "tracing-exporter", "trace exporter: none, stdout or file", func(c *Config, v string) error {
+		c.Tracing.Exporter = v
+		return nil
+	}},
+	{[]string{"TRACING_FILE"}, "tracing-file", "file the file trace exporter appends to", func(c *Config, v string) error {
+		c.Tracing.FilePath = v
+		return nil
+	}},
+	{[]string{"TRACING_SAMPLE_RATIO"}, "tracing-sample-ratio", "share of new traces recorded, from 0 to 1", func(c *Config, v string) error {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		c.Tracing.SampleRatio = parsed
+		return nil
+	}},
+}
+
+// Load builds the config in layers: defaults, then the config file
+// (--config or CONFIG_FILE), then environment variables, then command line
+// flags. args are the command line arguments without the program name.
+// The result is validated and every problem is reported at once.
+func Load(args []string) (*Config, Options, error) {
+	var options Options
+
+	// Parse the flags first so --config can pick the file, but apply them last
+	flags := flag.NewFlagSet("app", flag.ContinueOnError)
+	flags.StringVar(&options.ConfigFile, "config", os.Getenv("CONFIG_FILE"), "JSON or YAML config file")
+	flags.BoolVar(&options.PrintConfig, "print-config", false, "print the effective config and exit")
+	values := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		values[s.flag] = flags.String(s.flag, "", fmt.Sprintf("%s (env %s)", s.usage, strings.Join(s.env, ", ")))
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, options, err
+	}
+
+	cfg := Default()
+
+	if options.ConfigFile != "" {
+		if err := loadFile(cfg, options.ConfigFile); err != nil {
+			return nil, options, err
+		}
+	}
+
+	for _, s := range settings {
+		for _, name := range s.env {
+			if value, ok := os.LookupEnv(name); ok && value != "" {
+				if err := s.apply(cfg, value); err != nil {
+					return nil, options, fmt.Errorf("invalid %s: %w", name, err)
+				}
+			}
+		}
+	}
+
+	// Only flags given on the command line override earlier layers
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name && flagErr == nil {
+				if err := s.apply(cfg, *values[f.Name]); err != nil {
+					flagErr = fmt.Errorf("invalid --%s: %w", f.Name, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, options, flagErr
+	}
+
+	// Sources without an explicit weight count once
+	for i := range cfg.Promo.Sources {
+		if cfg.Promo.Sources[i].Weight == 0 {
+			cfg.Promo.Sources[i].Weight = 1
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, options, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return cfg, options, nil
+}
+
+// loadFile merges a JSON or YAML config file into cfg.
+// Fields missing from the file keep their current value; unknown fields are an error.
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(cfg)
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(cfg)
+	default:
+		return fmt.Errorf("unsupported config file format: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// durationSetter returns a setting that parses a duration into a config field
+func durationSetter(field func(*Config) *Duration) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		return field(c).set(v)
+	}
+}
+
+// intSetter returns a setting that parses an integer into a config field
+func intSetter(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = parsed
+		return nil
+	}
+}
+
+// parseSources reads "name=path" entries; a bare path is named after its file
+func parseSources(entries []string) []PromoSourceConfig {
+	sources := make([]PromoSourceConfig, 0, len(entries))
+	for _, entry := range entries {
+		name, path, found := strings.Cut(entry, "=")
+		if !found {
+			path = entry
+			name = sourceNameFromPath(path)
+		}
+		sources = append(sources, PromoSourceConfig{Name: strings.TrimSpace(name), Path: strings.TrimSpace(path), Weight: 1})
+	}
+	return sources
+}
+
+// sourceNameFromPath derives a valid source name from a file name
+func sourceNameFromPath(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '_'
+		}
+	}, base)
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		// file is written to a config file of the given extension when set
+		file    string
+		fileExt string
+		env     map[string]string
+		args    []string
+		check   func(t *testing.T, cfg *Config)
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Port != "8080" || cfg.Storage.Backend != BackendSQLite {
+					t.Errorf("port %q backend %q, want the defaults", cfg.Server.Port, cfg.Storage.Backend)
+				}
+			},
+		},
+		{
+			name:    "yaml file over defaults",
+			file:    "server:\n  port: \"9000\"\n  readTimeout: 7s\nstorage:\n  backend: memory\n",
+			fileExt: ".yaml",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Port != "9000" || cfg.Server.ReadTimeout != Duration(7*time.Second) || cfg.Storage.Backend != BackendMemory {
+					t.Errorf("port %q read timeout %v backend %q, want the file's values",
+						cfg.Server.Port, time.Duration(cfg.Server.ReadTimeout), cfg.Storage.Backend)
+				}
+				if cfg.Server.WriteTimeout != Default().Server.WriteTimeout {
+					t.Errorf("write timeout %v, want the default kept", time.Duration(cfg.Server.WriteTimeout))
+				}
+			},
+		},
+		{
+			name:    "json file over defaults",
+			file:    `{"server": {"port": "9001"}, "log": {"level": "debug"}}`,
+			fileExt: ".json",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Port != "9001" || cfg.Log.Level != "debug" {
+					t.Errorf("port %q log level %q, want the file's values", cfg.Server.Port, cfg.Log.Level)
+				}
+			},
+		},
+		{
+			name:    "environment over file",
+			file:    "server:\n  port: \"9000\"\n",
+			fileExt: ".yaml",
+			env:     map[string]string{"SERVER_PORT": "9100", "STORAGE_IDEMPOTENCY_TTL": "1h"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Port != "9100" || cfg.Storage.IdempotencyTTL != Duration(time.Hour) {
+					t.Errorf("port %q idempotency TTL %v, want the environment's values",
+						cfg.Server.Port, time.Duration(cfg.Storage.IdempotencyTTL))
+				}
+			},
+		},
+		{
+			name:    "flags over environment",
+			file:    "server:\n  port: \"9000\"\n",
+			fileExt: ".yaml",
+			env:     map[string]string{"SERVER_PORT": "9100", "LOG_LEVEL": "warn"},
+			args:    []string{"--port", "9200"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Port != "9200" || cfg.Log.Level != "warn" {
+					t.Errorf("port %q log level %q, want the flag's port and the environment's level", cfg.Server.Port, cfg.Log.Level)
+				}
+			},
+		},
+		{
+			name: "empty environment is ignored",
+			env:  map[string]string{"SERVER_PORT": ""},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Port != "8080" {
+					t.Errorf("port %q, want the default", cfg.Server.Port)
+				}
+			},
+		},
+		{
+			name: "promo sources with default weights",
+			args: []string{"--promo-sources", "a=/tmp/a,/tmp/couponbase9", "--promo-quorum", "any"},
+			check: func(t *testing.T, cfg *Config) {
+				sources := cfg.Promo.Sources
+				if len(sources) != 2 || sources[0].Name != "a" || sources[1].Name != "couponbase9" {
+					t.Fatalf("sources = %+v, want a and couponbase9", sources)
+				}
+				for _, source := range sources {
+					if source.Weight != 1 {
+						t.Errorf("source %s weight %d, want 1", source.Name, source.Weight)
+					}
+				}
+			},
+		},
+		{
+			name:    "unknown file field",
+			file:    "server:\n  prot: \"9000\"\n",
+			fileExt: ".yaml",
+			wantErr: "prot",
+		},
+		{
+			name:    "unsupported file format",
+			file:    "port = 9000",
+			fileExt: ".toml",
+			wantErr: ".toml",
+		},
+		{
+			name:    "invalid environment value",
+			env:     map[string]string{"STORAGE_QUERY_TIMEOUT": "soon"},
+			wantErr: "STORAGE_QUERY_TIMEOUT",
+		},
+		{
+			name:    "invalid flag value",
+			args:    []string{"--promo-batch-size", "many"},
+			wantErr: "--promo-batch-size",
+		},
+		{
+			name: "no default API keys",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Auth.APIKey != "" || cfg.Auth.AdminAPIKey != "" {
+					t.Errorf("API keys %q and %q, want none by default", cfg.Auth.APIKey, cfg.Auth.AdminAPIKey)
+				}
+			},
+		},
+		{
+			name:    "invalid result",
+			args:    []string{"--storage-backend", "postgres", "--port", "0"},
+			wantErr: "invalid configuration",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Keep the environment of the test run out of the config
+			t.Setenv("CONFIG_FILE", "")
+			for _, s := range settings {
+				for _, name := range s.env {
+					t.Setenv(name, "")
+				}
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			args := tt.args
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), "config"+tt.fileExt)
+				if err := os.WriteFile(path, []byte(tt.file), 0644); err != nil {
+					t.Fatalf("failed to write config file: %v", err)
+				}
+				args = append([]string{"--config", path}, args...)
+			}
+
+			cfg, _, err := Load(args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadConfigFileFromEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  port: \"9300\"\n"), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("PORT", "")
+	t.Setenv("SERVER_PORT", "")
+
+	cfg, options, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if options.ConfigFile != path || cfg.Server.Port != "9300" {
+		t.Errorf("config file %q port %q, want %q and 9300", options.ConfigFile, cfg.Server.Port, path)
+	}
+}
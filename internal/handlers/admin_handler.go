@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/services"
+)
+
+// AdminHandler handles operational requests
+type AdminHandler struct {
+	reloadService services.PromoReloadService
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(reloadService services.PromoReloadService) *AdminHandler {
+	return &AdminHandler{
+		reloadService: reloadService,
+	}
+}
+
+// ReloadPromoCodes handles POST /api/admin/promo/reload requests
+// Starts rebuilding the promo codes from their sources in the background
+func (h *AdminHandler) ReloadPromoCodes(w http.ResponseWriter, r *http.Request) {
+	if err := h.reloadService.StartReload("api"); err != nil {
+		switch {
+		case errors.Is(err, services.ErrPromoReloadInProgress):
+			respondWithError(w, http.StatusConflict, "A promo reload is already in progress")
+		case errors.Is(err, services.ErrReloadNotSupported):
+			respondWithError(w, http.StatusNotImplemented, "The promo backend does not support reloading")
+		case errors.Is(err, services.ErrPromoUnavailable):
+			respondWithError(w, http.StatusServiceUnavailable, "Promo codes are not loaded, please retry")
+		default:
+			respondWithError(w, http.StatusInternalServerError, "Failed to start promo reload")
+		}
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, toPromoReloadResponse(h.reloadService.Status()))
+}
+
+// GetPromoReloadStatus handles GET /api/admin/promo/reload requests
+// Returns the state of the running or most recent promo reload
+func (h *AdminHandler) GetPromoReloadStatus(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, toPromoReloadResponse(h.reloadService.Status()))
+}
+
+// toPromoReloadResponse converts a reload status into its API representation
+func toPromoReloadResponse(status services.PromoReloadStatus) PromoReloadStatus {
+	response := PromoReloadStatus{
+		Running:    status.Running,
+		Trigger:    status.Trigger,
+		DurationMs: status.Duration.Milliseconds(),
+		Codes:      status.Codes,
+		Error:      status.Error,
+	}
+	if !status.StartedAt.IsZero() {
+		response.StartedAt = timePtr(status.StartedAt)
+	}
+	if !status.FinishedAt.IsZero() {
+		response.FinishedAt = timePtr(status.FinishedAt)
+	}
+	return response
+}
+
+// timePtr returns a pointer to a copy of t
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/services"
+)
+
+// APIKeyHeader carries the client's API key
+const APIKeyHeader = "api_key"
+
+// apiKeyContextKey is the request context key of the authenticated API key
+type apiKeyContextKey struct{}
+
+// AuthMiddleware authenticates requests by their API key and checks its scopes
+type AuthMiddleware struct {
+	authService services.AuthService
+}
+
+// NewAuthMiddleware creates a new auth middleware
+func NewAuthMiddleware(authService services.AuthService) *AuthMiddleware {
+	return &AuthMiddleware{
+		authService: authService,
+	}
+}
+
+// Require returns a middleware that only lets requests through whose API key
+// grants the scope. The key is available to handlers via APIKeyFromContext.
+func (m *AuthMiddleware) Require(scope models.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, err := m.authService.Authenticate(r.Context(), r.Header.Get(APIKeyHeader))
+			if errors.Is(err, services.ErrUnauthenticated) {
+				respondWithError(w, http.StatusUnauthorized, "Invalid or missing API key")
+				return
+			}
+			if err != nil {
+				respondWithInternalError(w, err, "Failed to check API key")
+				return
+			}
+			if err := m.authService.Authorize(key, scope); err != nil {
+				respondWithError(w, http.StatusForbidden, "API key lacks the "+string(scope)+" scope")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key)))
+		})
+	}
+}
+
+// APIKeyFromContext returns the API key a request was authenticated with, or nil
+func APIKeyFromContext(ctx context.Context) *models.APIKey {
+	key, _ := ctx.Value(apiKeyContextKey{}).(*models.APIKey)
+	return key
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/repository"
+	"github.com/jilani-go/glofox/internal/services"
+)
+
+// failingAuthService fails every lookup as if the key store were down
+type failingAuthService struct{}
+
+func (failingAuthService) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	return nil, errors.New("key store unavailable")
+}
+
+func (failingAuthService) Authorize(key *models.APIKey, scope models.Scope) error {
+	return nil
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	now := time.Now()
+	key := func(id, secret string, scopes ...models.Scope) models.APIKey {
+		return models.APIKey{ID: id, Hash: models.HashAPIKey(secret), Scopes: scopes, CreatedAt: now}
+	}
+	expired := key("expired", "expired-secret", models.ScopeOrdersRead)
+	expired.ExpiresAt = now.Add(-time.Minute)
+	revoked := key("revoked", "revoked-secret", models.ScopeOrdersRead)
+	revoked.RevokedAt = now.Add(-time.Minute)
+
+	repo, err := repository.NewInMemoryAPIKeyRepository([]models.APIKey{
+		key("reader", "reader-secret", models.ScopeOrdersRead),
+		key("admin", "admin-secret", models.ScopeOrdersRead, models.ScopeCatalogAdmin),
+		expired,
+		revoked,
+	})
+	if err != nil {
+		t.Fatalf("failed to create API key repository: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		authService services.AuthService
+		apiKey      string
+		scope       models.Scope
+		wantCode    int
+		// wantKeyID is the ID of the key the handler sees on success
+		wantKeyID string
+	}{
+		{name: "granted scope", apiKey: "reader-secret", scope: models.ScopeOrdersRead, wantCode: http.StatusOK, wantKeyID: "reader"},
+		{name: "one of several scopes", apiKey: "admin-secret", scope: models.ScopeCatalogAdmin, wantCode: http.StatusOK, wantKeyID: "admin"},
+		{name: "missing key", scope: models.ScopeOrdersRead, wantCode: http.StatusUnauthorized},
+		{name: "unknown key", apiKey: "apitest", scope: models.ScopeOrdersRead, wantCode: http.StatusUnauthorized},
+		{name: "expired key", apiKey: "expired-secret", scope: models.ScopeOrdersRead, wantCode: http.StatusUnauthorized},
+		{name: "revoked key", apiKey: "revoked-secret", scope: models.ScopeOrdersRead, wantCode: http.StatusUnauthorized},
+		{name: "missing scope", apiKey: "reader-secret", scope: models.ScopeCatalogAdmin, wantCode: http.StatusForbidden},
+		{
+			name:        "key store down",
+			authService: failingAuthService{},
+			apiKey:      "reader-secret",
+			scope:       models.ScopeOrdersRead,
+			wantCode:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authService := tt.authService
+			if authService == nil {
+				authService = services.NewAuthService(repo)
+			}
+
+			var gotKeyID string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if key := APIKeyFromContext(r.Context()); key != nil {
+					gotKeyID = key.ID
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+			handler := NewAuthMiddleware(authService).Require(tt.scope)(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/order", nil)
+			if tt.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tt.apiKey)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if gotKeyID != tt.wantKeyID {
+				t.Errorf("handler saw key %q, want %q", gotKeyID, tt.wantKeyID)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/services"
+)
+
+// HealthHandler handles liveness, readiness and status probes
+type HealthHandler struct {
+	service services.HealthService
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(service services.HealthService) *HealthHandler {
+	return &HealthHandler{
+		service: service,
+	}
+}
+
+// Liveness handles GET /healthz requests
+// Reports that the process is up and serving HTTP, even while starting
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readiness handles GET /readyz requests
+// Returns 200 once the catalog and promo codes are loaded and the databases
+// answer, 503 otherwise
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	ready, checks := h.service.Ready(r.Context())
+	response := ReadinessResponse{Status: "ready", Checks: toHealthCheckResults(checks)}
+	code := http.StatusOK
+	if !ready {
+		response.Status = "not_ready"
+		code = http.StatusServiceUnavailable
+	}
+	respondWithJSON(w, code, response)
+}
+
+// GetStatus handles GET /status requests
+// Returns the readiness checks and the load progress of every promo source
+func (h *HealthHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	status := h.service.Status(r.Context())
+	now := time.Now()
+
+	response := ServiceStatusResponse{
+		Status:        "ready",
+		StartedAt:     status.StartedAt.UTC(),
+		UptimeSeconds: int64(now.Sub(status.StartedAt).Seconds()),
+		Checks:        toHealthCheckResults(status.Checks),
+		PromoSources:  make([]PromoSourceStatus, len(status.PromoSources)),
+	}
+	if !status.Ready {
+		response.Status = "starting"
+	}
+	for i, source := range status.PromoSources {
+		item := PromoSourceStatus{
+			Name:  source.Name,
+			State: source.State,
+			Lines: source.Lines,
+			Codes: source.Codes,
+			Error: source.Error,
+		}
+		if !source.StartedAt.IsZero() {
+			item.StartedAt = timePtr(source.StartedAt)
+			end := now
+			if !source.FinishedAt.IsZero() {
+				end = source.FinishedAt
+				item.FinishedAt = timePtr(source.FinishedAt)
+			}
+			item.DurationMs = end.Sub(source.StartedAt).Milliseconds()
+		}
+		response.PromoSources[i] = item
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// toHealthCheckResults converts check results into their API representation
+func toHealthCheckResults(results []services.HealthCheckResult) []HealthCheckResult {
+	response := make([]HealthCheckResult, len(results))
+	for i, result := range results {
+		response[i] = HealthCheckResult{Name: result.Name, OK: result.Error == "", Error: result.Error}
+	}
+	return response
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/jilani-go/glofox/internal/services"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client's idempotency key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response replayed for a retried request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// maxIdempotencyKeyLength caps the length of idempotency keys
+	maxIdempotencyKeyLength = 255
+)
+
+// responseRecorder passes a response through while keeping a copy of it
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+// WriteHeader records the status code
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the body
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+// withIdempotencyKey runs next once per idempotency key. The response is
+// stored with a fingerprint of the request, identical retries get it replayed
+// and a retry with another payload is rejected. Server errors are not stored,
+// so the request can be retried. Keys are scoped to the caller's API key, so
+// clients cannot collide with or replay each other's requests.
+func withIdempotencyKey(service services.IdempotencyService, key string, w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if len(key) > maxIdempotencyKeyLength {
+		respondWithError(w, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if apiKey := APIKeyFromContext(r.Context()); apiKey != nil {
+		key = apiKey.ID + ":" + key
+	}
+
+	owner, record, err := service.Begin(r.Context(), key, requestFingerprint(r, body))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			respondWithError(w, http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+		case errors.Is(err, services.ErrIdempotencyKeyInProgress):
+			respondWithError(w, http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+		default:
+			respondWithInternalError(w, err, "Failed to check Idempotency-Key")
+		}
+		return
+	}
+
+	// Replay the response of the first request
+	if record != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		w.WriteHeader(record.StatusCode)
+		w.Write(record.Body)
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: w}
+	next(recorder, r)
+
+	// Settle the key even if the client went away, or retries would find it
+	// in progress until the lock times out
+	ctx := context.WithoutCancel(r.Context())
+	if recorder.statusCode == 0 || recorder.statusCode >= http.StatusInternalServerError {
+		err = service.Abandon(ctx, key, owner)
+	} else {
+		err = service.Complete(ctx, key, owner, recorder.statusCode, recorder.body.Bytes())
+	}
+	if errors.Is(err, services.ErrIdempotencyKeyTakenOver) {
+		slog.WarnContext(ctx, "Idempotency key was taken over before the response was stored", "key", key)
+	} else if err != nil {
+		slog.ErrorContext(ctx, "Failed to store response for idempotency key", "key", key, "error", err)
+	}
+}
+
+// requestFingerprint identifies a request by its method, path and body
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.Path+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
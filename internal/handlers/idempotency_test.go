@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/repository"
+	"github.com/jilani-go/glofox/internal/services"
+)
+
+func TestWithIdempotencyKey(t *testing.T) {
+	// The steps run in order against one store, each step's handler
+	// answering with status and a body counting its runs
+	steps := []struct {
+		name     string
+		apiKeyID string
+		key      string
+		body     string
+		status   int
+		wantCode int
+		wantBody string
+		// wantReplayed means the response is replayed without running the handler
+		wantReplayed bool
+	}{
+		{name: "first request", apiKeyID: "alice", key: "k1", body: `{"a":1}`, status: http.StatusCreated, wantCode: http.StatusCreated, wantBody: "run 1"},
+		{name: "retry replayed", apiKeyID: "alice", key: "k1", body: `{"a":1}`, status: http.StatusCreated, wantCode: http.StatusCreated, wantBody: "run 1", wantReplayed: true},
+		{name: "client error stored", apiKeyID: "alice", key: "k2", body: `{}`, status: http.StatusBadRequest, wantCode: http.StatusBadRequest, wantBody: "run 2"},
+		{name: "client error retry", apiKeyID: "alice", key: "k2", body: `{}`, status: http.StatusCreated, wantCode: http.StatusBadRequest, wantBody: "run 2", wantReplayed: true},
+		{name: "other payload", apiKeyID: "alice", key: "k1", body: `{"a":2}`, status: http.StatusCreated, wantCode: http.StatusUnprocessableEntity},
+		{name: "same key of another caller", apiKeyID: "bob", key: "k1", body: `{"a":1}`, status: http.StatusCreated, wantCode: http.StatusCreated, wantBody: "run 3"},
+		{name: "server error", apiKeyID: "alice", key: "k3", body: `{}`, status: http.StatusInternalServerError, wantCode: http.StatusInternalServerError, wantBody: "run 4"},
+		{name: "server error retried", apiKeyID: "alice", key: "k3", body: `{}`, status: http.StatusCreated, wantCode: http.StatusCreated, wantBody: "run 5"},
+		{name: "key too long", apiKeyID: "alice", key: strings.Repeat("k", maxIdempotencyKeyLength+1), body: `{}`, status: http.StatusCreated, wantCode: http.StatusBadRequest},
+	}
+
+	service := services.NewIdempotencyService(repository.NewInMemoryIdempotencyRepository(), time.Hour)
+	runs := 0
+	for _, step := range steps {
+		next := func(w http.ResponseWriter, r *http.Request) {
+			runs++
+			w.WriteHeader(step.status)
+			fmt.Fprintf(w, "run %d", runs)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(step.body))
+		req = req.WithContext(context.WithValue(req.Context(), apiKeyContextKey{}, &models.APIKey{ID: step.apiKeyID}))
+		rec := httptest.NewRecorder()
+		before := runs
+		withIdempotencyKey(service, step.key, rec, req, next)
+
+		if rec.Code != step.wantCode {
+			t.Fatalf("%s: status = %d, want %d: %s", step.name, rec.Code, step.wantCode, rec.Body)
+		}
+		if step.wantBody != "" && rec.Body.String() != step.wantBody {
+			t.Errorf("%s: body = %q, want %q", step.name, rec.Body, step.wantBody)
+		}
+		if replayed := rec.Header().Get(IdempotentReplayedHeader) == "true"; replayed != step.wantReplayed {
+			t.Errorf("%s: replayed = %v, want %v", step.name, replayed, step.wantReplayed)
+		}
+		// Only the steps answered by a fresh run of the handler run it
+		wantRun := step.wantBody != "" && !step.wantReplayed
+		if ran := runs > before; ran != wantRun {
+			t.Errorf("%s: handler ran = %v, want %v", step.name, ran, wantRun)
+		}
+	}
+}
+
+func TestWithIdempotencyKeyInProgress(t *testing.T) {
+	service := services.NewIdempotencyService(repository.NewInMemoryIdempotencyRepository(), time.Hour)
+	newRequest := func() *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/order", strings.NewReader(`{}`))
+	}
+
+	// A retry arriving while the first request still runs is turned away
+	var retry *httptest.ResponseRecorder
+	first := httptest.NewRecorder()
+	withIdempotencyKey(service, "k1", first, newRequest(), func(w http.ResponseWriter, r *http.Request) {
+		retry = httptest.NewRecorder()
+		withIdempotencyKey(service, "k1", retry, newRequest(), func(w http.ResponseWriter, r *http.Request) {
+			t.Error("retry ran while the first request was in progress")
+		})
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	if first.Code != http.StatusCreated {
+		t.Errorf("first status = %d, want %d", first.Code, http.StatusCreated)
+	}
+	if retry.Code != http.StatusConflict {
+		t.Errorf("retry status = %d, want %d", retry.Code, http.StatusConflict)
+	}
+}
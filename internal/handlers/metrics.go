@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jilani-go/glofox/internal/metrics"
+)
+
+// statusRecorder remembers the status code of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+// WriteHeader records the status code
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	if r.statusCode == 0 {
+		r.statusCode = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records an implicit 200 status
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	if r.statusCode == 0 {
+		r.statusCode = http.StatusOK
+	}
+	return r.ResponseWriter.Write(data)
+}
+
+// status returns the recorded status code, 200 if nothing was written
+func (r *statusRecorder) status() int {
+	if r.statusCode == 0 {
+		return http.StatusOK
+	}
+	return r.statusCode
+}
+
+// Metrics is a middleware recording the latency and status of every
+// request. Requests are labelled with the route template rather than the
+// path, so IDs in the path don't create a series per resource.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		metrics.ObserveHTTPRequest(routeTemplate(r), r.Method, recorder.status(), time.Since(start))
+	})
+}
+
+// routeContextKey is the context key of the route template found by MatchRoute
+type routeContextKey struct{}
+
+// MatchRoute returns a middleware finding the route of router a request
+// matches before the router serves it. Middlewares wrapped by it label the
+// request with the route template, which they could not find themselves
+// outside the router, and so also see the 404 and 405 responses the router
+// writes without calling any route.
+func MatchRoute(router *mux.Router) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			template := "unknown"
+			var match mux.RouteMatch
+			if router.Match(r, &match) && match.Route != nil {
+				if t, err := match.Route.GetPathTemplate(); err == nil {
+					template = t
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeContextKey{}, template)))
+		})
+	}
+}
+
+// routeTemplate returns the template of the route matching a request,
+// "unknown" if no route matches
+func routeTemplate(r *http.Request) string {
+	if template, ok := r.Context().Value(routeContextKey{}).(string); ok {
+		return template
+	}
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unknown"
+}
@@ -1,13 +1,20 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
 	"github.com/jilani-go/glofox/internal/models"
 	"github.com/jilani-go/glofox/internal/services"
+	"github.com/jilani-go/glofox/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // OrderHandler handles order-related requests
@@ -15,119 +22,439 @@ type OrderHandler struct {
 	orderService   services.OrderService
 	productService services.ProductService
 	validator      *validator.Validate
-	promoService   services.PromoService
+	// idempotencyService replays the responses of retried orders
+	idempotencyService services.IdempotencyService
 }
 
 // NewOrderHandler creates a new order handler
-func NewOrderHandler(orderService services.OrderService, productService services.ProductService, promoService services.PromoService) *OrderHandler {
+func NewOrderHandler(orderService services.OrderService, productService services.ProductService, idempotencyService services.IdempotencyService) *OrderHandler {
 	return &OrderHandler{
-		orderService:   orderService,
-		productService: productService,
-		validator:      validator.New(),
-		promoService:   promoService,
+		orderService:       orderService,
+		productService:     productService,
+		validator:          validator.New(),
+		idempotencyService: idempotencyService,
 	}
 }
 
 // PlaceOrder handles POST /api/order requests
-// Creates a new order with the provided items
+// Creates a new order with the provided items. Requests with an
+// Idempotency-Key header create at most one order per key.
 func (h *OrderHandler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
-	// Check for API key (authentication)
-	apiKey := r.Header.Get("api_key")
-	if apiKey != "apitest" {
-		respondWithError(w, http.StatusUnauthorized, "Invalid or missing API key")
+	// Retried requests with the same key get the first response
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+		withIdempotencyKey(h.idempotencyService, key, w, r, h.placeOrder)
+		return
+	}
+	h.placeOrder(w, r)
+}
+
+// placeOrder creates the order of an authenticated request
+func (h *OrderHandler) placeOrder(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.decodeOrder(w, r)
+	if !ok {
+		return
+	}
+
+	// Create the order via service
+	createdOrder, err := h.orderService.CreateOrder(r.Context(), order)
+	if err != nil {
+		respondWithOrderError(w, err, "Failed to create order: ")
+		return
+	}
+
+	// Create the order response
+	orderResponse, err := h.toOrderResponse(r.Context(), createdOrder)
+	if err != nil {
+		respondWithInternalError(w, err, "Error retrieving product details")
+		return
+	}
+
+	// Set response headers
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	// Encode and return the response
+	if err := json.NewEncoder(w).Encode(orderResponse); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
+
+// QuoteOrder handles POST /api/order/quote requests
+// Validates and prices an order like PlaceOrder without placing it
+func (h *OrderHandler) QuoteOrder(w http.ResponseWriter, r *http.Request) {
+	order, ok := h.decodeOrder(w, r)
+	if !ok {
+		return
+	}
+
+	quoted, err := h.orderService.QuoteOrder(r.Context(), order)
+	if err != nil {
+		respondWithOrderError(w, err, "Failed to quote order: ")
+		return
+	}
+
+	orderResponse, err := h.toOrderResponse(r.Context(), quoted)
+	if err != nil {
+		respondWithInternalError(w, err, "Error retrieving product details")
 		return
 	}
 
+	respondWithJSON(w, http.StatusOK, OrderQuote{
+		Items:      orderResponse.Items,
+		Products:   orderResponse.Products,
+		Currency:   orderResponse.Currency,
+		Subtotal:   orderResponse.Subtotal,
+		Discount:   orderResponse.Discount,
+		Total:      orderResponse.Total,
+		CustomerID: orderResponse.CustomerID,
+		CouponCode: orderResponse.CouponCode,
+		Promotion:  orderResponse.Promotion,
+	})
+}
+
+// decodeOrder parses and validates an order request. On failure it writes
+// the error response and returns false.
+func (h *OrderHandler) decodeOrder(w http.ResponseWriter, r *http.Request) (*models.Order, bool) {
 	// Parse the request body into an OrderReq struct
 	var orderReq OrderReq
 	if err := json.NewDecoder(r.Body).Decode(&orderReq); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
-		return
+		return nil, false
 	}
 	defer r.Body.Close()
 
 	// Validate the request
 	if err := h.validator.Struct(orderReq); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Validation error: "+err.Error())
-		return
+		return nil, false
 	}
 
 	// Convert request to domain model
-	orderItems := make([]models.OrderItem, len(orderReq.Items))
-	for i, item := range orderReq.Items {
-		orderItems[i] = models.OrderItem{
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-		}
+	return &models.Order{
+		Items:      toOrderItems(orderReq.Items),
+		CustomerID: orderReq.CustomerID,
+		CouponCode: orderReq.CouponCode,
+	}, true
+}
+
+// respondWithOrderError reports why an order could not be created or quoted.
+// Unexpected errors are reported as internal errors prefixed with fallback.
+func respondWithOrderError(w http.ResponseWriter, err error, fallback string) {
+	var rejected *services.PromoRejectedError
+	switch {
+	case errors.As(err, &rejected):
+		respondWithPromoRejection(w, rejected.Reason)
+	case errors.Is(err, services.ErrProductNotFound):
+		respondWithError(w, http.StatusBadRequest, "One or more products not found")
+	case errors.Is(err, models.ErrCurrencyMismatch):
+		respondWithError(w, http.StatusUnprocessableEntity, "All products in an order must share a currency")
+	case errors.Is(err, models.ErrAmountOverflow):
+		respondWithError(w, http.StatusUnprocessableEntity, "Order total is too large")
+	case errors.Is(err, services.ErrPromoCodeExhausted):
+		respondWithError(w, http.StatusConflict, "Promo code has been fully redeemed")
+	case errors.Is(err, services.ErrCustomerLimitReached):
+		respondWithError(w, http.StatusConflict, "Promo code has already been redeemed the maximum number of times by this customer")
+	case errors.Is(err, services.ErrCustomerRequired):
+		respondWithError(w, http.StatusUnprocessableEntity, "customerId is required to redeem this promo code")
+	default:
+		respondWithInternalError(w, err, fallback+err.Error())
 	}
+}
 
-	order := &models.Order{
-		Items:      orderItems,
-		CouponCode: orderReq.CouponCode,
+// GetOrder handles GET /api/order/{orderId} requests
+// Returns a single order by ID
+func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	// Extract the order ID from the URL path
+	orderID := mux.Vars(r)["orderId"]
+
+	order, err := h.orderService.GetOrder(r.Context(), orderID)
+	if err != nil {
+		respondWithInternalError(w, err, "Failed to retrieve order")
+		return
+	}
+
+	// If order not found, return 404
+	if order == nil {
+		respondWithError(w, http.StatusNotFound, "Order not found")
+		return
 	}
-	valid, err := h.promoService.ValidatePromoCode(order.CouponCode)
+
+	orderResponse, err := h.toOrderResponse(r.Context(), order)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error validating promo code: "+err.Error())
+		respondWithInternalError(w, err, "Error retrieving product details")
 		return
 	}
-	if !valid {
-		respondWithError(w, http.StatusBadRequest, "Invalid promo code")
+
+	respondWithJSON(w, http.StatusOK, orderResponse)
+}
+
+// ListOrders handles GET /api/order requests
+// Returns a page of orders, optionally filtered by date range, coupon and product
+func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseOrderFilter(r.URL.Query())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Create the order via service
-	createdOrder, err := h.orderService.CreateOrder(order)
+	orders, total, err := h.orderService.ListOrders(r.Context(), filter)
 	if err != nil {
-		if errors.Is(err, services.ErrProductNotFound) {
-			respondWithError(w, http.StatusBadRequest, "One or more products not found")
+		if errors.Is(err, services.ErrInvalidOrderFilter) {
+			respondWithError(w, http.StatusBadRequest, "Invalid order filter")
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Failed to create order: "+err.Error())
+		respondWithInternalError(w, err, "Failed to list orders")
 		return
 	}
 
-	// Get product details for response
-	products := make([]Product, 0, len(createdOrder.Items))
-	for _, item := range createdOrder.Items {
-		product, err := h.productService.GetProductByID(item.ProductID)
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Error retrieving product details")
-			return
+	page := OrderPage{
+		Orders: make([]Order, 0, len(orders)),
+		Total:  total,
+		Offset: filter.Offset,
+		Limit:  filter.Limit,
+	}
+	if page.Limit == 0 {
+		page.Limit = services.DefaultOrderPageSize
+	}
+	page.Limit = min(page.Limit, services.MaxOrderPageSize)
+
+	// Look up the products of the whole page at once
+	pageOrders := make([]*models.Order, len(orders))
+	for i := range orders {
+		pageOrders[i] = &orders[i]
+	}
+	products, err := h.orderProducts(r.Context(), pageOrders...)
+	if err != nil {
+		respondWithInternalError(w, err, "Error retrieving product details")
+		return
+	}
+	for _, order := range pageOrders {
+		page.Orders = append(page.Orders, newOrderResponse(order, products))
+	}
+
+	respondWithJSON(w, http.StatusOK, page)
+}
+
+// orderActions maps the kitchen workflow actions to the status they move an order to
+var orderActions = map[string]models.OrderStatus{
+	"accept":   models.OrderStatusAccepted,
+	"prepare":  models.OrderStatusPreparing,
+	"ready":    models.OrderStatusReady,
+	"complete": models.OrderStatusCompleted,
+	"cancel":   models.OrderStatusCancelled,
+	"refund":   models.OrderStatusRefunded,
+}
+
+// UpdateOrderStatus handles PATCH /api/order/{orderId}/status requests
+// Moves an order to the status given in the request body
+func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	var statusReq OrderStatusReq
+	if err := json.NewDecoder(r.Body).Decode(&statusReq); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.validator.Struct(statusReq); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return
+	}
+
+	h.transitionOrder(r.Context(), w, mux.Vars(r)["orderId"], models.OrderStatus(statusReq.Status))
+}
+
+// ApplyOrderAction handles POST /api/order/{orderId}/{action} requests
+// Moves an order through the kitchen workflow (accept, prepare, ready, complete, cancel, refund)
+func (h *OrderHandler) ApplyOrderAction(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	status, ok := orderActions[vars["action"]]
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Unknown order action")
+		return
+	}
+
+	h.transitionOrder(r.Context(), w, vars["orderId"], status)
+}
+
+// transitionOrder moves an order to a new status and writes the updated order
+func (h *OrderHandler) transitionOrder(ctx context.Context, w http.ResponseWriter, orderID string, status models.OrderStatus) {
+	order, err := h.orderService.TransitionOrder(ctx, orderID, status)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOrderNotFound):
+			respondWithError(w, http.StatusNotFound, "Order not found")
+		case errors.Is(err, services.ErrInvalidOrderStatus):
+			respondWithError(w, http.StatusBadRequest, "Unknown order status")
+		case errors.Is(err, services.ErrInvalidTransition):
+			respondWithError(w, http.StatusConflict, "Order cannot move to status "+string(status))
+		case errors.Is(err, services.ErrOrderStatusChanged):
+			respondWithError(w, http.StatusConflict, "Order status was changed by another request")
+		default:
+			respondWithInternalError(w, err, "Failed to update order status")
 		}
+		return
+	}
+
+	orderResponse, err := h.toOrderResponse(ctx, order)
+	if err != nil {
+		respondWithInternalError(w, err, "Error retrieving product details")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, orderResponse)
+}
 
-		if product != nil {
-			products = append(products, Product{
-				ID:       product.ID,
-				Name:     product.Name,
-				Price:    product.Price,
-				Category: product.Category,
-			})
+// parseOrderFilter reads the order listing query parameters
+func parseOrderFilter(query url.Values) (models.OrderFilter, error) {
+	filter := models.OrderFilter{
+		CouponCode: query.Get("couponCode"),
+		ProductID:  query.Get("productId"),
+	}
+
+	var err error
+	if value := query.Get("from"); value != "" {
+		if filter.From, err = time.Parse(time.RFC3339, value); err != nil {
+			return filter, errors.New("invalid from: expected an RFC 3339 timestamp")
+		}
+	}
+	if value := query.Get("to"); value != "" {
+		if filter.To, err = time.Parse(time.RFC3339, value); err != nil {
+			return filter, errors.New("invalid to: expected an RFC 3339 timestamp")
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		if filter.Offset, err = strconv.Atoi(value); err != nil || filter.Offset < 0 {
+			return filter, errors.New("invalid offset: expected a non-negative integer")
+		}
+	}
+	if value := query.Get("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 1 {
+			return filter, errors.New("invalid limit: expected a positive integer")
 		}
 	}
 
-	// Create API response
-	apiItems := make([]OrderItem, len(createdOrder.Items))
-	for i, item := range createdOrder.Items {
-		apiItems[i] = OrderItem{
+	return filter, nil
+}
+
+// toOrderResponse converts a domain order into its API representation
+func (h *OrderHandler) toOrderResponse(ctx context.Context, order *models.Order) (Order, error) {
+	products, err := h.orderProducts(ctx, order)
+	if err != nil {
+		return Order{}, err
+	}
+	return newOrderResponse(order, products), nil
+}
+
+// newOrderResponse converts a domain order into its API representation,
+// taking the details of its items' products from products
+func newOrderResponse(order *models.Order, products map[string]models.Product) Order {
+	orderResponse := Order{
+		ID:         order.ID,
+		Items:      toOrderLines(order.Items),
+		Products:   make([]Product, 0, len(order.Items)),
+		Currency:   order.Total.Currency,
+		Subtotal:   order.Subtotal,
+		Discount:   order.Discount,
+		Total:      order.Total,
+		CustomerID: order.CustomerID,
+		CouponCode: order.CouponCode,
+		CreatedAt:  order.CreatedAt,
+		Status:     string(order.Status),
+	}
+	orderResponse.StatusHistory = make([]OrderStatusChange, len(order.StatusHistory))
+	for i, change := range order.StatusHistory {
+		orderResponse.StatusHistory[i] = OrderStatusChange{
+			Status: string(change.Status),
+			At:     change.At,
+		}
+	}
+	for _, item := range order.Items {
+		if product, ok := products[item.ProductID]; ok {
+			orderResponse.Products = append(orderResponse.Products, toProductResponse(product))
+		}
+	}
+	orderResponse.Promotion = toAppliedPromotion(order.Promotion)
+
+	return orderResponse
+}
+
+// orderProducts looks up the products of the orders' items for the response
+// with a single call, so a page of orders costs one lookup
+func (h *OrderHandler) orderProducts(ctx context.Context, orders ...*models.Order) (_ map[string]models.Product, err error) {
+	ctx, span := tracing.Start(ctx, "OrderHandler.orderProducts", attribute.Int("order.count", len(orders)))
+	defer func() { tracing.End(span, err) }()
+
+	seen := make(map[string]bool)
+	var ids []string
+	for _, order := range orders {
+		for _, item := range order.Items {
+			if !seen[item.ProductID] {
+				seen[item.ProductID] = true
+				ids = append(ids, item.ProductID)
+			}
+		}
+	}
+	return h.productService.GetProductsByIDs(ctx, ids)
+}
+
+// toOrderLines converts priced order items into their API representation
+func toOrderLines(items []models.OrderItem) []OrderLine {
+	lines := make([]OrderLine, len(items))
+	for i, item := range items {
+		lines[i] = OrderLine{
 			ProductID: item.ProductID,
 			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+			LineTotal: item.LineTotal,
+			Discount:  item.Discount,
+			Total:     item.Total,
 		}
 	}
+	return lines
+}
 
-	// Create the order response
-	orderResponse := Order{
-		ID:       createdOrder.ID,
-		Items:    apiItems,
-		Products: products,
+// toAppliedPromotion converts a promotion into its API representation, nil if there is none
+func toAppliedPromotion(promotion *models.Promotion) *AppliedPromotion {
+	if promotion == nil {
+		return nil
+	}
+	return &AppliedPromotion{
+		Code:        promotion.Code,
+		Type:        string(promotion.Discount.Type),
+		Description: promotion.Discount.Description,
 	}
+}
 
-	// Set response headers
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+// promoRejectionMessages describes every promo code rejection reason
+var promoRejectionMessages = map[services.PromoRejection]string{
+	services.RejectionInvalidFormat:   "Invalid promo code",
+	services.RejectionNotFound:        "Invalid promo code",
+	services.RejectionNotStarted:      "Promo code is not valid yet",
+	services.RejectionExpired:         "Promo code has expired",
+	services.RejectionNoEligibleItems: "Promo code does not apply to any product in the order",
+	services.RejectionBelowMinimum:    "Order total is below the promo code's minimum order value",
+}
 
-	// Encode and return the response
-	if err := json.NewEncoder(w).Encode(orderResponse); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		return
+// respondWithPromoRejection reports a rejected promo code with its reason.
+// Codes that are invalid by themselves are a bad request, codes the order
+// does not qualify for are unprocessable.
+func respondWithPromoRejection(w http.ResponseWriter, reason services.PromoRejection) {
+	code := http.StatusBadRequest
+	if reason == services.RejectionNoEligibleItems || reason == services.RejectionBelowMinimum {
+		code = http.StatusUnprocessableEntity
+	}
+	message, ok := promoRejectionMessages[reason]
+	if !ok {
+		message = "Invalid promo code"
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(ApiResponse{
+		Code:    code,
+		Type:    "error",
+		Message: message,
+		Reason:  string(reason),
+	})
 }
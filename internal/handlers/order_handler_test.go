@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/repository"
+	"github.com/jilani-go/glofox/internal/services"
+)
+
+// countingProductService counts the product lookups made through it
+type countingProductService struct {
+	services.ProductService
+	lookups int
+}
+
+func (s *countingProductService) GetProductByID(ctx context.Context, id string) (*models.Product, error) {
+	s.lookups++
+	return s.ProductService.GetProductByID(ctx, id)
+}
+
+func (s *countingProductService) GetProductsByIDs(ctx context.Context, ids []string) (map[string]models.Product, error) {
+	s.lookups++
+	return s.ProductService.GetProductsByIDs(ctx, ids)
+}
+
+func TestListOrders(t *testing.T) {
+	ctx := context.Background()
+	productRepo := repository.NewInMemoryProductRepository(true)
+	orderRepo := repository.NewInMemoryOrderRepository(productRepo)
+
+	// Three orders placed at the same instant and an older one, whose items
+	// include a product that was deleted since
+	placed := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	orders := []models.Order{
+		{Items: []models.OrderItem{{ProductID: "1", Quantity: 1}, {ProductID: "2", Quantity: 2}}, CreatedAt: placed},
+		{Items: []models.OrderItem{{ProductID: "2", Quantity: 1}}, CreatedAt: placed},
+		{Items: []models.OrderItem{{ProductID: "3", Quantity: 1}, {ProductID: "gone", Quantity: 1}}, CreatedAt: placed},
+		{Items: []models.OrderItem{{ProductID: "1", Quantity: 3}}, CreatedAt: placed.Add(-time.Hour), CouponCode: "HAPPYHRS"},
+	}
+	for i := range orders {
+		if _, err := orderRepo.Create(ctx, &orders[i]); err != nil {
+			t.Fatalf("Create() unexpected error: %v", err)
+		}
+	}
+
+	// Orders placed at the same instant come in ID order
+	sameInstant := []string{orders[0].ID, orders[1].ID, orders[2].ID}
+	sort.Strings(sameInstant)
+	newestFirst := append(sameInstant, orders[3].ID)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantIDs   []string
+		wantTotal int
+	}{
+		{name: "every order", query: "", wantCode: http.StatusOK, wantIDs: newestFirst, wantTotal: 4},
+		{name: "first page", query: "?limit=2", wantCode: http.StatusOK, wantIDs: newestFirst[:2], wantTotal: 4},
+		{name: "second page", query: "?limit=2&offset=2", wantCode: http.StatusOK, wantIDs: newestFirst[2:], wantTotal: 4},
+		{name: "past the end", query: "?offset=10", wantCode: http.StatusOK, wantIDs: []string{}, wantTotal: 4},
+		{name: "by coupon", query: "?couponCode=HAPPYHRS", wantCode: http.StatusOK, wantIDs: newestFirst[3:], wantTotal: 1},
+		{name: "by product", query: "?productId=3", wantCode: http.StatusOK, wantIDs: []string{orders[2].ID}, wantTotal: 1},
+		{name: "invalid limit", query: "?limit=0", wantCode: http.StatusBadRequest},
+		{name: "invalid range", query: "?from=2026-07-02T00:00:00Z&to=2026-07-01T00:00:00Z", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			productService := &countingProductService{ProductService: services.NewProductService(productRepo)}
+			orderService := services.NewOrderService(orderRepo, productRepo, nil, nil)
+			handler := NewOrderHandler(orderService, productService, nil)
+
+			rec := httptest.NewRecorder()
+			handler.ListOrders(rec, httptest.NewRequest(http.MethodGet, "/order"+tt.query, nil))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			var page OrderPage
+			if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			ids := make([]string, len(page.Orders))
+			for i, order := range page.Orders {
+				ids[i] = order.ID
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("orders = %v, want %v", ids, tt.wantIDs)
+			}
+			if page.Total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", page.Total, tt.wantTotal)
+			}
+			if len(page.Orders) > 0 && productService.lookups != 1 {
+				t.Errorf("product lookups = %d, want 1 for the page", productService.lookups)
+			}
+
+			// Every item's product is listed, except the deleted one
+			for _, order := range page.Orders {
+				var want []string
+				for _, item := range order.Items {
+					if item.ProductID != "gone" {
+						want = append(want, item.ProductID)
+					}
+				}
+				got := make([]string, len(order.Products))
+				for i, product := range order.Products {
+					got[i] = product.ID
+				}
+				if !slices.Equal(got, want) {
+					t.Errorf("order %s products = %v, want %v", order.ID, got, want)
+				}
+			}
+		})
+	}
+}
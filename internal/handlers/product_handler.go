@@ -1,22 +1,28 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"github.com/jilani-go/glofox/internal/models"
 	"github.com/jilani-go/glofox/internal/services"
 )
 
 // ProductHandler handles product-related requests
 type ProductHandler struct {
-	service services.ProductService
+	service   services.ProductService
+	validator *validator.Validate
 }
 
 // NewProductHandler creates a new product handler
 func NewProductHandler(service services.ProductService) *ProductHandler {
 	return &ProductHandler{
-		service: service,
+		service:   service,
+		validator: validator.New(),
 	}
 }
 
@@ -24,21 +30,16 @@ func NewProductHandler(service services.ProductService) *ProductHandler {
 // Returns a list of all available products
 func (h *ProductHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
 	// Get products from service
-	modelProducts, err := h.service.GetAllProducts()
+	modelProducts, err := h.service.GetAllProducts(r.Context())
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve products")
+		respondWithInternalError(w, err, "Failed to retrieve products")
 		return
 	}
 
 	// Convert model products to API products
 	products := make([]Product, 0, len(modelProducts))
 	for _, p := range modelProducts {
-		products = append(products, Product{
-			ID:       p.ID,
-			Name:     p.Name,
-			Price:    p.Price,
-			Category: p.Category,
-		})
+		products = append(products, toProductResponse(p))
 	}
 
 	// Set response headers
@@ -60,9 +61,9 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	productID := vars["productId"]
 
 	// Get product from service
-	modelProduct, err := h.service.GetProductByID(productID)
+	modelProduct, err := h.service.GetProductByID(r.Context(), productID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to retrieve product")
+		respondWithInternalError(w, err, "Failed to retrieve product")
 		return
 	}
 
@@ -73,12 +74,7 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Convert model product to API product
-	product := Product{
-		ID:       modelProduct.ID,
-		Name:     modelProduct.Name,
-		Price:    modelProduct.Price,
-		Category: modelProduct.Category,
-	}
+	product := toProductResponse(*modelProduct)
 
 	// Set response headers
 	w.Header().Set("Content-Type", "application/json")
@@ -91,6 +87,140 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// CreateProduct handles POST /api/product requests
+// Adds a product to the catalog
+func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	product, ok := h.decodeProductReq(w, r, models.DefaultCurrency)
+	if !ok {
+		return
+	}
+
+	created, err := h.service.CreateProduct(r.Context(), product)
+	if err != nil {
+		if errors.Is(err, services.ErrProductExists) {
+			respondWithError(w, http.StatusConflict, "A product with this ID already exists")
+			return
+		}
+		respondWithInternalError(w, err, "Failed to create product")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, toProductResponse(*created))
+}
+
+// UpdateProduct handles PUT /api/product/{productId} requests
+// Replaces the details of a catalog product
+func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
+	// The path decides which product is updated
+	productID := mux.Vars(r)["productId"]
+	existing, err := h.service.GetProductByID(r.Context(), productID)
+	if err != nil {
+		respondWithInternalError(w, err, "Failed to retrieve product")
+		return
+	}
+	if existing == nil {
+		respondWithError(w, http.StatusNotFound, "Product not found")
+		return
+	}
+
+	// Without a currency the price is in the product's current currency
+	product, ok := h.decodeProductReq(w, r, existing.Price.Currency)
+	if !ok {
+		return
+	}
+	if product.ID != "" && product.ID != productID {
+		respondWithError(w, http.StatusBadRequest, "Product ID in body does not match the URL")
+		return
+	}
+	product.ID = productID
+
+	updated, err := h.service.UpdateProduct(r.Context(), product)
+	if err != nil {
+		if errors.Is(err, services.ErrProductDoesNotExist) {
+			respondWithError(w, http.StatusNotFound, "Product not found")
+			return
+		}
+		respondWithInternalError(w, err, "Failed to update product")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, toProductResponse(*updated))
+}
+
+// DeleteProduct handles DELETE /api/product/{productId} requests
+// Removes a product from the catalog
+func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteProduct(r.Context(), mux.Vars(r)["productId"]); err != nil {
+		if errors.Is(err, services.ErrProductDoesNotExist) {
+			respondWithError(w, http.StatusNotFound, "Product not found")
+			return
+		}
+		respondWithInternalError(w, err, "Failed to delete product")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeProductReq parses and validates a product request body, reading the
+// price in defaultCurrency when the request gives no currency.
+// It writes the error response itself and reports whether decoding succeeded.
+func (h *ProductHandler) decodeProductReq(w http.ResponseWriter, r *http.Request, defaultCurrency string) (*models.Product, bool) {
+	var productReq ProductReq
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&productReq); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return nil, false
+	}
+	defer r.Body.Close()
+
+	// Validate the request
+	if err := h.validator.Struct(productReq); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return nil, false
+	}
+
+	// Parse the price exactly in the requested currency
+	currency := productReq.Currency
+	if currency == "" {
+		currency = defaultCurrency
+	}
+	price, err := models.ParseMoney(productReq.Price.String(), currency)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return nil, false
+	}
+	if price.IsNegative() || price.IsZero() {
+		respondWithError(w, http.StatusBadRequest, "Validation error: price must be greater than zero")
+		return nil, false
+	}
+
+	return &models.Product{
+		ID:          productReq.ID,
+		Name:        productReq.Name,
+		Price:       price,
+		Category:    productReq.Category,
+		Description: productReq.Description,
+		ImageURL:    productReq.ImageURL,
+	}, true
+}
+
+// toProductResponse converts a domain product into its API representation
+func toProductResponse(p models.Product) Product {
+	return Product{
+		ID:          p.ID,
+		Name:        p.Name,
+		Price:       p.Price,
+		Currency:    p.Price.Currency,
+		Category:    p.Category,
+		Description: p.Description,
+		ImageURL:    p.ImageURL,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
+
 // Helper function to respond with an error message
 func respondWithError(w http.ResponseWriter, code int, message string) {
 	response := ApiResponse{
@@ -103,3 +233,27 @@ func respondWithError(w http.ResponseWriter, code int, message string) {
 	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(response)
 }
+
+// respondWithInternalError reports an unexpected error. An operation that ran
+// out of time or needs promo codes that are not loaded is reported as 503 so the
+// client knows to retry.
+func respondWithInternalError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		respondWithError(w, http.StatusServiceUnavailable, "Request timed out, please retry")
+		return
+	case errors.Is(err, services.ErrPromoUnavailable):
+		respondWithError(w, http.StatusServiceUnavailable, "Promo codes are not loaded, please retry")
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, message)
+}
+
+// Helper function to respond with a JSON payload
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
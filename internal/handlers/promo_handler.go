@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/services"
+)
+
+// PromoHandler handles promo code requests
+type PromoHandler struct {
+	orderService services.OrderService
+	validator    *validator.Validate
+}
+
+// NewPromoHandler creates a new promo handler
+func NewPromoHandler(orderService services.OrderService) *PromoHandler {
+	return &PromoHandler{
+		orderService: orderService,
+		validator:    validator.New(),
+	}
+}
+
+// ValidatePromoCode handles POST /api/promo/validate requests
+// Checks a promo code, optionally against a cart, without placing an order
+func (h *PromoHandler) ValidatePromoCode(w http.ResponseWriter, r *http.Request) {
+	var req PromoValidateReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.validator.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return
+	}
+
+	result, ok := h.preview(r.Context(), w, req.Code, toOrderItems(req.Items))
+	if !ok {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// ValidatePromoCodes handles POST /api/promo/validate/batch requests
+// Checks several promo codes against the same cart
+func (h *PromoHandler) ValidatePromoCodes(w http.ResponseWriter, r *http.Request) {
+	var req PromoValidateBatchReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if err := h.validator.Struct(req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Validation error: "+err.Error())
+		return
+	}
+
+	items := toOrderItems(req.Items)
+	batch := PromoValidationBatch{Results: make([]PromoValidation, 0, len(req.Codes))}
+	for _, code := range req.Codes {
+		result, ok := h.preview(r.Context(), w, code, items)
+		if !ok {
+			return
+		}
+		batch.Results = append(batch.Results, result)
+	}
+	respondWithJSON(w, http.StatusOK, batch)
+}
+
+// preview validates one code against the cart. On failure it writes the
+// error response and returns false.
+func (h *PromoHandler) preview(ctx context.Context, w http.ResponseWriter, code string, items []models.OrderItem) (PromoValidation, bool) {
+	preview, err := h.orderService.PreviewPromoCode(ctx, code, items)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrProductNotFound):
+			respondWithError(w, http.StatusBadRequest, "One or more products not found")
+		case errors.Is(err, models.ErrCurrencyMismatch):
+			respondWithError(w, http.StatusUnprocessableEntity, "All products in an order must share a currency")
+		default:
+			respondWithInternalError(w, err, "Error validating promo code: "+err.Error())
+		}
+		return PromoValidation{}, false
+	}
+	return toPromoValidationResponse(code, preview), true
+}
+
+// toOrderItems converts requested items into order items
+func toOrderItems(items []OrderItem) []models.OrderItem {
+	orderItems := make([]models.OrderItem, len(items))
+	for i, item := range items {
+		orderItems[i] = models.OrderItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+		}
+	}
+	return orderItems
+}
+
+// toPromoValidationResponse converts a promo preview into its API representation
+func toPromoValidationResponse(code string, preview *services.PromoPreview) PromoValidation {
+	response := PromoValidation{
+		Code:           code,
+		Valid:          preview.Valid,
+		Reason:         string(preview.Reason),
+		MatchedSources: preview.MatchedSources,
+		Promotion:      toAppliedPromotion(preview.Promotion),
+	}
+	if !preview.Valid {
+		response.Message = promoRejectionMessages[preview.Reason]
+	}
+	if response.MatchedSources == nil {
+		response.MatchedSources = []string{}
+	}
+	if quorum := preview.Quorum; quorum != nil {
+		response.Rule = &PromoRuleOutcome{
+			Mode:      string(quorum.Mode),
+			Required:  quorum.Required,
+			Matched:   quorum.Matched,
+			Weight:    quorum.Weight,
+			Satisfied: quorum.Satisfied,
+		}
+	}
+	if campaign := preview.Campaign; campaign != nil {
+		response.Campaign = &PromoCampaign{
+			Name:       campaign.Name,
+			Categories: campaign.Categories,
+			ProductIDs: campaign.ProductIDs,
+		}
+		if !campaign.StartsAt.IsZero() {
+			response.Campaign.StartsAt = timePtr(campaign.StartsAt)
+		}
+		if !campaign.EndsAt.IsZero() {
+			response.Campaign.EndsAt = timePtr(campaign.EndsAt)
+		}
+		if !campaign.MinOrderValue.IsZero() {
+			minOrderValue := campaign.MinOrderValue
+			response.Campaign.MinOrderValue = &minOrderValue
+		}
+	}
+	if order := preview.Order; order != nil {
+		response.Preview = &PromoCartPreview{
+			Items:    toOrderLines(order.Items),
+			Currency: order.Total.Currency,
+			Subtotal: order.Subtotal,
+			Discount: order.Discount,
+			Total:    order.Total,
+		}
+	}
+	return response
+}
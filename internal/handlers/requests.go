@@ -1,30 +1,227 @@
 package handlers
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
 // Product represents a food product
 type Product struct {
-	ID       string  `json:"id"`
-	Name     string  `json:"name"`
-	Price    float64 `json:"price"`
-	Category string  `json:"category"`
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Price       models.Money `json:"price"`
+	Currency    string       `json:"currency"`
+	Category    string       `json:"category"`
+	Description string       `json:"description,omitempty"`
+	ImageURL    string       `json:"imageUrl,omitempty"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	UpdatedAt   time.Time    `json:"updatedAt"`
+}
+
+// ProductReq represents the API request for creating or updating a product
+type ProductReq struct {
+	ID          string      `json:"id,omitempty" validate:"omitempty,max=64"`
+	Name        string      `json:"name" validate:"required,max=200"`
+	Price       json.Number `json:"price" validate:"required"`
+	Currency    string      `json:"currency,omitempty" validate:"omitempty,iso4217"`
+	Category    string      `json:"category" validate:"required,max=100"`
+	Description string      `json:"description,omitempty" validate:"max=2000"`
+	ImageURL    string      `json:"imageUrl,omitempty" validate:"omitempty,url"`
 }
 
 // OrderItem represents an item in an order
 type OrderItem struct {
 	ProductID string `json:"productId" validate:"required"`
-	Quantity  int    `json:"quantity" validate:"required,min=1"`
+	Quantity  int    `json:"quantity" validate:"required,min=1,max=10000"`
 }
 
 // OrderReq represents the API request for placing an order
 type OrderReq struct {
+	// CustomerID identifies the customer for per-customer coupon limits
+	CustomerID string      `json:"customerId,omitempty" validate:"max=64"`
 	CouponCode string      `json:"couponCode,omitempty"`
 	Items      []OrderItem `json:"items" validate:"required,min=1,dive"`
 }
 
+// OrderLine represents a priced item in a placed order
+type OrderLine struct {
+	ProductID string       `json:"productId"`
+	Quantity  int          `json:"quantity"`
+	UnitPrice models.Money `json:"unitPrice"`
+	LineTotal models.Money `json:"lineTotal"`
+	Discount  models.Money `json:"discount"`
+	Total     models.Money `json:"total"`
+}
+
+// AppliedPromotion describes the coupon discount applied to an order
+type AppliedPromotion struct {
+	Code        string `json:"code"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
 // Order represents a placed order
 type Order struct {
-	ID       string      `json:"id"`
-	Items    []OrderItem `json:"items"`
-	Products []Product   `json:"products"`
+	ID       string       `json:"id"`
+	Items    []OrderLine  `json:"items"`
+	Products []Product    `json:"products"`
+	Currency string       `json:"currency"`
+	Subtotal models.Money `json:"subtotal"`
+	Discount models.Money `json:"discount"`
+	Total    models.Money `json:"total"`
+
+	CustomerID string            `json:"customerId,omitempty"`
+	CouponCode string            `json:"couponCode,omitempty"`
+	Promotion  *AppliedPromotion `json:"promotion,omitempty"`
+	CreatedAt  time.Time         `json:"createdAt"`
+
+	Status        string              `json:"status"`
+	StatusHistory []OrderStatusChange `json:"statusHistory"`
+}
+
+// OrderQuote represents a priced order that has not been placed
+type OrderQuote struct {
+	Items    []OrderLine  `json:"items"`
+	Products []Product    `json:"products"`
+	Currency string       `json:"currency"`
+	Subtotal models.Money `json:"subtotal"`
+	Discount models.Money `json:"discount"`
+	Total    models.Money `json:"total"`
+
+	CustomerID string            `json:"customerId,omitempty"`
+	CouponCode string            `json:"couponCode,omitempty"`
+	Promotion  *AppliedPromotion `json:"promotion,omitempty"`
+}
+
+// OrderStatusChange represents a status an order entered and when
+type OrderStatusChange struct {
+	Status string    `json:"status"`
+	At     time.Time `json:"at"`
+}
+
+// OrderStatusReq represents the API request for changing an order status
+type OrderStatusReq struct {
+	Status string `json:"status" validate:"required"`
+}
+
+// OrderPage represents a page of an order listing
+type OrderPage struct {
+	Orders []Order `json:"orders"`
+	Total  int     `json:"total"`
+	Offset int     `json:"offset"`
+	Limit  int     `json:"limit"`
+}
+
+// PromoReloadStatus represents the state of the running or most recent promo reload
+type PromoReloadStatus struct {
+	Running    bool           `json:"running"`
+	Trigger    string         `json:"trigger,omitempty"`
+	StartedAt  *time.Time     `json:"startedAt,omitempty"`
+	FinishedAt *time.Time     `json:"finishedAt,omitempty"`
+	DurationMs int64          `json:"durationMs"`
+	Codes      map[string]int `json:"codes"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// HealthCheckResult represents the outcome of a readiness check
+type HealthCheckResult struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// ReadinessResponse represents whether the service can handle requests
+type ReadinessResponse struct {
+	// Status is "ready" or "not_ready"
+	Status string              `json:"status"`
+	Checks []HealthCheckResult `json:"checks"`
+}
+
+// PromoSourceStatus represents the load progress of a promo source
+type PromoSourceStatus struct {
+	Name string `json:"name"`
+	// State is pending, loading, loaded or failed
+	State      string     `json:"state"`
+	Lines      int        `json:"lines"`
+	Codes      int        `json:"codes"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	DurationMs int64      `json:"durationMs"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// ServiceStatusResponse represents the state of the running service
+type ServiceStatusResponse struct {
+	// Status is "ready" or "starting"
+	Status        string              `json:"status"`
+	StartedAt     time.Time           `json:"startedAt"`
+	UptimeSeconds int64               `json:"uptimeSeconds"`
+	Checks        []HealthCheckResult `json:"checks"`
+	PromoSources  []PromoSourceStatus `json:"promoSources"`
+}
+
+// PromoValidateReq represents the API request for validating a promo code,
+// optionally against a cart
+type PromoValidateReq struct {
+	Code  string      `json:"code" validate:"required,max=64"`
+	Items []OrderItem `json:"items,omitempty" validate:"dive"`
+}
+
+// PromoValidateBatchReq represents the API request for validating several
+// promo codes against the same cart
+type PromoValidateBatchReq struct {
+	Codes []string    `json:"codes" validate:"required,min=1,max=50,dive,required,max=64"`
+	Items []OrderItem `json:"items,omitempty" validate:"dive"`
+}
+
+// PromoRuleOutcome represents how a promo code fared against the source quorum
+type PromoRuleOutcome struct {
+	Mode      string `json:"mode"`
+	Required  int    `json:"required"`
+	Matched   int    `json:"matched"`
+	Weight    int    `json:"weight"`
+	Satisfied bool   `json:"satisfied"`
+}
+
+// PromoCampaign represents the campaign a promo code belongs to
+type PromoCampaign struct {
+	Name          string        `json:"name"`
+	StartsAt      *time.Time    `json:"startsAt,omitempty"`
+	EndsAt        *time.Time    `json:"endsAt,omitempty"`
+	Categories    []string      `json:"categories,omitempty"`
+	ProductIDs    []string      `json:"productIds,omitempty"`
+	MinOrderValue *models.Money `json:"minOrderValue,omitempty"`
+}
+
+// PromoCartPreview represents a cart priced with a promo code
+type PromoCartPreview struct {
+	Items    []OrderLine  `json:"items"`
+	Currency string       `json:"currency"`
+	Subtotal models.Money `json:"subtotal"`
+	Discount models.Money `json:"discount"`
+	Total    models.Money `json:"total"`
+}
+
+// PromoValidation represents the result of validating a promo code
+type PromoValidation struct {
+	Code  string `json:"code"`
+	Valid bool   `json:"valid"`
+	// Reason and Message say why an invalid code was rejected
+	Reason         string            `json:"reason,omitempty"`
+	Message        string            `json:"message,omitempty"`
+	MatchedSources []string          `json:"matchedSources"`
+	Rule           *PromoRuleOutcome `json:"rule,omitempty"`
+	Campaign       *PromoCampaign    `json:"campaign,omitempty"`
+	Promotion      *AppliedPromotion `json:"promotion,omitempty"`
+	// Preview is the cart priced with the code, set when a cart was sent
+	Preview *PromoCartPreview `json:"preview,omitempty"`
+}
+
+// PromoValidationBatch represents the results of validating several promo codes, in request order
+type PromoValidationBatch struct {
+	Results []PromoValidation `json:"results"`
 }
 
 // ApiResponse represents a general API response
@@ -32,4 +229,6 @@ type ApiResponse struct {
 	Code    int    `json:"code"`
 	Type    string `json:"type"`
 	Message string `json:"message"`
+	// Reason is a machine-readable cause, set for rejected promo codes
+	Reason string `json:"reason,omitempty"`
 }
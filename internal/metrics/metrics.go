@@ -0,0 +1,149 @@
+// Package metrics holds the Prometheus collectors of the service and the
+// handler exposing them. Collectors are registered on a registry of their
+// own, so only the service's metrics and the Go runtime's are exported.
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric name
+const namespace = "glofox"
+
+// Promo lookup backends
+const (
+	BackendMemory = "memory"
+	BackendSQLite = "sqlite"
+	BackendIndex  = "index"
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by route template, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	promoValidations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "promo_validations_total",
+		Help:      "Promo code validations by result: valid or the rejection reason.",
+	}, []string{"result"})
+
+	promoSourceMatches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "promo_source_lookups_total",
+		Help:      "Promo codes looked up in each source by whether the source holds them.",
+	}, []string{"source", "matched"})
+
+	promoQuorumResults = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "promo_quorum_results_total",
+		Help:      "Promo codes checked against the quorum by mode and whether they met it.",
+	}, []string{"mode", "satisfied"})
+
+	promoLookupDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "promo_lookup_duration_seconds",
+		Help:      "Latency of looking a promo code up by backend.",
+		Buckets:   []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5, 1},
+	}, []string{"backend"})
+
+	ordersCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_created_total",
+		Help:      "Orders placed by currency.",
+	}, []string{"currency"})
+
+	orderValue = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "order_value",
+		Help:      "Total of placed orders in major units of their currency.",
+		Buckets:   []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000},
+	}, []string{"currency"})
+
+	promoLoadDuration = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "promo_load_duration_seconds",
+		Help:      "Time the last load of each promo source took.",
+	}, []string{"source"})
+
+	promoSourceCodes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "promo_source_codes",
+		Help:      "Distinct codes read by the last load of each promo source.",
+	}, []string{"source"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequestDuration,
+		promoValidations,
+		promoSourceMatches,
+		promoQuorumResults,
+		promoLookupDuration,
+		ordersCreated,
+		orderValue,
+		promoLoadDuration,
+		promoSourceCodes,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTPRequest records a served request under its route template
+func ObserveHTTPRequest(route, method string, status int, elapsed time.Duration) {
+	httpRequestDuration.WithLabelValues(route, method, strconv.Itoa(status)).Observe(elapsed.Seconds())
+}
+
+// ObservePromoValidation records the result of a promo code validation,
+// "valid" or the reason the code was rejected
+func ObservePromoValidation(result string) {
+	promoValidations.WithLabelValues(result).Inc()
+}
+
+// ObservePromoSources records which sources held a code and whether the code
+// met the quorum. sources and found are parallel.
+func ObservePromoSources(sources []string, found []bool, mode string, satisfied bool) {
+	for i, source := range sources {
+		promoSourceMatches.WithLabelValues(source, strconv.FormatBool(found[i])).Inc()
+	}
+	promoQuorumResults.WithLabelValues(mode, strconv.FormatBool(satisfied)).Inc()
+}
+
+// ObservePromoLookup records the latency of a promo code lookup in a backend
+// that started at start. It is meant to be deferred.
+func ObservePromoLookup(backend string, start time.Time) {
+	promoLookupDuration.WithLabelValues(backend).Observe(time.Since(start).Seconds())
+}
+
+// ObserveOrderCreated records a placed order and its total
+func ObserveOrderCreated(order *models.Order) {
+	currency := order.Total.Currency
+	ordersCreated.WithLabelValues(currency).Inc()
+	value := float64(order.Total.Amount) / math.Pow10(models.CurrencyExponent(currency))
+	orderValue.WithLabelValues(currency).Observe(value)
+}
+
+// ObservePromoSourceLoad records how long loading a promo source took and
+// how many codes it holds
+func ObservePromoSourceLoad(source string, codes int, elapsed time.Duration) {
+	promoLoadDuration.WithLabelValues(source).Set(elapsed.Seconds())
+	promoSourceCodes.WithLabelValues(source).Set(float64(codes))
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Scope is a permission granted to an API key
+type Scope string
+
+// API key scopes
+const (
+	// ScopeOrdersRead allows fetching and listing orders
+	ScopeOrdersRead Scope = "orders:read"
+	// ScopeOrdersWrite allows quoting, placing and updating orders and validating promo codes
+	ScopeOrdersWrite Scope = "orders:write"
+	// ScopeCatalogAdmin allows changing the product catalog
+	ScopeCatalogAdmin Scope = "catalog:admin"
+	// ScopePromoAdmin allows reloading promo codes
+	ScopePromoAdmin Scope = "promo:admin"
+)
+
+// Scopes lists every known scope
+var Scopes = []Scope{ScopeOrdersRead, ScopeOrdersWrite, ScopeCatalogAdmin, ScopePromoAdmin}
+
+// ValidScope reports whether a scope is known
+func ValidScope(scope Scope) bool {
+	for _, known := range Scopes {
+		if scope == known {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKey is a client credential. Only a hash of the key itself is stored.
+type APIKey struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// Hash is the hex SHA-256 of the key, see HashAPIKey
+	Hash   string  `json:"-"`
+	Scopes []Scope `json:"scopes"`
+	// ExpiresAt is when the key stops working, zero means never
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+	// RevokedAt is when the key was revoked, zero if it was not
+	RevokedAt time.Time `json:"revokedAt,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// HashAPIKey returns the hex SHA-256 of a key. API keys are long random
+// strings, so a fast hash is enough to keep them out of storage.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Expired reports whether the key has expired at the given time
+func (k APIKey) Expired(at time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !at.Before(k.ExpiresAt)
+}
+
+// Revoked reports whether the key has been revoked at the given time
+func (k APIKey) Revoked(at time.Time) bool {
+	return !k.RevokedAt.IsZero() && !at.Before(k.RevokedAt)
+}
+
+// HasScope reports whether the key grants a scope
+func (k APIKey) HasScope(scope Scope) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
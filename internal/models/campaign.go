@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// Campaign groups promo codes under a marketing campaign with a validity
+// window and constraints on the orders the codes can be used for
+type Campaign struct {
+	Name string `json:"name"`
+	// StartsAt is the first instant the codes are valid, zero means no start
+	StartsAt time.Time `json:"startsAt,omitempty"`
+	// EndsAt is the first instant the codes are no longer valid, zero means no end
+	EndsAt time.Time `json:"endsAt,omitempty"`
+	// Timezone is the IANA zone the window was defined in
+	Timezone string `json:"timezone,omitempty"`
+	// Categories and ProductIDs restrict the products the codes can be used for;
+	// an order needs at least one of them and only they are discounted. Both
+	// empty means every product.
+	Categories []string `json:"categories,omitempty"`
+	ProductIDs []string `json:"productIds,omitempty"`
+	// MinOrderValue is the subtotal the campaign's products in an order must
+	// reach, zero means no minimum
+	MinOrderValue Money `json:"minOrderValue"`
+	// Codes are the promo codes belonging to the campaign
+	Codes []string `json:"codes"`
+}
+
+// Started reports whether the campaign has started at the given time
+func (c Campaign) Started(at time.Time) bool {
+	return c.StartsAt.IsZero() || !at.Before(c.StartsAt)
+}
+
+// Ended reports whether the campaign has ended at the given time
+func (c Campaign) Ended(at time.Time) bool {
+	return !c.EndsAt.IsZero() && !at.Before(c.EndsAt)
+}
+
+// AppliesTo reports whether a product is eligible for the campaign
+func (c Campaign) AppliesTo(product Product) bool {
+	if len(c.Categories) == 0 && len(c.ProductIDs) == 0 {
+		return true
+	}
+	for _, category := range c.Categories {
+		if category == product.Category {
+			return true
+		}
+	}
+	for _, id := range c.ProductIDs {
+		if id == product.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// MeetsMinimum reports whether the subtotal of an order's eligible products
+// reaches the minimum order value. A subtotal in another currency than the
+// minimum never does.
+func (c Campaign) MeetsMinimum(subtotal Money) bool {
+	if c.MinOrderValue.IsZero() {
+		return true
+	}
+	return c.MinOrderValue.SameCurrency(subtotal) && subtotal.Amount >= c.MinOrderValue.Amount
+}
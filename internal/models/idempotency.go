@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// IdempotencyRecord remembers a request made with an idempotency key and,
+// once it completed, the response to replay for retries
+type IdempotencyRecord struct {
+	Key string
+	// Fingerprint identifies the request payload the key was first used with
+	Fingerprint string
+	// Owner is a token identifying the request holding the key, so a request
+	// whose key was taken over cannot settle it for the new holder
+	Owner string
+	// StatusCode and Body are the stored response; a zero status code means
+	// the first request is still in progress
+	StatusCode int
+	Body       []byte
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+}
+
+// Completed reports whether the response of the request has been stored
+func (r IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
@@ -0,0 +1,217 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the ISO 4217 currency used when none is specified
+const DefaultCurrency = "USD"
+
+// Errors for Money
+var (
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidAmount    = errors.New("invalid money amount")
+	ErrAmountOverflow   = errors.New("money amount out of range")
+)
+
+// currencyExponents lists ISO 4217 currencies whose minor unit is not 1/100.
+// Every other currency is assumed to have two decimal places.
+var currencyExponents = map[string]int{
+	"BHD": 3,
+	"CLP": 0,
+	"ISK": 0,
+	"JOD": 3,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+	"VND": 0,
+}
+
+// CurrencyExponent returns the number of decimal places of a currency's minor unit
+func CurrencyExponent(currency string) int {
+	if exp, ok := currencyExponents[currency]; ok {
+		return exp
+	}
+	return 2
+}
+
+// Money is an exact amount expressed in integer minor units (e.g. cents)
+// of an ISO 4217 currency. It is encoded in JSON as a decimal number in
+// major units (e.g. 6.5) so existing clients keep working.
+type Money struct {
+	// Amount is the value in minor units of Currency
+	Amount int64
+	// Currency is the ISO 4217 currency code
+	Currency string
+}
+
+// NewMoney creates a Money value from minor units
+func NewMoney(amount int64, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney parses a decimal string in major units (e.g. "6.50") exactly.
+// More decimal places than the currency allows is an error, not a rounding.
+func ParseMoney(value string, currency string) (Money, error) {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	exp := CurrencyExponent(currency)
+
+	value = strings.TrimSpace(value)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	whole, fraction, hasFraction := strings.Cut(value, ".")
+	if whole == "" || (hasFraction && fraction == "") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, value)
+	}
+	if len(fraction) > exp {
+		// Allow trailing zeros beyond the currency precision (e.g. "6.500")
+		if strings.TrimRight(fraction[exp:], "0") != "" {
+			return Money{}, fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidAmount, value, exp)
+		}
+		fraction = fraction[:exp]
+	}
+	fraction += strings.Repeat("0", exp-len(fraction))
+
+	amount, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil || strings.ContainsAny(whole+fraction, "+-") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, value)
+	}
+	if negative {
+		amount = -amount
+	}
+
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// SameCurrency reports whether two amounts share a currency
+func (m Money) SameCurrency(other Money) bool {
+	return m.Currency == other.Currency
+}
+
+// Add returns the sum of two amounts, ErrAmountOverflow if it does not fit.
+// Callers must make sure both amounts share a currency (see SameCurrency).
+func (m Money) Add(other Money) (Money, error) {
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrAmountOverflow, m, other)
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// Sub returns the difference of two amounts.
+// Callers must make sure both amounts share a currency (see SameCurrency).
+func (m Money) Sub(other Money) Money {
+	return Money{Amount: m.Amount - other.Amount, Currency: m.Currency}
+}
+
+// Multiply returns the amount multiplied by a quantity, ErrAmountOverflow if
+// the product does not fit
+func (m Money) Multiply(quantity int) (Money, error) {
+	product, ok := multiply(m.Amount, int64(quantity))
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %s * %d", ErrAmountOverflow, m, quantity)
+	}
+	return Money{Amount: product, Currency: m.Currency}, nil
+}
+
+// multiply returns a * b, false if it overflows
+func multiply(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return product, true
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Decimal formats the amount in major units with the currency's precision (e.g. "6.50")
+func (m Money) Decimal() string {
+	exp := CurrencyExponent(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.FormatInt(amount, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+// String formats the amount with its currency code (e.g. "6.50 USD")
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// MarshalJSON encodes the amount as a decimal number in major units
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Decimal()), nil
+}
+
+// UnmarshalJSON decodes a decimal number in major units.
+// The currency is left unchanged, or set to DefaultCurrency when empty.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	parsed, err := ParseMoney(value, m.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Percentage returns the given percentage of the amount, rounded half away
+// from zero, ErrAmountOverflow if it does not fit. Whole hundreds and the rest
+// are scaled separately so no percentage up to 100 can overflow.
+func (m Money) Percentage(percent int) (Money, error) {
+	hundreds, ok := multiply(m.Amount/100, int64(percent))
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %d%% of %s", ErrAmountOverflow, percent, m)
+	}
+	rest := m.Amount % 100 * int64(percent)
+	if rest < 0 {
+		rest = (rest - 50) / 100
+	} else {
+		rest = (rest + 50) / 100
+	}
+	return Money{Amount: hundreds, Currency: m.Currency}.Add(Money{Amount: rest, Currency: m.Currency})
+}
+
+// Min returns the smaller of two amounts.
+// Callers must make sure both amounts share a currency (see SameCurrency).
+func (m Money) Min(other Money) Money {
+	if other.Amount < m.Amount {
+		return other
+	}
+	return m
+}
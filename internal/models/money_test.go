@@ -0,0 +1,162 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		currency string
+		want     Money
+		wantErr  bool
+	}{
+		{name: "whole units", value: "6", currency: "USD", want: Money{Amount: 600, Currency: "USD"}},
+		{name: "cents", value: "6.50", currency: "USD", want: Money{Amount: 650, Currency: "USD"}},
+		{name: "single decimal", value: "6.5", currency: "USD", want: Money{Amount: 650, Currency: "USD"}},
+		{name: "trailing zeros beyond precision", value: "6.500", currency: "USD", want: Money{Amount: 650, Currency: "USD"}},
+		{name: "surrounding spaces", value: " 1.25 ", currency: "EUR", want: Money{Amount: 125, Currency: "EUR"}},
+		{name: "negative", value: "-3.10", currency: "USD", want: Money{Amount: -310, Currency: "USD"}},
+		{name: "default currency", value: "2.00", currency: "", want: Money{Amount: 200, Currency: DefaultCurrency}},
+		{name: "zero exponent currency", value: "500", currency: "JPY", want: Money{Amount: 500, Currency: "JPY"}},
+		{name: "three decimal currency", value: "1.234", currency: "KWD", want: Money{Amount: 1234, Currency: "KWD"}},
+		{name: "too many decimals", value: "6.505", currency: "USD", wantErr: true},
+		{name: "decimals on zero exponent currency", value: "500.5", currency: "JPY", wantErr: true},
+		{name: "empty", value: "", currency: "USD", wantErr: true},
+		{name: "missing whole part", value: ".50", currency: "USD", wantErr: true},
+		{name: "missing fraction", value: "6.", currency: "USD", wantErr: true},
+		{name: "not a number", value: "abc", currency: "USD", wantErr: true},
+		{name: "double sign", value: "--1", currency: "USD", wantErr: true},
+		{name: "plus sign in fraction", value: "1.+5", currency: "USD", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMoney(tt.value, tt.currency)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidAmount) {
+					t.Fatalf("ParseMoney(%q, %q) error = %v, want %v", tt.value, tt.currency, err, ErrInvalidAmount)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMoney(%q, %q) unexpected error: %v", tt.value, tt.currency, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMoney(%q, %q) = %v, want %v", tt.value, tt.currency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyPercentage(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int64
+		percent int
+		want    int64
+		wantErr error
+	}{
+		{name: "exact", amount: 1000, percent: 10, want: 100},
+		{name: "rounds half up", amount: 250, percent: 18, want: 45},
+		{name: "rounds down below half", amount: 101, percent: 10, want: 10},
+		{name: "rounds half away from zero", amount: 5, percent: 10, want: 1},
+		{name: "negative rounds away from zero", amount: -5, percent: 10, want: -1},
+		{name: "whole amount", amount: 799, percent: 100, want: 799},
+		{name: "zero percent", amount: 799, percent: 0, want: 0},
+		{name: "largest amount", amount: math.MaxInt64, percent: 100, want: math.MaxInt64},
+		{name: "half of the largest amount", amount: math.MaxInt64, percent: 50, want: math.MaxInt64/2 + 1},
+		{name: "overflow", amount: math.MaxInt64 / 2, percent: 300, wantErr: ErrAmountOverflow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewMoney(tt.amount, "USD").Percentage(tt.percent)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Money{%d}.Percentage(%d) error = %v, want %v", tt.amount, tt.percent, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got.Amount != tt.want || got.Currency != "USD" {
+				t.Errorf("Money{%d}.Percentage(%d) = %v, want %d USD", tt.amount, tt.percent, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyArithmeticOverflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      func() (Money, error)
+		want    int64
+		wantErr error
+	}{
+		{
+			name: "multiply",
+			op:   func() (Money, error) { return NewMoney(650, "USD").Multiply(3) },
+			want: 1950,
+		},
+		{
+			name:    "multiply overflow",
+			op:      func() (Money, error) { return NewMoney(math.MaxInt64/2+1, "USD").Multiply(2) },
+			wantErr: ErrAmountOverflow,
+		},
+		{
+			name:    "multiply negative overflow",
+			op:      func() (Money, error) { return NewMoney(math.MinInt64, "USD").Multiply(-1) },
+			wantErr: ErrAmountOverflow,
+		},
+		{
+			name: "add",
+			op:   func() (Money, error) { return NewMoney(math.MaxInt64-1, "USD").Add(NewMoney(1, "USD")) },
+			want: math.MaxInt64,
+		},
+		{
+			name:    "add overflow",
+			op:      func() (Money, error) { return NewMoney(math.MaxInt64, "USD").Add(NewMoney(1, "USD")) },
+			wantErr: ErrAmountOverflow,
+		},
+		{
+			name:    "add negative overflow",
+			op:      func() (Money, error) { return NewMoney(math.MinInt64, "USD").Add(NewMoney(-1, "USD")) },
+			wantErr: ErrAmountOverflow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.op()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got.Amount != tt.want {
+				t.Errorf("amount = %d, want %d", got.Amount, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{money: NewMoney(650, "USD"), want: "6.50"},
+		{money: NewMoney(5, "USD"), want: "0.05"},
+		{money: NewMoney(-310, "USD"), want: "-3.10"},
+		{money: NewMoney(500, "JPY"), want: "500"},
+		{money: NewMoney(1234, "KWD"), want: "1.234"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want+" "+tt.money.Currency, func(t *testing.T) {
+			if got := tt.money.Decimal(); got != tt.want {
+				t.Errorf("%#v.Decimal() = %q, want %q", tt.money, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,14 +1,71 @@
 package models
 
+import "time"
+
+// OrderStatus is a step of the order lifecycle
+type OrderStatus string
+
+// Order lifecycle statuses
+const (
+	OrderStatusPlaced    OrderStatus = "placed"
+	OrderStatusAccepted  OrderStatus = "accepted"
+	OrderStatusPreparing OrderStatus = "preparing"
+	OrderStatusReady     OrderStatus = "ready"
+	OrderStatusCompleted OrderStatus = "completed"
+	OrderStatusCancelled OrderStatus = "cancelled"
+	OrderStatusRefunded  OrderStatus = "refunded"
+)
+
+// RedeemsCoupon reports whether an order in this status uses up a redemption
+// of its coupon. Cancelled and refunded orders give their redemption back.
+func (s OrderStatus) RedeemsCoupon() bool {
+	return s != OrderStatusCancelled && s != OrderStatusRefunded
+}
+
+// StatusChange records when an order entered a status
+type StatusChange struct {
+	Status OrderStatus `json:"status"`
+	At     time.Time   `json:"at"`
+}
+
 // OrderItem represents a product with quantity in an order
 type OrderItem struct {
 	ProductID string `json:"productId"`
 	Quantity  int    `json:"quantity"`
+	UnitPrice Money  `json:"unitPrice"`
+	LineTotal Money  `json:"lineTotal"`
+	Discount  Money  `json:"discount"`
+	Total     Money  `json:"total"`
 }
 
 // Order represents a customer order
 type Order struct {
-	ID         string      `json:"id"`
-	Items      []OrderItem `json:"items"`
-	CouponCode string      `json:"couponCode,omitempty"`
+	ID    string      `json:"id"`
+	Items []OrderItem `json:"items"`
+	// CustomerID identifies who placed the order, for per-customer coupon limits
+	CustomerID string     `json:"customerId,omitempty"`
+	CouponCode string     `json:"couponCode,omitempty"`
+	Subtotal   Money      `json:"subtotal"`
+	Discount   Money      `json:"discount"`
+	Total      Money      `json:"total"`
+	Promotion  *Promotion `json:"promotion,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	// Status is the current lifecycle status, StatusHistory every status entered so far
+	Status        OrderStatus    `json:"status"`
+	StatusHistory []StatusChange `json:"statusHistory"`
+}
+
+// OrderFilter narrows down an order listing.
+// Zero values mean "no restriction" for every field.
+type OrderFilter struct {
+	// From and To bound the creation time; From is inclusive, To exclusive
+	From time.Time
+	To   time.Time
+	// CouponCode only keeps orders placed with this coupon
+	CouponCode string
+	// ProductID only keeps orders containing this product
+	ProductID string
+	// Offset and Limit select a page of the newest-first listing
+	Offset int
+	Limit  int
 }
@@ -8,7 +8,7 @@ import (
 type Product struct {
 	ID          string    `json:"id"`
 	Name        string    `json:"name"`
-	Price       float64   `json:"price"`
+	Price       Money     `json:"price"`
 	Category    string    `json:"category"`
 	Description string    `json:"description,omitempty"`
 	ImageURL    string    `json:"imageUrl,omitempty"`
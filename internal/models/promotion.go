@@ -0,0 +1,88 @@
+package models
+
+// Promo codes are between MinPromoCodeLength and MaxPromoCodeLength characters long
+const (
+	MinPromoCodeLength = 8
+	MaxPromoCodeLength = 10
+)
+
+// ValidPromoCodeLength reports whether a code has the length of a promo code
+func ValidPromoCodeLength(code string) bool {
+	return len(code) >= MinPromoCodeLength && len(code) <= MaxPromoCodeLength
+}
+
+// DiscountType identifies how a promotion reduces an order
+type DiscountType string
+
+// Supported discount types
+const (
+	// DiscountPercentage takes a percentage off every eligible line
+	DiscountPercentage DiscountType = "percentage"
+	// DiscountFixedAmount takes a fixed amount off the eligible lines
+	DiscountFixedAmount DiscountType = "fixed_amount"
+	// DiscountFreeCheapestItem makes the cheapest eligible unit free
+	DiscountFreeCheapestItem DiscountType = "free_cheapest_item"
+	// DiscountBuyXGetY makes Y of every X+Y eligible units free, cheapest first
+	DiscountBuyXGetY DiscountType = "buy_x_get_y"
+)
+
+// Discount describes what a promo code takes off an order
+type Discount struct {
+	Type DiscountType `json:"type"`
+	// Percentage is the percent taken off (1-100) for DiscountPercentage
+	Percentage int `json:"percentage,omitempty"`
+	// Amount is the value taken off for DiscountFixedAmount
+	Amount Money `json:"amount"`
+	// BuyQuantity and GetQuantity define a DiscountBuyXGetY deal
+	BuyQuantity int `json:"buyQuantity,omitempty"`
+	GetQuantity int `json:"getQuantity,omitempty"`
+	// Category limits the discount to one product category, empty means every product
+	Category string `json:"category,omitempty"`
+	// Description is a human readable summary shown to customers
+	Description string `json:"description,omitempty"`
+}
+
+// AppliesTo reports whether a product is eligible for the discount
+func (d Discount) AppliesTo(product Product) bool {
+	return d.Category == "" || d.Category == product.Category
+}
+
+// Promotion links a promo code to the discount it grants
+type Promotion struct {
+	Code     string   `json:"code"`
+	Discount Discount `json:"discount"`
+	// Limits caps how often the code can be redeemed; zero values fall back to the configured defaults
+	Limits RedemptionLimits `json:"limits"`
+}
+
+// RedemptionLimits caps how often a promo code can be redeemed.
+// Zero values mean unlimited.
+type RedemptionLimits struct {
+	// MaxRedemptions caps the redemptions across all customers
+	MaxRedemptions int `json:"maxRedemptions,omitempty"`
+	// MaxPerCustomer caps the redemptions by one customer
+	MaxPerCustomer int `json:"maxPerCustomer,omitempty"`
+	// SingleUse allows a single redemption in total
+	SingleUse bool `json:"singleUse,omitempty"`
+}
+
+// IsZero reports whether no limit is set
+func (l RedemptionLimits) IsZero() bool {
+	return l == RedemptionLimits{}
+}
+
+// GlobalLimit returns the maximum number of redemptions across all customers, 0 if unlimited
+func (l RedemptionLimits) GlobalLimit() int {
+	if l.SingleUse {
+		return 1
+	}
+	return l.MaxRedemptions
+}
+
+// RedemptionCount is how often a promo code has been redeemed
+type RedemptionCount struct {
+	// Total counts the redemptions by every customer
+	Total int
+	// Customer counts the redemptions by one customer
+	Customer int
+}
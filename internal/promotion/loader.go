@@ -0,0 +1,160 @@
+package promotion
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Errors for promotion loading
+var (
+	ErrUnsupportedFormat = errors.New("unsupported promotions format")
+)
+
+// currencyPattern matches ISO 4217 style currency codes
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// entry is a promotion before validation. The fixed amount is kept as text
+// so it can be parsed exactly in the promotion's currency.
+type entry struct {
+	Code        string      `json:"code" yaml:"code"`
+	Type        string      `json:"type" yaml:"type"`
+	Percentage  int         `json:"percentage" yaml:"percentage"`
+	Amount      interface{} `json:"amount" yaml:"amount"`
+	Currency    string      `json:"currency" yaml:"currency"`
+	BuyQuantity int         `json:"buyQuantity" yaml:"buyQuantity"`
+	GetQuantity int         `json:"getQuantity" yaml:"getQuantity"`
+	Category    string      `json:"category" yaml:"category"`
+	Description string      `json:"description" yaml:"description"`
+	Limits      limits      `json:"limits" yaml:"limits"`
+}
+
+// limits are the redemption limits of an entry
+type limits struct {
+	MaxRedemptions int  `json:"maxRedemptions" yaml:"maxRedemptions"`
+	MaxPerCustomer int  `json:"maxPerCustomer" yaml:"maxPerCustomer"`
+	SingleUse      bool `json:"singleUse" yaml:"singleUse"`
+}
+
+// Load reads and validates the promotions of a JSON or YAML file, chosen by extension.
+// Every problem is reported at once.
+func Load(path string) ([]models.Promotion, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read promotions: %w", err)
+	}
+
+	var entries []entry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&entries)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &entries)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse promotions %s: %w", path, err)
+	}
+
+	promotions, err := validate(entries)
+	if err != nil {
+		return nil, fmt.Errorf("promotions %s are invalid:\n%w", path, err)
+	}
+	return promotions, nil
+}
+
+// validate converts entries into promotions, collecting every problem
+func validate(entries []entry) ([]models.Promotion, error) {
+	var errs []error
+	promotions := make([]models.Promotion, 0, len(entries))
+	codes := make(map[string]bool, len(entries))
+
+	for i, e := range entries {
+		fail := func(field, format string, args ...interface{}) {
+			errs = append(errs, fmt.Errorf("promotions[%d].%s: %s", i, field, fmt.Sprintf(format, args...)))
+		}
+
+		code := strings.TrimSpace(e.Code)
+		if !models.ValidPromoCodeLength(code) {
+			fail("code", "must be %d to %d characters", models.MinPromoCodeLength, models.MaxPromoCodeLength)
+		} else if codes[code] {
+			fail("code", "duplicate promotion for %s", code)
+		}
+		codes[code] = true
+
+		discount := models.Discount{
+			Type:        models.DiscountType(strings.TrimSpace(e.Type)),
+			Category:    strings.TrimSpace(e.Category),
+			Description: strings.TrimSpace(e.Description),
+		}
+		switch discount.Type {
+		case models.DiscountPercentage:
+			if e.Percentage < 1 || e.Percentage > 100 {
+				fail("percentage", "must be between 1 and 100")
+			}
+			discount.Percentage = e.Percentage
+		case models.DiscountFixedAmount:
+			currency := strings.TrimSpace(e.Currency)
+			if currency == "" {
+				currency = models.DefaultCurrency
+			} else if !currencyPattern.MatchString(currency) {
+				fail("currency", "must be an ISO 4217 code")
+			}
+			if e.Amount == nil {
+				fail("amount", "must be set for a fixed_amount discount")
+			} else if parsed, err := models.ParseMoney(fmt.Sprint(e.Amount), currency); err != nil {
+				fail("amount", "%v", err)
+			} else if parsed.IsNegative() || parsed.IsZero() {
+				fail("amount", "must be greater than zero")
+			} else {
+				discount.Amount = parsed
+			}
+		case models.DiscountFreeCheapestItem:
+		case models.DiscountBuyXGetY:
+			if e.BuyQuantity < 1 {
+				fail("buyQuantity", "must be at least 1")
+			}
+			if e.GetQuantity < 1 {
+				fail("getQuantity", "must be at least 1")
+			}
+			discount.BuyQuantity = e.BuyQuantity
+			discount.GetQuantity = e.GetQuantity
+		default:
+			fail("type", "must be %s, %s, %s or %s", models.DiscountPercentage, models.DiscountFixedAmount,
+				models.DiscountFreeCheapestItem, models.DiscountBuyXGetY)
+		}
+
+		if e.Limits.MaxRedemptions < 0 {
+			fail("limits.maxRedemptions", "must not be negative")
+		}
+		if e.Limits.MaxPerCustomer < 0 {
+			fail("limits.maxPerCustomer", "must not be negative")
+		}
+
+		promotions = append(promotions, models.Promotion{
+			Code:     code,
+			Discount: discount,
+			Limits: models.RedemptionLimits{
+				MaxRedemptions: e.Limits.MaxRedemptions,
+				MaxPerCustomer: e.Limits.MaxPerCustomer,
+				SingleUse:      e.Limits.SingleUse,
+			},
+		})
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return promotions, nil
+}
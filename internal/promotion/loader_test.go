@@ -0,0 +1,176 @@
+package promotion
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []models.Promotion
+		// wantFields lists the fields the error must report, as promotions[i].field
+		wantFields []string
+		wantErr    error
+	}{
+		{
+			name: "yaml",
+			file: "promotions.yaml",
+			content: `- code: " HAPPYHRS "
+  type: percentage
+  percentage: 18
+  category: Waffle
+  description: 18% off waffles
+- code: FIVEOFFNOW
+  type: fixed_amount
+  amount: "5.00"
+  limits:
+    maxRedemptions: 100
+    maxPerCustomer: 1
+- code: FREEBIE01
+  type: free_cheapest_item
+  limits:
+    singleUse: true
+- code: BUYGETONE
+  type: buy_x_get_y
+  buyQuantity: 2
+  getQuantity: 1
+`,
+			want: []models.Promotion{
+				{
+					Code:     "HAPPYHRS",
+					Discount: models.Discount{Type: models.DiscountPercentage, Percentage: 18, Category: "Waffle", Description: "18% off waffles"},
+				},
+				{
+					Code:     "FIVEOFFNOW",
+					Discount: models.Discount{Type: models.DiscountFixedAmount, Amount: models.NewMoney(500, "USD")},
+					Limits:   models.RedemptionLimits{MaxRedemptions: 100, MaxPerCustomer: 1},
+				},
+				{
+					Code:     "FREEBIE01",
+					Discount: models.Discount{Type: models.DiscountFreeCheapestItem},
+					Limits:   models.RedemptionLimits{SingleUse: true},
+				},
+				{
+					Code:     "BUYGETONE",
+					Discount: models.Discount{Type: models.DiscountBuyXGetY, BuyQuantity: 2, GetQuantity: 1},
+				},
+			},
+		},
+		{
+			name:    "json amount in another currency",
+			file:    "promotions.json",
+			content: `[{"code": "YENOFF500", "type": "fixed_amount", "amount": 500, "currency": "JPY"}]`,
+			want: []models.Promotion{
+				{Code: "YENOFF500", Discount: models.Discount{Type: models.DiscountFixedAmount, Amount: models.NewMoney(500, "JPY")}},
+			},
+		},
+		{
+			name: "every problem reported",
+			file: "promotions.yaml",
+			content: `- code: SHORT
+  type: percentage
+  percentage: 101
+- code: FIVEOFFNOW
+  type: fixed_amount
+  currency: usd
+- code: FIVEOFFNOW
+  type: fixed_amount
+  amount: "0"
+  limits:
+    maxRedemptions: -1
+    maxPerCustomer: -1
+- code: BUYGETONE
+  type: buy_x_get_y
+- code: BOGUSTYPE
+  type: half_price
+- code: CENTSOFF1
+  type: fixed_amount
+  amount: "0.001"
+`,
+			wantFields: []string{
+				"promotions[0].code",
+				"promotions[0].percentage",
+				"promotions[1].currency",
+				"promotions[1].amount",
+				"promotions[2].code",
+				"promotions[2].amount",
+				"promotions[2].limits.maxRedemptions",
+				"promotions[2].limits.maxPerCustomer",
+				"promotions[3].buyQuantity",
+				"promotions[3].getQuantity",
+				"promotions[4].type",
+				"promotions[5].amount",
+			},
+		},
+		{
+			name:       "malformed json",
+			file:       "promotions.json",
+			content:    `[{"code": "HAPPYHRS",]`,
+			wantFields: []string{"failed to parse"},
+		},
+		{
+			name:    "unsupported format",
+			file:    "promotions.csv",
+			content: "code,type",
+			wantErr: ErrUnsupportedFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write promotions: %v", err)
+			}
+
+			promotions, err := Load(path)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case tt.wantFields != nil:
+				if err == nil {
+					t.Fatal("Load() succeeded, want an error")
+				}
+				for _, field := range tt.wantFields {
+					if !strings.Contains(err.Error(), field) {
+						t.Errorf("Load() error = %v, want it to report %s", err, field)
+					}
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Load() unexpected error: %v", err)
+			}
+			if len(promotions) != len(tt.want) {
+				t.Fatalf("Load() = %+v, want %+v", promotions, tt.want)
+			}
+			for i := range promotions {
+				if promotions[i] != tt.want[i] {
+					t.Errorf("promotion %d = %+v, want %+v", i, promotions[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadExample(t *testing.T) {
+	promotions, err := Load(filepath.Join("..", "..", "examples", "promotions.yaml"))
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if len(promotions) == 0 {
+		t.Error("Load() found no promotions in the example")
+	}
+}
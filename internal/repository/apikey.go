@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+// Errors for APIKeyRepository
+var (
+	ErrAPIKeyExists = errors.New("API key already exists")
+)
+
+// InMemoryAPIKeyRepository implements APIKeyRepository using in-memory storage
+type InMemoryAPIKeyRepository struct {
+	// keys maps key hashes to keys
+	keys  map[string]models.APIKey
+	mutex sync.RWMutex
+}
+
+// NewInMemoryAPIKeyRepository creates a new repository holding the given keys.
+// It fails with ErrAPIKeyExists when two keys share an ID or hash.
+func NewInMemoryAPIKeyRepository(keys []models.APIKey) (*InMemoryAPIKeyRepository, error) {
+	repo := &InMemoryAPIKeyRepository{
+		keys: make(map[string]models.APIKey, len(keys)),
+	}
+	for i := range keys {
+		if _, err := repo.Create(context.Background(), &keys[i]); err != nil {
+			return nil, err
+		}
+	}
+	return repo, nil
+}
+
+// FindByHash returns the key with the given hash
+func (r *InMemoryAPIKeyRepository) FindByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	key, ok := r.keys[hash]
+	if !ok {
+		return nil, nil // Not found
+	}
+	keyCopy := copyAPIKey(key)
+	return &keyCopy, nil
+}
+
+// FindAll returns every key, ordered by ID
+func (r *InMemoryAPIKeyRepository) FindAll(ctx context.Context) ([]models.APIKey, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	keys := make([]models.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, copyAPIKey(key))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+// Create adds a key
+func (r *InMemoryAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, taken := r.keys[key.Hash]; taken {
+		return nil, fmt.Errorf("%w: %s has the hash of another key", ErrAPIKeyExists, key.ID)
+	}
+	for _, existing := range r.keys {
+		if existing.ID == key.ID {
+			return nil, fmt.Errorf("%w: %s", ErrAPIKeyExists, key.ID)
+		}
+	}
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now().UTC()
+	}
+	r.keys[key.Hash] = copyAPIKey(*key)
+	return key, nil
+}
+
+// Revoke marks a key as revoked from the given time
+func (r *InMemoryAPIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for hash, key := range r.keys {
+		if key.ID == id {
+			key.RevokedAt = at
+			r.keys[hash] = key
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// copyAPIKey returns a copy of a key that shares no memory with the original
+func copyAPIKey(key models.APIKey) models.APIKey {
+	keyCopy := key
+	keyCopy.Scopes = append([]models.Scope(nil), key.Scopes...)
+	return keyCopy
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/mattn/go-sqlite3"
+)
+
+// apiKeyMigrations versions the schema used by SQLiteAPIKeyRepository
+var apiKeyMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_api_keys",
+		Up: execStatements(
+			`CREATE TABLE IF NOT EXISTS api_keys (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL DEFAULT '',
+				key_hash TEXT NOT NULL UNIQUE,
+				scopes TEXT NOT NULL,
+				created_at INTEGER NOT NULL,
+				expires_at INTEGER NOT NULL DEFAULT 0,
+				revoked_at INTEGER NOT NULL DEFAULT 0
+			)`,
+		),
+		Down: execStatements(
+			`DROP TABLE IF EXISTS api_keys`,
+		),
+	},
+}
+
+// apiKeyColumns lists the api_keys columns in the order scanAPIKey expects them
+const apiKeyColumns = `id, name, key_hash, scopes, created_at, expires_at, revoked_at`
+
+// SQLiteAPIKeyRepository implements APIKeyRepository using SQLite database
+type SQLiteAPIKeyRepository struct {
+	db *sql.DB
+	// queryTimeout bounds every operation, 0 leaves them to the caller's context
+	queryTimeout time.Duration
+}
+
+// SQLiteAPIKeyConfig contains configuration options for SQLiteAPIKeyRepository
+type SQLiteAPIKeyConfig struct {
+	// DatabasePath is the path where the SQLite database will be stored
+	DatabasePath string
+	// QueryTimeout bounds every database operation; 0 means no limit
+	QueryTimeout time.Duration
+}
+
+// NewSQLiteAPIKeyRepository creates a new SQLite-based API key repository
+func NewSQLiteAPIKeyRepository(config SQLiteAPIKeyConfig) (*SQLiteAPIKeyRepository, error) {
+	if config.DatabasePath == "" {
+		config.DatabasePath = "auth.db"
+	}
+
+	db, err := openSQLiteDatabase(config.DatabasePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := NewMigrator(db, "auth", apiKeyMigrations).Up(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteAPIKeyRepository{db: db, queryTimeout: config.QueryTimeout}, nil
+}
+
+// FindByHash returns the key with the given hash
+func (r *SQLiteAPIKeyRepository) FindByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ?`, hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Not found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+	return key, nil
+}
+
+// FindAll returns every key, ordered by ID
+func (r *SQLiteAPIKeyRepository) FindAll(ctx context.Context) ([]models.APIKey, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]models.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	return keys, rows.Err()
+}
+
+// Create adds a key
+func (r *SQLiteAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now().UTC()
+	}
+
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT INTO api_keys (`+apiKeyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		key.ID, key.Name, key.Hash, strings.Join(scopes, " "),
+		key.CreatedAt.UnixNano(), unixNanoOrZero(key.ExpiresAt), unixNanoOrZero(key.RevokedAt))
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+			return nil, fmt.Errorf("%w: %s", ErrAPIKeyExists, key.ID)
+		}
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	return key, nil
+}
+
+// Revoke marks a key as revoked from the given time
+func (r *SQLiteAPIKeyRepository) Revoke(ctx context.Context, id string, at time.Time) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = ? WHERE id = ?`, at.UnixNano(), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	return updated > 0, err
+}
+
+// scanAPIKey reads a key from a row selected with apiKeyColumns
+func scanAPIKey(row rowScanner) (*models.APIKey, error) {
+	var key models.APIKey
+	var scopes string
+	var createdAt, expiresAt, revokedAt int64
+	if err := row.Scan(&key.ID, &key.Name, &key.Hash, &scopes, &createdAt, &expiresAt, &revokedAt); err != nil {
+		return nil, err
+	}
+
+	for _, scope := range strings.Fields(scopes) {
+		key.Scopes = append(key.Scopes, models.Scope(scope))
+	}
+	key.CreatedAt = time.Unix(0, createdAt).UTC()
+	if expiresAt != 0 {
+		key.ExpiresAt = time.Unix(0, expiresAt).UTC()
+	}
+	if revokedAt != 0 {
+		key.RevokedAt = time.Unix(0, revokedAt).UTC()
+	}
+	return &key, nil
+}
+
+// unixNanoOrZero stores a zero time as 0 rather than its far negative Unix time
+func unixNanoOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// Ping checks that the database can be reached
+func (r *SQLiteAPIKeyRepository) Ping(ctx context.Context) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+	return r.db.PingContext(ctx)
+}
+
+// Close closes the database connection
+func (r *SQLiteAPIKeyRepository) Close() error {
+	if r.db != nil {
+		return r.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+// Errors for CampaignRepository
+var (
+	ErrCampaignCodeConflict = errors.New("promo code belongs to more than one campaign")
+)
+
+// InMemoryCampaignRepository implements CampaignRepository using in-memory storage
+type InMemoryCampaignRepository struct {
+	// campaigns maps every promo code to its campaign
+	campaigns map[string]*models.Campaign
+	mutex     sync.RWMutex
+}
+
+// NewInMemoryCampaignRepository creates a new repository holding the given campaigns.
+// It fails with ErrCampaignCodeConflict when two campaigns share a code.
+func NewInMemoryCampaignRepository(campaigns []models.Campaign) (*InMemoryCampaignRepository, error) {
+	repo := &InMemoryCampaignRepository{
+		campaigns: make(map[string]*models.Campaign),
+	}
+	for i := range campaigns {
+		campaign := copyCampaign(campaigns[i])
+		for _, code := range campaign.Codes {
+			if other, taken := repo.campaigns[code]; taken && other.Name != campaign.Name {
+				return nil, fmt.Errorf("%w: %s is in %q and %q", ErrCampaignCodeConflict, code, other.Name, campaign.Name)
+			}
+			repo.campaigns[code] = &campaign
+		}
+	}
+	return repo, nil
+}
+
+// FindByCode returns the campaign a promo code belongs to
+func (r *InMemoryCampaignRepository) FindByCode(ctx context.Context, code string) (*models.Campaign, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	campaign, ok := r.campaigns[code]
+	if !ok {
+		return nil, nil // Not found
+	}
+
+	// Return a copy to prevent data races
+	campaignCopy := copyCampaign(*campaign)
+	return &campaignCopy, nil
+}
+
+// copyCampaign returns a copy of a campaign that shares no memory with the original
+func copyCampaign(campaign models.Campaign) models.Campaign {
+	campaignCopy := campaign
+	campaignCopy.Categories = append([]string(nil), campaign.Categories...)
+	campaignCopy.ProductIDs = append([]string(nil), campaign.ProductIDs...)
+	campaignCopy.Codes = append([]string(nil), campaign.Codes...)
+	return campaignCopy
+}
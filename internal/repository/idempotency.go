@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+// Errors for IdempotencyRepository
+var (
+	ErrIdempotencyKeyNotOwned = errors.New("idempotency key is no longer held by this request")
+)
+
+// InMemoryIdempotencyRepository implements IdempotencyRepository using in-memory storage
+type InMemoryIdempotencyRepository struct {
+	records map[string]models.IdempotencyRecord
+	mutex   sync.Mutex
+}
+
+// NewInMemoryIdempotencyRepository creates a new, empty idempotency repository
+func NewInMemoryIdempotencyRepository() *InMemoryIdempotencyRepository {
+	return &InMemoryIdempotencyRepository{
+		records: make(map[string]models.IdempotencyRecord),
+	}
+}
+
+// Reserve stores a new in-progress record unless the key has an unexpired record
+// that is completed or still in progress
+func (r *InMemoryIdempotencyRepository) Reserve(ctx context.Context, record models.IdempotencyRecord, staleBefore time.Time) (*models.IdempotencyRecord, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	existing, ok := r.records[record.Key]
+	stale := !existing.Completed() && existing.CreatedAt.Before(staleBefore)
+	if ok && existing.ExpiresAt.After(record.CreatedAt) && !stale {
+		existing.Body = append([]byte(nil), existing.Body...)
+		return &existing, nil
+	}
+
+	record.StatusCode = 0
+	record.Body = nil
+	r.records[record.Key] = record
+	return nil, nil
+}
+
+// Complete stores the response of a key reserved by owner
+func (r *InMemoryIdempotencyRepository) Complete(ctx context.Context, key, owner string, statusCode int, body []byte) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	record, ok := r.records[key]
+	if !ok || record.Owner != owner {
+		return ErrIdempotencyKeyNotOwned
+	}
+	record.StatusCode = statusCode
+	record.Body = append([]byte(nil), body...)
+	r.records[key] = record
+	return nil
+}
+
+// Delete removes the record of a key reserved by owner
+func (r *InMemoryIdempotencyRepository) Delete(ctx context.Context, key, owner string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if record, ok := r.records[key]; !ok || record.Owner != owner {
+		return ErrIdempotencyKeyNotOwned
+	}
+	delete(r.records, key)
+	return nil
+}
+
+// DeleteExpired removes every record expired at the given time
+func (r *InMemoryIdempotencyRepository) DeleteExpired(ctx context.Context, at time.Time) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	deleted := 0
+	for key, record := range r.records {
+		if !record.ExpiresAt.After(at) {
+			delete(r.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+// idempotencyMigrations versions the schema used by SQLiteIdempotencyRepository
+var idempotencyMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_idempotency_keys",
+		Up: execStatements(
+			`CREATE TABLE IF NOT EXISTS idempotency_keys (
+				key TEXT PRIMARY KEY,
+				fingerprint TEXT NOT NULL,
+				status_code INTEGER NOT NULL DEFAULT 0,
+				body BLOB,
+				created_at INTEGER NOT NULL,
+				expires_at INTEGER NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at)`,
+		),
+		Down: execStatements(
+			`DROP TABLE IF EXISTS idempotency_keys`,
+		),
+	},
+	{
+		Version: 2,
+		Name:    "idempotency_key_owner",
+		Up: execStatements(
+			`ALTER TABLE idempotency_keys ADD COLUMN owner TEXT NOT NULL DEFAULT ''`,
+		),
+		Down: execStatements(
+			`ALTER TABLE idempotency_keys DROP COLUMN owner`,
+		),
+	},
+}
+
+// SQLiteIdempotencyRepository implements IdempotencyRepository using SQLite database
+type SQLiteIdempotencyRepository struct {
+	db *sql.DB
+	// queryTimeout bounds every operation, 0 leaves them to the caller's context
+	queryTimeout time.Duration
+}
+
+// SQLiteIdempotencyConfig contains configuration options for SQLiteIdempotencyRepository
+type SQLiteIdempotencyConfig struct {
+	// DatabasePath is the path of the SQLite database, usually the order database
+	DatabasePath string
+	// QueryTimeout bounds every database operation; 0 means no limit
+	QueryTimeout time.Duration
+}
+
+// NewSQLiteIdempotencyRepository creates a new SQLite-based idempotency repository
+func NewSQLiteIdempotencyRepository(config SQLiteIdempotencyConfig) (*SQLiteIdempotencyRepository, error) {
+	if config.DatabasePath == "" {
+		config.DatabasePath = "orders.db"
+	}
+
+	db, err := openSQLiteDatabase(config.DatabasePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := NewMigrator(db, "idempotency", idempotencyMigrations).Up(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteIdempotencyRepository{db: db, queryTimeout: config.QueryTimeout}, nil
+}
+
+// Reserve stores a new in-progress record unless the key has an unexpired record
+// that is completed or still in progress
+func (r *SQLiteIdempotencyRepository) Reserve(ctx context.Context, record models.IdempotencyRecord, staleBefore time.Time) (*models.IdempotencyRecord, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	// Claim the key unless a live record holds it, in a single statement
+	// so that concurrent requests with the same key cannot both claim it
+	result, err := r.db.ExecContext(ctx, `INSERT INTO idempotency_keys (key, fingerprint, owner, status_code, body, created_at, expires_at)
+		VALUES (?, ?, ?, 0, NULL, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			fingerprint = excluded.fingerprint, owner = excluded.owner, status_code = 0, body = NULL,
+			created_at = excluded.created_at, expires_at = excluded.expires_at
+		WHERE idempotency_keys.expires_at <= excluded.created_at
+			OR (idempotency_keys.status_code = 0 AND idempotency_keys.created_at < ?)`,
+		record.Key, record.Fingerprint, record.Owner, record.CreatedAt.UnixNano(), record.ExpiresAt.UnixNano(), staleBefore.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if claimed, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if claimed > 0 {
+		return nil, nil
+	}
+
+	var existing models.IdempotencyRecord
+	var createdAt, expiresAt int64
+	err = r.db.QueryRowContext(ctx, `SELECT key, fingerprint, owner, status_code, body, created_at, expires_at
+		FROM idempotency_keys WHERE key = ?`, record.Key).Scan(
+		&existing.Key, &existing.Fingerprint, &existing.Owner, &existing.StatusCode, &existing.Body, &createdAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Deleted since the insert, try again
+		return r.Reserve(ctx, record, staleBefore)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+	existing.CreatedAt = time.Unix(0, createdAt).UTC()
+	existing.ExpiresAt = time.Unix(0, expiresAt).UTC()
+	return &existing, nil
+}
+
+// Complete stores the response of a key reserved by owner
+func (r *SQLiteIdempotencyRepository) Complete(ctx context.Context, key, owner string, statusCode int, body []byte) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE idempotency_keys SET status_code = ?, body = ? WHERE key = ? AND owner = ?`,
+		statusCode, body, key, owner)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return ownedRow(result)
+}
+
+// Delete removes the record of a key reserved by owner
+func (r *SQLiteIdempotencyRepository) Delete(ctx context.Context, key, owner string) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = ? AND owner = ?`, key, owner)
+	if err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", err)
+	}
+	return ownedRow(result)
+}
+
+// ownedRow fails with ErrIdempotencyKeyNotOwned unless the statement matched the owner's record
+func ownedRow(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrIdempotencyKeyNotOwned
+	}
+	return nil
+}
+
+// DeleteExpired removes every record expired at the given time
+func (r *SQLiteIdempotencyRepository) DeleteExpired(ctx context.Context, at time.Time) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= ?`, at.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
+
+// Ping checks that the database can be reached
+func (r *SQLiteIdempotencyRepository) Ping(ctx context.Context) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+	return r.db.PingContext(ctx)
+}
+
+// Close closes the database connection
+func (r *SQLiteIdempotencyRepository) Close() error {
+	if r.db != nil {
+		return r.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+// idempotencyRepositories returns a fresh repository of every implementation
+func idempotencyRepositories(t *testing.T) map[string]IdempotencyRepository {
+	t.Helper()
+	sqliteRepo, err := NewSQLiteIdempotencyRepository(SQLiteIdempotencyConfig{
+		DatabasePath: filepath.Join(t.TempDir(), "orders.db"),
+	})
+	if err != nil {
+		t.Fatalf("failed to open SQLite idempotency repository: %v", err)
+	}
+	t.Cleanup(func() { sqliteRepo.Close() })
+
+	return map[string]IdempotencyRepository{
+		"memory": NewInMemoryIdempotencyRepository(),
+		"sqlite": sqliteRepo,
+	}
+}
+
+func TestIdempotencyRepository(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	claim := func(owner string, createdAt time.Time) models.IdempotencyRecord {
+		return models.IdempotencyRecord{
+			Key:         "key",
+			Fingerprint: "fingerprint-" + owner,
+			Owner:       owner,
+			CreatedAt:   createdAt,
+			ExpiresAt:   createdAt.Add(time.Hour),
+		}
+	}
+
+	tests := []struct {
+		name string
+		// run exercises the repository and returns the record the last Reserve
+		// found, nil if it claimed the key
+		run         func(ctx context.Context, repo IdempotencyRepository) (*models.IdempotencyRecord, error)
+		wantOwner   string
+		wantStatus  int
+		wantClaimed bool
+		wantErr     error
+	}{
+		{
+			name: "claim a new key",
+			run: func(ctx context.Context, repo IdempotencyRepository) (*models.IdempotencyRecord, error) {
+				return repo.Reserve(ctx, claim("a", now), now.Add(-time.Minute))
+			},
+			wantClaimed: true,
+		},
+		{
+			name: "key in progress",
+			run: func(ctx context.Context, repo IdempotencyRepository) (*models.IdempotencyRecord, error) {
+				if _, err := repo.Reserve(ctx, claim("a", now), now.Add(-time.Minute)); err != nil {
+					return nil, err
+				}
+				return repo.Reserve(ctx, claim("b", now), now.Add(-time.Minute))
+			},
+			wantOwner: "a",
+		},
+		{
+			name: "completed key",
+			run: func(ctx context.Context, repo IdempotencyRepository) (*models.IdempotencyRecord, error) {
+				if _, err := repo.Reserve(ctx, claim("a", now), now.Add(-time.Minute)); err != nil {
+					return nil, err
+				}
+				if err := repo.Complete(ctx, "key", "a", 201, []byte(`{}`)); err != nil {
+					return nil, err
+				}
+				return repo.Reserve(ctx, claim("b", now.Add(2*time.Minute)), now.Add(time.Minute))
+			},
+			wantOwner:  "a",
+			wantStatus: 201,
+		},
+		{
+			name: "stale key taken over",
+			run: func(ctx context.Context, repo IdempotencyRepository) (*models.IdempotencyRecord, error) {
+				if _, err := repo.Reserve(ctx, claim("a", now), now.Add(-time.Minute)); err != nil {
+					return nil, err
+				}
+				return repo.Reserve(ctx, claim("b", now.Add(2*time.Minute)), now.Add(time.Minute))
+			},
+			wantClaimed: true,
+		},
+		{
+			name: "expired key taken over",
+			run: func(ctx context.Context, repo IdempotencyRepository) (*models.IdempotencyRecord, error) {
+				if _, err := repo.Reserve(ctx, claim("a", now), now.Add(-time.Minute)); err != nil {
+					return nil, err
+				}
+				if err := repo.Complete(ctx, "key", "a", 201, []byte(`{}`)); err != nil {
+					return nil, err
+				}
+				return repo.Reserve(ctx, claim("b", now.Add(2*time.Hour)), now.Add(time.Hour))
+			},
+			wantClaimed: true,
+		},
+		{
+			name: "abandoned key",
+			run: func(ctx context.Context, repo IdempotencyRepository) (*models.IdempotencyRecord, error) {
+				if _, err := repo.Reserve(ctx, claim("a", now), now.Add(-time.Minute)); err != nil {
+					return nil, err
+				}
+				if err := repo.Delete(ctx, "key", "a"); err != nil {
+					return nil, err
+				}
+				return repo.Reserve(ctx, claim("b", now), now.Add(-time.Minute))
+			},
+			wantClaimed: true,
+		},
+		{
+			name: "complete after takeover",
+			run: func(ctx context.Context, repo IdempotencyRepository) (*models.IdempotencyRecord, error) {
+				if _, err := repo.Reserve(ctx, claim("a", now), now.Add(-time.Minute)); err != nil {
+					return nil, err
+				}
+				if _, err := repo.Reserve(ctx, claim("b", now.Add(2*time.Minute)), now.Add(time.Minute)); err != nil {
+					return nil, err
+				}
+				return nil, repo.Complete(ctx, "key", "a", 201, []byte(`{}`))
+			},
+			wantErr: ErrIdempotencyKeyNotOwned,
+		},
+		{
+			name: "abandon after takeover",
+			run: func(ctx context.Context, repo IdempotencyRepository) (*models.IdempotencyRecord, error) {
+				if _, err := repo.Reserve(ctx, claim("a", now), now.Add(-time.Minute)); err != nil {
+					return nil, err
+				}
+				if _, err := repo.Reserve(ctx, claim("b", now.Add(2*time.Minute)), now.Add(time.Minute)); err != nil {
+					return nil, err
+				}
+				return nil, repo.Delete(ctx, "key", "a")
+			},
+			wantErr: ErrIdempotencyKeyNotOwned,
+		},
+		{
+			name: "complete a missing key",
+			run: func(ctx context.Context, repo IdempotencyRepository) (*models.IdempotencyRecord, error) {
+				return nil, repo.Complete(ctx, "key", "a", 201, nil)
+			},
+			wantErr: ErrIdempotencyKeyNotOwned,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, repo := range idempotencyRepositories(t) {
+				t.Run(name, func(t *testing.T) {
+					existing, err := tt.run(context.Background(), repo)
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("error = %v, want %v", err, tt.wantErr)
+					}
+					if tt.wantErr != nil {
+						return
+					}
+					if tt.wantClaimed {
+						if existing != nil {
+							t.Fatalf("Reserve() = %+v, want the key claimed", existing)
+						}
+						return
+					}
+					if existing == nil {
+						t.Fatal("Reserve() claimed the key, want the existing record")
+					}
+					if existing.Owner != tt.wantOwner || existing.StatusCode != tt.wantStatus {
+						t.Errorf("Reserve() = owner %q status %d, want owner %q status %d",
+							existing.Owner, existing.StatusCode, tt.wantOwner, tt.wantStatus)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestIdempotencyRepositoryDeleteExpired(t *testing.T) {
+	now := time.Now().UTC()
+	for name, repo := range idempotencyRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for i, ttl := range []time.Duration{time.Minute, time.Hour, 2 * time.Hour} {
+				record := models.IdempotencyRecord{
+					Key:       string(rune('a' + i)),
+					Owner:     "owner",
+					CreatedAt: now,
+					ExpiresAt: now.Add(ttl),
+				}
+				if _, err := repo.Reserve(ctx, record, now.Add(-time.Minute)); err != nil {
+					t.Fatalf("Reserve() unexpected error: %v", err)
+				}
+			}
+
+			deleted, err := repo.DeleteExpired(ctx, now.Add(time.Hour))
+			if err != nil {
+				t.Fatalf("DeleteExpired() unexpected error: %v", err)
+			}
+			if deleted != 2 {
+				t.Errorf("DeleteExpired() = %d, want 2", deleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// ErrSchemaOutdated means migrations of a scope have not been applied yet
+var ErrSchemaOutdated = errors.New("database schema is out of date")
+
+// Migration is a versioned, reversible schema change
+type Migration struct {
+	// Version orders the migrations of a scope, starting at 1
+	Version int
+	// Name is a short description recorded alongside the version
+	Name string
+	// Up applies the change, Down reverts it
+	Up   func(tx *sql.Tx) error
+	Down func(tx *sql.Tx) error
+}
+
+// execStatements returns a migration step that runs SQL statements in order
+func execStatements(statements ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Migrator applies the migrations of one scope (e.g. "orders") to a database.
+// Applied versions are recorded in the schema_migrations table, so several
+// repositories can keep their own migrations in the same database file.
+type Migrator struct {
+	db         *sql.DB
+	scope      string
+	migrations []Migration
+}
+
+// NewMigrator creates a migrator for the given scope and migrations
+func NewMigrator(db *sql.DB, scope string, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	return &Migrator{
+		db:         db,
+		scope:      scope,
+		migrations: sorted,
+	}
+}
+
+// ensureTable creates the schema_migrations table if needed
+func (m *Migrator) ensureTable() error {
+	_, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		scope TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		applied_at INTEGER NOT NULL,
+		PRIMARY KEY (scope, version)
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// applied returns the versions already applied for the scope
+func (m *Migrator) applied() (map[int]bool, error) {
+	rows, err := m.db.Query(`SELECT version FROM schema_migrations WHERE scope = ?`, m.scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		versions[version] = true
+	}
+	return versions, rows.Err()
+}
+
+// Version returns the highest applied version of the scope, 0 if none
+func (m *Migrator) Version() (int, error) {
+	if err := m.ensureTable(); err != nil {
+		return 0, err
+	}
+
+	var version sql.NullInt64
+	err := m.db.QueryRow(`SELECT MAX(version) FROM schema_migrations WHERE scope = ?`, m.scope).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+// Check fails with ErrSchemaOutdated if any migration has not been applied.
+// Unlike the other methods it never writes, so it works on a read-only database.
+func (m *Migrator) Check() error {
+	var tables int
+	err := m.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`).Scan(&tables)
+	if err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+	applied := make(map[int]bool)
+	if tables > 0 {
+		if applied, err = m.applied(); err != nil {
+			return err
+		}
+	}
+
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			return fmt.Errorf("%w: %s migration %d (%s) is not applied", ErrSchemaOutdated, m.scope, migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// Up applies every pending migration in version order.
+// Each migration runs in its own transaction together with its record.
+func (m *Migrator) Up() error {
+	if err := m.ensureTable(); err != nil {
+		return err
+	}
+
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, migration := range m.migrations {
+		if applied[migration.Version] {
+			continue
+		}
+
+		slog.Debug("Applying migration", "scope", m.scope, "version", migration.Version, "name", migration.Name)
+		err := m.inTransaction(func(tx *sql.Tx) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (scope, version, name, applied_at) VALUES (?, ?, ?, ?)`,
+				m.scope, migration.Version, migration.Name, time.Now().UTC().UnixNano())
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply %s migration %d (%s): %w", m.scope, migration.Version, migration.Name, err)
+		}
+		count++
+	}
+
+	if count > 0 {
+		slog.Info("Applied migrations", "scope", m.scope, "count", count)
+	}
+	return nil
+}
+
+// Down reverts the given number of most recently applied migrations
+func (m *Migrator) Down(steps int) error {
+	if err := m.ensureTable(); err != nil {
+		return err
+	}
+
+	applied, err := m.applied()
+	if err != nil {
+		return err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+		migration := m.migrations[i]
+		if !applied[migration.Version] {
+			continue
+		}
+		if migration.Down == nil {
+			return fmt.Errorf("%s migration %d (%s) cannot be reverted", m.scope, migration.Version, migration.Name)
+		}
+
+		slog.Info("Reverting migration", "scope", m.scope, "version", migration.Version, "name", migration.Name)
+		err := m.inTransaction(func(tx *sql.Tx) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`DELETE FROM schema_migrations WHERE scope = ? AND version = ?`, m.scope, migration.Version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to revert %s migration %d (%s): %w", m.scope, migration.Version, migration.Name, err)
+		}
+		steps--
+	}
+
+	return nil
+}
+
+// inTransaction runs fn in a transaction, committing only if it succeeds
+func (m *Migrator) inTransaction(fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testMigrations create a table and add a column to it
+var testMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_widgets",
+		Up:      execStatements(`CREATE TABLE widgets (id TEXT PRIMARY KEY)`),
+		Down:    execStatements(`DROP TABLE widgets`),
+	},
+	{
+		Version: 2,
+		Name:    "widget_color",
+		Up:      execStatements(`ALTER TABLE widgets ADD COLUMN color TEXT NOT NULL DEFAULT ''`),
+		Down:    execStatements(`ALTER TABLE widgets DROP COLUMN color`),
+	},
+}
+
+// openTestDatabase opens an empty SQLite database removed after the test
+func openTestDatabase(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := openSQLiteDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// columns returns the column names of a table, nil if it does not exist
+func columns(t *testing.T, db *sql.DB, table string) []string {
+	t.Helper()
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		t.Fatalf("failed to read table %s: %v", table, err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("failed to read table %s: %v", table, err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestMigrator(t *testing.T) {
+	tests := []struct {
+		name string
+		// run migrates the database of a fresh migrator
+		run         func(m *Migrator) error
+		wantVersion int
+		wantColumns []string
+	}{
+		{
+			name:        "up",
+			run:         func(m *Migrator) error { return m.Up() },
+			wantVersion: 2,
+			wantColumns: []string{"id", "color"},
+		},
+		{
+			name: "up twice",
+			run: func(m *Migrator) error {
+				if err := m.Up(); err != nil {
+					return err
+				}
+				return m.Up()
+			},
+			wantVersion: 2,
+			wantColumns: []string{"id", "color"},
+		},
+		{
+			name: "down one step",
+			run: func(m *Migrator) error {
+				if err := m.Up(); err != nil {
+					return err
+				}
+				return m.Down(1)
+			},
+			wantVersion: 1,
+			wantColumns: []string{"id"},
+		},
+		{
+			name: "down more steps than applied",
+			run: func(m *Migrator) error {
+				if err := m.Up(); err != nil {
+					return err
+				}
+				return m.Down(5)
+			},
+			wantVersion: 0,
+		},
+		{
+			name: "down then up",
+			run: func(m *Migrator) error {
+				if err := m.Up(); err != nil {
+					return err
+				}
+				if err := m.Down(2); err != nil {
+					return err
+				}
+				return m.Up()
+			},
+			wantVersion: 2,
+			wantColumns: []string{"id", "color"},
+		},
+		{
+			name:        "down on an empty database",
+			run:         func(m *Migrator) error { return m.Down(1) },
+			wantVersion: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDatabase(t)
+			migrator := NewMigrator(db, "widgets", testMigrations)
+
+			if err := tt.run(migrator); err != nil {
+				t.Fatalf("migration unexpected error: %v", err)
+			}
+			version, err := migrator.Version()
+			if err != nil {
+				t.Fatalf("Version() unexpected error: %v", err)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("Version() = %d, want %d", version, tt.wantVersion)
+			}
+			if got := columns(t, db, "widgets"); !slices.Equal(got, tt.wantColumns) {
+				t.Errorf("widgets columns = %v, want %v", got, tt.wantColumns)
+			}
+
+			err = migrator.Check()
+			if outdated := version < len(testMigrations); outdated != errors.Is(err, ErrSchemaOutdated) {
+				t.Errorf("Check() error = %v at version %d", err, version)
+			}
+		})
+	}
+}
+
+func TestMigratorUpFailure(t *testing.T) {
+	db := openTestDatabase(t)
+	migrations := append([]Migration{}, testMigrations...)
+	migrations = append(migrations, Migration{
+		Version: 3,
+		Name:    "broken",
+		Up:      execStatements(`CREATE TABLE gadgets (id TEXT)`, `NOT VALID SQL`),
+	})
+	migrator := NewMigrator(db, "widgets", migrations)
+
+	if err := migrator.Up(); err == nil {
+		t.Fatal("Up() succeeded, want the broken migration to fail")
+	}
+	if version, err := migrator.Version(); err != nil || version != 2 {
+		t.Errorf("Version() = %d, %v, want 2", version, err)
+	}
+	// The failed migration is rolled back as a whole
+	if got := columns(t, db, "gadgets"); got != nil {
+		t.Errorf("gadgets columns = %v, want the table rolled back", got)
+	}
+}
+
+func TestMigratorIrreversible(t *testing.T) {
+	db := openTestDatabase(t)
+	migrations := append([]Migration{}, testMigrations...)
+	migrations = append(migrations, Migration{
+		Version: 3,
+		Name:    "irreversible",
+		Up:      execStatements(`CREATE TABLE gadgets (id TEXT)`),
+	})
+	migrator := NewMigrator(db, "widgets", migrations)
+
+	if err := migrator.Up(); err != nil {
+		t.Fatalf("Up() unexpected error: %v", err)
+	}
+	if err := migrator.Down(1); err == nil {
+		t.Fatal("Down() reverted a migration without Down")
+	}
+	if version, err := migrator.Version(); err != nil || version != 3 {
+		t.Errorf("Version() = %d, %v, want 3", version, err)
+	}
+}
+
+func TestMigratorScopes(t *testing.T) {
+	db := openTestDatabase(t)
+	if err := NewMigrator(db, "widgets", testMigrations).Up(); err != nil {
+		t.Fatalf("Up() unexpected error: %v", err)
+	}
+
+	other := NewMigrator(db, "gadgets", []Migration{{
+		Version: 1,
+		Name:    "create_gadgets",
+		Up:      execStatements(`CREATE TABLE gadgets (id TEXT PRIMARY KEY)`),
+		Down:    execStatements(`DROP TABLE gadgets`),
+	}})
+	if err := other.Check(); !errors.Is(err, ErrSchemaOutdated) {
+		t.Fatalf("Check() error = %v, want %v", err, ErrSchemaOutdated)
+	}
+	if err := other.Up(); err != nil {
+		t.Fatalf("Up() unexpected error: %v", err)
+	}
+	if err := other.Down(1); err != nil {
+		t.Fatalf("Down() unexpected error: %v", err)
+	}
+	if version, err := NewMigrator(db, "widgets", testMigrations).Version(); err != nil || version != 2 {
+		t.Errorf("widgets Version() = %d, %v, want 2 after reverting another scope", version, err)
+	}
+}
+
+func TestMigratorCheckDoesNotWrite(t *testing.T) {
+	db := openTestDatabase(t)
+	if err := NewMigrator(db, "widgets", testMigrations).Check(); !errors.Is(err, ErrSchemaOutdated) {
+		t.Fatalf("Check() error = %v, want %v", err, ErrSchemaOutdated)
+	}
+	if got := columns(t, db, "schema_migrations"); got != nil {
+		t.Errorf("Check() created schema_migrations")
+	}
+}
+
+func TestRepositoryMigrationsRoundTrip(t *testing.T) {
+	scopes := []struct {
+		scope      string
+		migrations []Migration
+	}{
+		{"products", productMigrations(true)},
+		{"orders", orderMigrations},
+		{"idempotency", idempotencyMigrations},
+		{"apikeys", apiKeyMigrations},
+		{"promo", promoMigrations([]PromoSource{{Name: "a", Path: "a.txt"}, {Name: "b", Path: "b.txt"}})},
+	}
+
+	for _, tt := range scopes {
+		t.Run(tt.scope, func(t *testing.T) {
+			migrator := NewMigrator(openTestDatabase(t), tt.scope, tt.migrations)
+			if err := migrator.Up(); err != nil {
+				t.Fatalf("Up() unexpected error: %v", err)
+			}
+			if err := migrator.Down(len(tt.migrations)); err != nil {
+				t.Fatalf("Down() unexpected error: %v", err)
+			}
+			if err := migrator.Up(); err != nil {
+				t.Fatalf("Up() after Down() unexpected error: %v", err)
+			}
+			if err := migrator.Check(); err != nil {
+				t.Fatalf("Check() unexpected error: %v", err)
+			}
+		})
+	}
+}
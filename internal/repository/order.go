@@ -1,12 +1,21 @@
 package repository
 
 import (
+	"context"
+	"errors"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jilani-go/glofox/internal/models"
 )
 
+// Errors for OrderRepository
+var (
+	ErrOrderStatusConflict = errors.New("order status was changed concurrently")
+)
+
 // InMemoryOrderRepository implements OrderRepository using in-memory storage
 type InMemoryOrderRepository struct {
 	orders      []models.Order
@@ -23,15 +32,166 @@ func NewInMemoryOrderRepository(productRepo ProductRepository) *InMemoryOrderRep
 }
 
 // Create adds a new order
-func (r *InMemoryOrderRepository) Create(order *models.Order) (*models.Order, error) {
+func (r *InMemoryOrderRepository) Create(ctx context.Context, order *models.Order) (*models.Order, error) {
 	r.mutex.Lock()         // Lock for writing
 	defer r.mutex.Unlock() // Ensure unlock happens even if there's a panic
 
+	r.add(order)
+	return order, nil
+}
+
+// CreateRedeeming adds a new order if check accepts the redemptions of its coupon
+func (r *InMemoryOrderRepository) CreateRedeeming(ctx context.Context, order *models.Order, check func(models.RedemptionCount) error) (*models.Order, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := check(r.countRedemptions(order.CouponCode, order.CustomerID)); err != nil {
+		return nil, err
+	}
+	r.add(order)
+	return order, nil
+}
+
+// add stores a new order; the caller must hold the write lock
+func (r *InMemoryOrderRepository) add(order *models.Order) {
 	// Generate a new UUID for the order
 	order.ID = uuid.New().String()
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = time.Now().UTC()
+	}
 
 	// Add to storage
-	r.orders = append(r.orders, *order)
+	r.orders = append(r.orders, copyOrder(*order))
+}
 
-	return order, nil
+// CountRedemptions returns how often a promo code was redeemed in total and by one customer
+func (r *InMemoryOrderRepository) CountRedemptions(ctx context.Context, code, customerID string) (models.RedemptionCount, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.countRedemptions(code, customerID), nil
+}
+
+// countRedemptions counts the orders redeeming a code; the caller must hold the lock
+func (r *InMemoryOrderRepository) countRedemptions(code, customerID string) models.RedemptionCount {
+	var count models.RedemptionCount
+	for _, order := range r.orders {
+		if order.CouponCode != code || !order.Status.RedeemsCoupon() {
+			continue
+		}
+		count.Total++
+		if customerID != "" && order.CustomerID == customerID {
+			count.Customer++
+		}
+	}
+	return count
+}
+
+// FindByID returns an order by its ID
+func (r *InMemoryOrderRepository) FindByID(ctx context.Context, id string) (*models.Order, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, order := range r.orders {
+		if order.ID == id {
+			orderCopy := copyOrder(order)
+			return &orderCopy, nil
+		}
+	}
+	return nil, nil // Not found
+}
+
+// List returns a page of orders matching the filter, newest first,
+// along with the total number of matching orders
+func (r *InMemoryOrderRepository) List(ctx context.Context, filter models.OrderFilter) ([]models.Order, int, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	matches := make([]models.Order, 0)
+	for _, order := range r.orders {
+		if orderMatches(order, filter) {
+			matches = append(matches, order)
+		}
+	}
+
+	// Newest first, then by ID like the SQLite repository, so pages are stable
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.After(matches[j].CreatedAt)
+		}
+		return matches[i].ID < matches[j].ID
+	})
+
+	total := len(matches)
+	start := min(filter.Offset, total)
+	end := total
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, total)
+	}
+
+	page := make([]models.Order, 0, end-start)
+	for _, order := range matches[start:end] {
+		page = append(page, copyOrder(order))
+	}
+
+	return page, total, nil
+}
+
+// UpdateStatus moves an order from one status to another
+func (r *InMemoryOrderRepository) UpdateStatus(ctx context.Context, id string, from, to models.OrderStatus, at time.Time) (*models.Order, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.orders {
+		order := &r.orders[i]
+		if order.ID != id {
+			continue
+		}
+
+		if order.Status != from {
+			return nil, ErrOrderStatusConflict
+		}
+		order.Status = to
+		order.StatusHistory = append(order.StatusHistory, models.StatusChange{Status: to, At: at})
+
+		orderCopy := copyOrder(*order)
+		return &orderCopy, nil
+	}
+	return nil, nil // Not found
+}
+
+// orderMatches reports whether an order satisfies every restriction of a filter
+func orderMatches(order models.Order, filter models.OrderFilter) bool {
+	if !filter.From.IsZero() && order.CreatedAt.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !order.CreatedAt.Before(filter.To) {
+		return false
+	}
+	if filter.CouponCode != "" && order.CouponCode != filter.CouponCode {
+		return false
+	}
+	if filter.ProductID != "" {
+		for _, item := range order.Items {
+			if item.ProductID == filter.ProductID {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// copyOrder returns a copy of an order that shares no memory with the original
+func copyOrder(order models.Order) models.Order {
+	orderCopy := order
+	orderCopy.Items = make([]models.OrderItem, len(order.Items))
+	copy(orderCopy.Items, order.Items)
+	orderCopy.StatusHistory = make([]models.StatusChange, len(order.StatusHistory))
+	copy(orderCopy.StatusHistory, order.StatusHistory)
+	if order.Promotion != nil {
+		promotionCopy := *order.Promotion
+		orderCopy.Promotion = &promotionCopy
+	}
+	return orderCopy
 }
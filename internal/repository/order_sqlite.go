@@ -0,0 +1,559 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/tracing"
+)
+
+// orderMigrations versions the schema used by SQLiteOrderRepository
+var orderMigrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_orders",
+		Up: execStatements(
+			`CREATE TABLE IF NOT EXISTS orders (
+				id TEXT PRIMARY KEY,
+				coupon_code TEXT NOT NULL DEFAULT '',
+				currency TEXT NOT NULL,
+				subtotal INTEGER NOT NULL,
+				discount INTEGER NOT NULL,
+				total INTEGER NOT NULL,
+				promotion_code TEXT NOT NULL DEFAULT '',
+				discount_type TEXT NOT NULL DEFAULT '',
+				discount_percentage INTEGER NOT NULL DEFAULT 0,
+				discount_amount INTEGER NOT NULL DEFAULT 0,
+				discount_buy_quantity INTEGER NOT NULL DEFAULT 0,
+				discount_get_quantity INTEGER NOT NULL DEFAULT 0,
+				discount_category TEXT NOT NULL DEFAULT '',
+				discount_description TEXT NOT NULL DEFAULT '',
+				status TEXT NOT NULL,
+				created_at INTEGER NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_orders_coupon_code ON orders(coupon_code)`,
+			`CREATE TABLE IF NOT EXISTS order_items (
+				order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+				position INTEGER NOT NULL,
+				product_id TEXT NOT NULL,
+				quantity INTEGER NOT NULL,
+				unit_price INTEGER NOT NULL,
+				line_total INTEGER NOT NULL,
+				discount INTEGER NOT NULL,
+				total INTEGER NOT NULL,
+				PRIMARY KEY (order_id, position)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_order_items_product_id ON order_items(product_id)`,
+			`CREATE TABLE IF NOT EXISTS order_status_history (
+				order_id TEXT NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+				position INTEGER NOT NULL,
+				status TEXT NOT NULL,
+				changed_at INTEGER NOT NULL,
+				PRIMARY KEY (order_id, position)
+			)`,
+		),
+		Down: execStatements(
+			`DROP TABLE IF EXISTS order_status_history`,
+			`DROP TABLE IF EXISTS order_items`,
+			`DROP TABLE IF EXISTS orders`,
+		),
+	},
+	{
+		Version: 2,
+		Name:    "promo_redemptions",
+		Up: execStatements(
+			`ALTER TABLE orders ADD COLUMN customer_id TEXT NOT NULL DEFAULT ''`,
+			`CREATE TABLE IF NOT EXISTS promo_redemptions (
+				order_id TEXT PRIMARY KEY REFERENCES orders(id) ON DELETE CASCADE,
+				code TEXT NOT NULL,
+				customer_id TEXT NOT NULL DEFAULT '',
+				redeemed_at INTEGER NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_promo_redemptions_code ON promo_redemptions(code, customer_id)`,
+			// Orders placed before redemptions were tracked count as anonymous redemptions
+			`INSERT INTO promo_redemptions (order_id, code, redeemed_at)
+				SELECT id, coupon_code, created_at FROM orders WHERE coupon_code != ''`,
+		),
+		Down: execStatements(
+			`DROP TABLE IF EXISTS promo_redemptions`,
+			`ALTER TABLE orders DROP COLUMN customer_id`,
+		),
+	},
+}
+
+// orderColumns lists the orders columns in the order scanOrder expects them
+const orderColumns = `id, coupon_code, currency, subtotal, discount, total,
+	promotion_code, discount_type, discount_percentage, discount_amount,
+	discount_buy_quantity, discount_get_quantity, discount_category, discount_description,
+	status, created_at, customer_id`
+
+// SQLiteOrderRepository implements OrderRepository using SQLite database
+type SQLiteOrderRepository struct {
+	db *sql.DB
+	// queryTimeout bounds every operation, 0 leaves them to the caller's context
+	queryTimeout time.Duration
+}
+
+// SQLiteOrderConfig contains configuration options for SQLiteOrderRepository
+type SQLiteOrderConfig struct {
+	// DatabasePath is the path where the SQLite database will be stored
+	DatabasePath string
+	// QueryTimeout bounds every database operation; 0 means no limit
+	QueryTimeout time.Duration
+}
+
+// NewSQLiteOrderRepository creates a new SQLite-based order repository
+func NewSQLiteOrderRepository(config SQLiteOrderConfig) (*SQLiteOrderRepository, error) {
+	if config.DatabasePath == "" {
+		config.DatabasePath = "orders.db"
+	}
+
+	db, err := openSQLiteDatabase(config.DatabasePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := NewMigrator(db, "orders", orderMigrations).Up(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteOrderRepository{db: db, queryTimeout: config.QueryTimeout}, nil
+}
+
+// Create adds a new order
+func (r *SQLiteOrderRepository) Create(ctx context.Context, order *models.Order) (_ *models.Order, err error) {
+	ctx, span := startSpan(ctx, "SQLiteOrderRepository.Create", "orders")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	err = immediateTx(ctx, r.db, func(conn *sql.Conn) error {
+		return insertOrder(ctx, conn, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// CreateRedeeming adds a new order if check accepts the redemptions of its
+// coupon. The count and the insert share an immediate transaction, which
+// holds the write lock, so concurrent orders cannot both take the last
+// redemption even when placed through different processes.
+func (r *SQLiteOrderRepository) CreateRedeeming(ctx context.Context, order *models.Order, check func(models.RedemptionCount) error) (_ *models.Order, err error) {
+	ctx, span := startSpan(ctx, "SQLiteOrderRepository.CreateRedeeming", "orders")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	err = immediateTx(ctx, r.db, func(conn *sql.Conn) error {
+		count, err := countRedemptions(ctx, conn, order.CouponCode, order.CustomerID)
+		if err != nil {
+			return err
+		}
+		if err := check(count); err != nil {
+			return err
+		}
+		return insertOrder(ctx, conn, order)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// insertOrder inserts an order with its items, status history and coupon
+// redemption, generating its ID
+func insertOrder(ctx context.Context, conn *sql.Conn, order *models.Order) error {
+	// Generate a new UUID for the order
+	order.ID = uuid.New().String()
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = time.Now().UTC()
+	}
+
+	var promotion models.Promotion
+	if order.Promotion != nil {
+		promotion = *order.Promotion
+	}
+
+	_, err := conn.ExecContext(ctx, `INSERT INTO orders (`+orderColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		order.ID, order.CouponCode, order.Total.Currency,
+		order.Subtotal.Amount, order.Discount.Amount, order.Total.Amount,
+		promotion.Code, string(promotion.Discount.Type), promotion.Discount.Percentage,
+		promotion.Discount.Amount.Amount, promotion.Discount.BuyQuantity, promotion.Discount.GetQuantity,
+		promotion.Discount.Category, promotion.Discount.Description,
+		string(order.Status), order.CreatedAt.UnixNano(), order.CustomerID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert order: %w", err)
+	}
+
+	// Redeem the coupon together with the order
+	if order.CouponCode != "" {
+		_, err := conn.ExecContext(ctx, `INSERT INTO promo_redemptions (order_id, code, customer_id, redeemed_at) VALUES (?, ?, ?, ?)`,
+			order.ID, order.CouponCode, order.CustomerID, order.CreatedAt.UnixNano())
+		if err != nil {
+			return fmt.Errorf("failed to insert promo redemption: %w", err)
+		}
+	}
+
+	for i, change := range order.StatusHistory {
+		_, err := conn.ExecContext(ctx, `INSERT INTO order_status_history (order_id, position, status, changed_at) VALUES (?, ?, ?, ?)`,
+			order.ID, i, string(change.Status), change.At.UnixNano())
+		if err != nil {
+			return fmt.Errorf("failed to insert order status history: %w", err)
+		}
+	}
+
+	stmt, err := conn.PrepareContext(ctx, `INSERT INTO order_items
+		(order_id, position, product_id, quantity, unit_price, line_total, discount, total)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, item := range order.Items {
+		_, err := stmt.ExecContext(ctx, order.ID, i, item.ProductID, item.Quantity,
+			item.UnitPrice.Amount, item.LineTotal.Amount, item.Discount.Amount, item.Total.Amount)
+		if err != nil {
+			return fmt.Errorf("failed to insert order item: %w", err)
+		}
+	}
+	return nil
+}
+
+// FindByID returns an order by its ID
+func (r *SQLiteOrderRepository) FindByID(ctx context.Context, id string) (_ *models.Order, err error) {
+	ctx, span := startSpan(ctx, "SQLiteOrderRepository.FindByID", "orders")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	row := r.db.QueryRowContext(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = ?`, id)
+	order, err := scanOrder(row)
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read order %s: %w", id, err)
+	}
+
+	orders := []*models.Order{order}
+	if err := r.loadItems(ctx, orders); err != nil {
+		return nil, err
+	}
+	if err := r.loadStatusHistory(ctx, orders); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// List returns a page of orders matching the filter, newest first,
+// along with the total number of matching orders
+func (r *SQLiteOrderRepository) List(ctx context.Context, filter models.OrderFilter) (_ []models.Order, _ int, err error) {
+	ctx, span := startSpan(ctx, "SQLiteOrderRepository.List", "orders")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	// Build the WHERE clause from the filter
+	var conditions []string
+	var args []interface{}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.From.UnixNano())
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.To.UnixNano())
+	}
+	if filter.CouponCode != "" {
+		conditions = append(conditions, "coupon_code = ?")
+		args = append(args, filter.CouponCode)
+	}
+	if filter.ProductID != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM order_items WHERE order_items.order_id = orders.id AND order_items.product_id = ?)")
+		args = append(args, filter.ProductID)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Count all matches before paging
+	var total int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM orders"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count orders: %w", err)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = -1 // No limit
+	}
+	query := "SELECT " + orderColumns + " FROM orders" + where + " ORDER BY created_at DESC, id LIMIT ? OFFSET ?"
+	rows, err := r.db.QueryContext(ctx, query, append(args, limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list orders: %w", err)
+	}
+	defer rows.Close()
+
+	var page []*models.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read order: %w", err)
+		}
+		page = append(page, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list orders: %w", err)
+	}
+
+	if err := r.loadItems(ctx, page); err != nil {
+		return nil, 0, err
+	}
+	if err := r.loadStatusHistory(ctx, page); err != nil {
+		return nil, 0, err
+	}
+
+	orders := make([]models.Order, len(page))
+	for i, order := range page {
+		orders[i] = *order
+	}
+
+	return orders, total, nil
+}
+
+// loadItems reads the items of the given orders with a single query
+func (r *SQLiteOrderRepository) loadItems(ctx context.Context, orders []*models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*models.Order, len(orders))
+	placeholders := make([]string, len(orders))
+	args := make([]interface{}, len(orders))
+	for i, order := range orders {
+		byID[order.ID] = order
+		order.Items = []models.OrderItem{}
+		placeholders[i] = "?"
+		args[i] = order.ID
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT order_id, product_id, quantity, unit_price, line_total, discount, total
+		FROM order_items WHERE order_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY order_id, position`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to read order items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID string
+		var item models.OrderItem
+		err := rows.Scan(&orderID, &item.ProductID, &item.Quantity,
+			&item.UnitPrice.Amount, &item.LineTotal.Amount, &item.Discount.Amount, &item.Total.Amount)
+		if err != nil {
+			return fmt.Errorf("failed to read order item: %w", err)
+		}
+
+		order := byID[orderID]
+		currency := order.Total.Currency
+		item.UnitPrice.Currency = currency
+		item.LineTotal.Currency = currency
+		item.Discount.Currency = currency
+		item.Total.Currency = currency
+		order.Items = append(order.Items, item)
+	}
+
+	return rows.Err()
+}
+
+// loadStatusHistory reads the status history of the given orders with a single query
+func (r *SQLiteOrderRepository) loadStatusHistory(ctx context.Context, orders []*models.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*models.Order, len(orders))
+	placeholders := make([]string, len(orders))
+	args := make([]interface{}, len(orders))
+	for i, order := range orders {
+		byID[order.ID] = order
+		order.StatusHistory = []models.StatusChange{}
+		placeholders[i] = "?"
+		args[i] = order.ID
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT order_id, status, changed_at
+		FROM order_status_history WHERE order_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY order_id, position`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to read order status history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID, status string
+		var changedAt int64
+		if err := rows.Scan(&orderID, &status, &changedAt); err != nil {
+			return fmt.Errorf("failed to read order status change: %w", err)
+		}
+
+		order := byID[orderID]
+		order.StatusHistory = append(order.StatusHistory, models.StatusChange{
+			Status: models.OrderStatus(status),
+			At:     time.Unix(0, changedAt).UTC(),
+		})
+	}
+
+	return rows.Err()
+}
+
+// UpdateStatus moves an order from one status to another
+func (r *SQLiteOrderRepository) UpdateStatus(ctx context.Context, id string, from, to models.OrderStatus, at time.Time) (_ *models.Order, err error) {
+	ctx, span := startSpan(ctx, "SQLiteOrderRepository.UpdateStatus", "orders")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Only move the order if nobody else moved it in the meantime
+	result, err := tx.ExecContext(ctx, `UPDATE orders SET status = ? WHERE id = ? AND status = ?`, string(to), id, string(from))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update order status: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update order status: %w", err)
+	}
+	if updated == 0 {
+		var exists int
+		err := tx.QueryRowContext(ctx, `SELECT 1 FROM orders WHERE id = ?`, id).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return nil, nil // Not found
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read order %s: %w", id, err)
+		}
+		return nil, ErrOrderStatusConflict
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO order_status_history (order_id, position, status, changed_at)
+		SELECT ?, COALESCE(MAX(position), -1) + 1, ?, ? FROM order_status_history WHERE order_id = ?`,
+		id, string(to), at.UnixNano(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert order status history: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit order status: %w", err)
+	}
+
+	return r.FindByID(ctx, id)
+}
+
+// CountRedemptions returns how often a promo code was redeemed in total and by one customer
+func (r *SQLiteOrderRepository) CountRedemptions(ctx context.Context, code, customerID string) (_ models.RedemptionCount, err error) {
+	ctx, span := startSpan(ctx, "SQLiteOrderRepository.CountRedemptions", "orders")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return countRedemptions(ctx, r.db, code, customerID)
+}
+
+// countRedemptions counts the redemptions of a code whose order still
+// redeems it, see models.OrderStatus.RedeemsCoupon
+func countRedemptions(ctx context.Context, q queryRower, code, customerID string) (models.RedemptionCount, error) {
+	var count models.RedemptionCount
+	err := q.QueryRowContext(ctx, `SELECT COUNT(*), COUNT(CASE WHEN ? != '' AND r.customer_id = ? THEN 1 END)
+		FROM promo_redemptions r JOIN orders o ON o.id = r.order_id
+		WHERE r.code = ? AND o.status NOT IN (?, ?)`,
+		customerID, customerID, code, string(models.OrderStatusCancelled), string(models.OrderStatusRefunded),
+	).Scan(&count.Total, &count.Customer)
+	if err != nil {
+		return count, fmt.Errorf("failed to count redemptions of %s: %w", code, err)
+	}
+	return count, nil
+}
+
+// queryRower is implemented by *sql.DB, *sql.Conn and *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanOrder reads an order (without items) from a row selected with orderColumns
+func scanOrder(row rowScanner) (*models.Order, error) {
+	var order models.Order
+	var currency string
+	var promotion models.Promotion
+	var discountType string
+	var status string
+	var createdAt int64
+
+	err := row.Scan(&order.ID, &order.CouponCode, &currency,
+		&order.Subtotal.Amount, &order.Discount.Amount, &order.Total.Amount,
+		&promotion.Code, &discountType, &promotion.Discount.Percentage,
+		&promotion.Discount.Amount.Amount, &promotion.Discount.BuyQuantity, &promotion.Discount.GetQuantity,
+		&promotion.Discount.Category, &promotion.Discount.Description,
+		&status, &createdAt, &order.CustomerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	order.Subtotal.Currency = currency
+	order.Discount.Currency = currency
+	order.Total.Currency = currency
+	order.CreatedAt = time.Unix(0, createdAt).UTC()
+	order.Status = models.OrderStatus(status)
+
+	// An empty discount type means no promotion was applied
+	if discountType != "" {
+		promotion.Discount.Type = models.DiscountType(discountType)
+		promotion.Discount.Amount.Currency = currency
+		order.Promotion = &promotion
+	}
+
+	return &order, nil
+}
+
+// Ping checks that the database can be reached
+func (r *SQLiteOrderRepository) Ping(ctx context.Context) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+	return r.db.PingContext(ctx)
+}
+
+// Close closes the database connection
+func (r *SQLiteOrderRepository) Close() error {
+	if r.db != nil {
+		return r.db.Close()
+	}
+	return nil
+}
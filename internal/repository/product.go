@@ -1,84 +1,108 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jilani-go/glofox/internal/models"
 )
 
+// Errors for ProductRepository
+var (
+	ErrProductExists = errors.New("product already exists")
+)
+
 // InMemoryProductRepository implements ProductRepository using in-memory storage
 type InMemoryProductRepository struct {
 	products []models.Product
 	mutex    sync.RWMutex // Add RWMutex for thread safety
 }
 
-// NewInMemoryProductRepository creates a new repository with predefined products
-func NewInMemoryProductRepository() *InMemoryProductRepository {
-	// Initialize with some products
-	products := []models.Product{
+// NewInMemoryProductRepository creates a new repository, holding the
+// built-in dessert catalog if seed is set and empty otherwise
+func NewInMemoryProductRepository(seed bool) *InMemoryProductRepository {
+	if !seed {
+		return &InMemoryProductRepository{products: []models.Product{}}
+	}
+	products := defaultProducts()
+
+	// Seed products count as created when the repository is
+	now := time.Now().UTC()
+	for i := range products {
+		products[i].CreatedAt = now
+		products[i].UpdatedAt = now
+	}
+
+	return &InMemoryProductRepository{
+		products: products,
+	}
+}
+
+// defaultProducts returns the built-in dessert catalog used to seed repositories
+func defaultProducts() []models.Product {
+	return []models.Product{
 		{
 			ID:       "1",
 			Name:     "Waffle with Berries",
-			Price:    6.5,
+			Price:    models.NewMoney(650, models.DefaultCurrency),
 			Category: "Waffle",
 		},
 		{
 			ID:       "2",
 			Name:     "Vanilla Bean Crème Brûlée",
-			Price:    7.0,
+			Price:    models.NewMoney(700, models.DefaultCurrency),
 			Category: "Crème Brûlée",
 		},
 		{
 			ID:       "3",
 			Name:     "Macaron Mix of Five",
-			Price:    8.0,
+			Price:    models.NewMoney(800, models.DefaultCurrency),
 			Category: "Macaron",
 		},
 		{
 			ID:       "4",
 			Name:     "Classic Tiramisu",
-			Price:    5.5,
+			Price:    models.NewMoney(550, models.DefaultCurrency),
 			Category: "Tiramisu",
 		},
 		{
 			ID:       "5",
 			Name:     "Pistachio Baklava",
-			Price:    4.0,
+			Price:    models.NewMoney(400, models.DefaultCurrency),
 			Category: "Baklava",
 		},
 		{
 			ID:       "6",
 			Name:     "Lemon Meringue Pie",
-			Price:    5.0,
+			Price:    models.NewMoney(500, models.DefaultCurrency),
 			Category: "Pie",
 		},
 		{
 			ID:       "7",
 			Name:     "Red Velvet Cake",
-			Price:    4.5,
+			Price:    models.NewMoney(450, models.DefaultCurrency),
 			Category: "Cake",
 		},
 		{
 			ID:       "8",
 			Name:     "Salted Caramel Brownie",
-			Price:    4.5,
+			Price:    models.NewMoney(450, models.DefaultCurrency),
 			Category: "Brownie",
 		},
 		{
 			ID:       "9",
 			Name:     "Vanilla Panna Cotta",
-			Price:    6.5,
+			Price:    models.NewMoney(650, models.DefaultCurrency),
 			Category: "Panna Cotta",
 		},
 	}
-
-	return &InMemoryProductRepository{
-		products: products,
-	}
 }
 
 // FindAll returns all products
-func (r *InMemoryProductRepository) FindAll() ([]models.Product, error) {
+func (r *InMemoryProductRepository) FindAll(ctx context.Context) ([]models.Product, error) {
 	r.mutex.RLock()         // Use read lock for read-only operations
 	defer r.mutex.RUnlock() // Ensure unlock happens even if there's a panic
 
@@ -90,7 +114,7 @@ func (r *InMemoryProductRepository) FindAll() ([]models.Product, error) {
 }
 
 // FindByID returns a product by its ID
-func (r *InMemoryProductRepository) FindByID(id string) (*models.Product, error) {
+func (r *InMemoryProductRepository) FindByID(ctx context.Context, id string) (*models.Product, error) {
 	r.mutex.RLock()         // Use read lock for read-only operations
 	defer r.mutex.RUnlock() // Ensure unlock happens even if there's a panic
 
@@ -103,3 +127,69 @@ func (r *InMemoryProductRepository) FindByID(id string) (*models.Product, error)
 	}
 	return nil, nil // Not found
 }
+
+// FindByIDs returns the products with the given IDs by ID
+func (r *InMemoryProductRepository) FindByIDs(ctx context.Context, ids []string) (map[string]models.Product, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	products := make(map[string]models.Product, len(wanted))
+	for _, product := range r.products {
+		if wanted[product.ID] {
+			products[product.ID] = product
+		}
+	}
+	return products, nil
+}
+
+// Create adds a new product
+func (r *InMemoryProductRepository) Create(ctx context.Context, product *models.Product) (*models.Product, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if product.ID == "" {
+		product.ID = uuid.New().String()
+	}
+	for _, existing := range r.products {
+		if existing.ID == product.ID {
+			return nil, ErrProductExists
+		}
+	}
+
+	r.products = append(r.products, *product)
+
+	return product, nil
+}
+
+// Update replaces an existing product
+func (r *InMemoryProductRepository) Update(ctx context.Context, product *models.Product) (*models.Product, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.products {
+		if r.products[i].ID == product.ID {
+			r.products[i] = *product
+			return product, nil
+		}
+	}
+	return nil, nil // Not found
+}
+
+// Delete removes a product
+func (r *InMemoryProductRepository) Delete(ctx context.Context, id string) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.products {
+		if r.products[i].ID == id {
+			r.products = append(r.products[:i], r.products[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,309 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/tracing"
+	"github.com/mattn/go-sqlite3"
+)
+
+// productMigrations versions the schema used by SQLiteProductRepository.
+// The second version loads the built-in dessert catalog when seed is set and
+// does nothing otherwise, so every database numbers its versions the same way
+// whether or not it was seeded.
+func productMigrations(seed bool) []Migration {
+	return []Migration{
+		{
+			Version: 1,
+			Name:    "create_products",
+			Up: execStatements(
+				`CREATE TABLE IF NOT EXISTS products (
+					id TEXT PRIMARY KEY,
+					name TEXT NOT NULL,
+					price INTEGER NOT NULL,
+					currency TEXT NOT NULL,
+					category TEXT NOT NULL,
+					description TEXT NOT NULL DEFAULT '',
+					image_url TEXT NOT NULL DEFAULT '',
+					created_at INTEGER NOT NULL,
+					updated_at INTEGER NOT NULL
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_products_category ON products(category)`,
+			),
+			Down: execStatements(
+				`DROP TABLE IF EXISTS products`,
+			),
+		},
+		{
+			Version: 2,
+			Name:    "seed_default_catalog",
+			Up: func(tx *sql.Tx) error {
+				if !seed {
+					return nil
+				}
+				now := time.Now().UTC().UnixNano()
+				for _, product := range defaultProducts() {
+					_, err := tx.Exec(`INSERT OR IGNORE INTO products (`+productColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+						product.ID, product.Name, product.Price.Amount, product.Price.Currency, product.Category,
+						product.Description, product.ImageURL, now, now)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Down: func(tx *sql.Tx) error {
+				if !seed {
+					return nil
+				}
+				for _, product := range defaultProducts() {
+					if _, err := tx.Exec(`DELETE FROM products WHERE id = ?`, product.ID); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// productColumns lists the products columns in the order scanProduct expects them
+const productColumns = `id, name, price, currency, category, description, image_url, created_at, updated_at`
+
+// SQLiteProductRepository implements ProductRepository using SQLite database
+type SQLiteProductRepository struct {
+	db *sql.DB
+	// queryTimeout bounds every operation, 0 leaves them to the caller's context
+	queryTimeout time.Duration
+}
+
+// SQLiteProductConfig contains configuration options for SQLiteProductRepository
+type SQLiteProductConfig struct {
+	// DatabasePath is the path where the SQLite database will be stored
+	DatabasePath string
+	// QueryTimeout bounds every database operation; 0 means no limit
+	QueryTimeout time.Duration
+	// Seed loads the built-in dessert catalog on first start
+	Seed bool
+}
+
+// NewSQLiteProductRepository creates a new SQLite-based product repository
+func NewSQLiteProductRepository(config SQLiteProductConfig) (*SQLiteProductRepository, error) {
+	if config.DatabasePath == "" {
+		config.DatabasePath = "products.db"
+	}
+
+	db, err := openSQLiteDatabase(config.DatabasePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := NewMigrator(db, "products", productMigrations(config.Seed)).Up(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteProductRepository{db: db, queryTimeout: config.QueryTimeout}, nil
+}
+
+// FindAll returns all products in the order they were added
+func (r *SQLiteProductRepository) FindAll(ctx context.Context) (_ []models.Product, err error) {
+	ctx, span := startSpan(ctx, "SQLiteProductRepository.FindAll", "products")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+productColumns+` FROM products ORDER BY rowid`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+	defer rows.Close()
+
+	products := []models.Product{}
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read product: %w", err)
+		}
+		products = append(products, *product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+
+	return products, nil
+}
+
+// FindByID returns a product by its ID
+func (r *SQLiteProductRepository) FindByID(ctx context.Context, id string) (_ *models.Product, err error) {
+	ctx, span := startSpan(ctx, "SQLiteProductRepository.FindByID", "products")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	row := r.db.QueryRowContext(ctx, `SELECT `+productColumns+` FROM products WHERE id = ?`, id)
+	product, err := scanProduct(row)
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read product %s: %w", id, err)
+	}
+	return product, nil
+}
+
+// FindByIDs returns the products with the given IDs by ID with a single query
+func (r *SQLiteProductRepository) FindByIDs(ctx context.Context, ids []string) (_ map[string]models.Product, err error) {
+	ctx, span := startSpan(ctx, "SQLiteProductRepository.FindByIDs", "products")
+	defer func() { tracing.End(span, err) }()
+
+	products := make(map[string]models.Product, len(ids))
+	if len(ids) == 0 {
+		return products, nil
+	}
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+productColumns+` FROM products
+		WHERE id IN (`+strings.Join(placeholders, ", ")+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read products: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read product: %w", err)
+		}
+		products[product.ID] = *product
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read products: %w", err)
+	}
+
+	return products, nil
+}
+
+// Create adds a new product
+func (r *SQLiteProductRepository) Create(ctx context.Context, product *models.Product) (_ *models.Product, err error) {
+	ctx, span := startSpan(ctx, "SQLiteProductRepository.Create", "products")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	if product.ID == "" {
+		product.ID = uuid.New().String()
+	}
+
+	_, err = r.db.ExecContext(ctx, `INSERT INTO products (`+productColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		product.ID, product.Name, product.Price.Amount, product.Price.Currency, product.Category,
+		product.Description, product.ImageURL, product.CreatedAt.UnixNano(), product.UpdatedAt.UnixNano())
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+			return nil, ErrProductExists
+		}
+		return nil, fmt.Errorf("failed to insert product: %w", err)
+	}
+
+	return product, nil
+}
+
+// Update replaces an existing product
+func (r *SQLiteProductRepository) Update(ctx context.Context, product *models.Product) (_ *models.Product, err error) {
+	ctx, span := startSpan(ctx, "SQLiteProductRepository.Update", "products")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `UPDATE products
+		SET name = ?, price = ?, currency = ?, category = ?, description = ?, image_url = ?, created_at = ?, updated_at = ?
+		WHERE id = ?`,
+		product.Name, product.Price.Amount, product.Price.Currency, product.Category,
+		product.Description, product.ImageURL, product.CreatedAt.UnixNano(), product.UpdatedAt.UnixNano(),
+		product.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update product %s: %w", product.ID, err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to update product %s: %w", product.ID, err)
+	}
+	if updated == 0 {
+		return nil, nil // Not found
+	}
+
+	return product, nil
+}
+
+// Delete removes a product
+func (r *SQLiteProductRepository) Delete(ctx context.Context, id string) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "SQLiteProductRepository.Delete", "products")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM products WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete product %s: %w", id, err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete product %s: %w", id, err)
+	}
+	return deleted > 0, nil
+}
+
+// scanProduct reads a product from a row selected with productColumns
+func scanProduct(row rowScanner) (*models.Product, error) {
+	var product models.Product
+	var createdAt, updatedAt int64
+
+	err := row.Scan(&product.ID, &product.Name, &product.Price.Amount, &product.Price.Currency, &product.Category,
+		&product.Description, &product.ImageURL, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	product.CreatedAt = time.Unix(0, createdAt).UTC()
+	product.UpdatedAt = time.Unix(0, updatedAt).UTC()
+
+	return &product, nil
+}
+
+// Ping checks that the database can be reached
+func (r *SQLiteProductRepository) Ping(ctx context.Context) error {
+	ctx, cancel := withQueryTimeout(ctx, r.queryTimeout)
+	defer cancel()
+	return r.db.PingContext(ctx)
+}
+
+// Close closes the database connection
+func (r *SQLiteProductRepository) Close() error {
+	if r.db != nil {
+		return r.db.Close()
+	}
+	return nil
+}
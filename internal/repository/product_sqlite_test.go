@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteProductSeedMigration(t *testing.T) {
+	tests := []struct {
+		name string
+		// seeds lists the Seed setting of each start on the same database
+		seeds []bool
+		want  int
+	}{
+		{name: "seeded", seeds: []bool{true}, want: len(defaultProducts())},
+		{name: "not seeded", seeds: []bool{false}},
+		{name: "seeded on first start only", seeds: []bool{false, true}},
+		{name: "seed kept when disabled later", seeds: []bool{true, false}, want: len(defaultProducts())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			databasePath := filepath.Join(t.TempDir(), "products.db")
+			for _, seed := range tt.seeds {
+				repo, err := NewSQLiteProductRepository(SQLiteProductConfig{DatabasePath: databasePath, Seed: seed})
+				if err != nil {
+					t.Fatalf("NewSQLiteProductRepository() unexpected error: %v", err)
+				}
+				repo.Close()
+			}
+
+			db, err := openSQLiteDatabase(databasePath)
+			if err != nil {
+				t.Fatalf("failed to open database: %v", err)
+			}
+			defer db.Close()
+			version, err := NewMigrator(db, "products", productMigrations(false)).Version()
+			if err != nil {
+				t.Fatalf("Version() unexpected error: %v", err)
+			}
+			if version != 2 {
+				t.Errorf("Version() = %d, want 2", version)
+			}
+
+			repo := &SQLiteProductRepository{db: db}
+			products, err := repo.FindAll(context.Background())
+			if err != nil {
+				t.Fatalf("FindAll() unexpected error: %v", err)
+			}
+			if len(products) != tt.want {
+				t.Errorf("FindAll() returned %d products, want %d", len(products), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestProductRepositoriesFindByIDs(t *testing.T) {
+	sqliteRepo, err := NewSQLiteProductRepository(SQLiteProductConfig{
+		DatabasePath: filepath.Join(t.TempDir(), "products.db"),
+		Seed:         true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create SQLite product repository: %v", err)
+	}
+	defer sqliteRepo.Close()
+
+	repos := map[string]ProductRepository{
+		"memory": NewInMemoryProductRepository(true),
+		"sqlite": sqliteRepo,
+	}
+
+	tests := []struct {
+		name string
+		ids  []string
+		want []string
+	}{
+		{name: "no IDs", ids: nil, want: []string{}},
+		{name: "several", ids: []string{"3", "1"}, want: []string{"1", "3"}},
+		{name: "repeated", ids: []string{"2", "2"}, want: []string{"2"}},
+		{name: "missing left out", ids: []string{"1", "missing"}, want: []string{"1"}},
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			for _, tt := range tests {
+				products, err := repo.FindByIDs(context.Background(), tt.ids)
+				if err != nil {
+					t.Fatalf("FindByIDs(%v) unexpected error: %v", tt.ids, err)
+				}
+				got := make([]string, 0, len(products))
+				for id, product := range products {
+					if product.ID != id {
+						t.Errorf("FindByIDs(%v) holds product %s under %s", tt.ids, product.ID, id)
+					}
+					got = append(got, id)
+				}
+				sort.Strings(got)
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("%s: FindByIDs(%v) = %v, want %v", tt.name, tt.ids, got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -2,15 +2,22 @@ package repository
 
 import (
 	"bufio"
+	"compress/gzip"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
+	"regexp"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/jilani-go/glofox/internal/metrics"
+	"github.com/jilani-go/glofox/internal/models"
 )
 
 // Constants for optimized file reading
@@ -30,76 +37,217 @@ const (
 
 // Errors for PromoRepository
 var (
-	ErrInvalidFileNumber = errors.New("invalid file number")
-	ErrLoadingPromoFile  = errors.New("error loading promo file")
+	ErrInvalidFileNumber  = errors.New("invalid file number")
+	ErrLoadingPromoFile   = errors.New("error loading promo file")
+	ErrInvalidPromoSource = errors.New("invalid promo source")
+	ErrPromoReadOnly      = errors.New("promo repository is read-only")
+	ErrPromoClosed        = errors.New("promo repository is closed")
 )
 
+// promoSourceNamePattern matches source names; they are used in SQLite table names
+var promoSourceNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// PromoSource is a named file of promo codes, one code per line
+type PromoSource struct {
+	Name string
+	Path string
+}
+
+// PromoSourceStats describes one source read while building a promo store
+type PromoSourceStats struct {
+	Name string
+	// Lines is the number of non-empty lines read
+	Lines int
+	// Codes is the number of distinct codes kept
+	Codes int
+	// Duplicates is the number of lines repeating an earlier code
+	Duplicates int
+	// Skipped is the number of lines that cannot be promo codes because of
+	// their length, see models.ValidPromoCodeLength
+	Skipped int
+}
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// promoSourceReader reads a source file, decompressing it on the fly when it is gzipped
+type promoSourceReader struct {
+	io.Reader
+	file *os.File
+	gzip *gzip.Reader
+}
+
+// openPromoSource opens a source file. Gzipped files are recognised by
+// their content rather than their extension.
+func openPromoSource(path string) (*promoSourceReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	buffered := bufio.NewReaderSize(file, readerBufferSize)
+	source := &promoSourceReader{Reader: buffered, file: file}
+	if magic, err := buffered.Peek(len(gzipMagic)); err == nil && string(magic) == string(gzipMagic) {
+		source.gzip, err = gzip.NewReader(buffered)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		source.Reader = source.gzip
+	}
+	return source, nil
+}
+
+// Close closes the decompressor, if any, and the file
+func (r *promoSourceReader) Close() error {
+	if r.gzip != nil {
+		r.gzip.Close()
+	}
+	return r.file.Close()
+}
+
+// validatePromoSources checks that sources are non-empty, uniquely named and usable as table names
+func validatePromoSources(sources []PromoSource) error {
+	if len(sources) == 0 {
+		return fmt.Errorf("%w: no sources configured", ErrInvalidPromoSource)
+	}
+	seen := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		if !promoSourceNamePattern.MatchString(source.Name) {
+			return fmt.Errorf("%w: name %q", ErrInvalidPromoSource, source.Name)
+		}
+		if seen[source.Name] {
+			return fmt.Errorf("%w: duplicate name %q", ErrInvalidPromoSource, source.Name)
+		}
+		seen[source.Name] = true
+	}
+	return nil
+}
+
+// sourceNames returns the names of sources in order
+func sourceNames(sources []PromoSource) []string {
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = source.Name
+	}
+	return names
+}
+
+// containsSource reports whether sources include one with the given name
+func containsSource(sources []PromoSource, name string) bool {
+	for _, source := range sources {
+		if source.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // PromoRepository defines the interface for promo code operations
 type PromoRepository interface {
-	// ExistsInFile checks if a given promo code exists in a specific file
-	ExistsInFile(code string, fileNumber int) (bool, error)
+	// ExistsInFile checks if a given promo code exists in a specific source.
+	// fileNumber is the 1-based position of the source in Sources.
+	ExistsInFile(ctx context.Context, code string, fileNumber int) (bool, error)
+
+	// Sources returns the names of the promo sources in lookup order
+	Sources() []string
 
 	// Close cleans up any resources used by the repository
 	// Implementation is optional for repositories that don't require cleanup
 	Close() error
 }
 
-// InMemoryPromoRepository implements PromoRepository using in-memory maps
+// PromoSourceLookup is implemented by promo repositories that can find every
+// source holding a code in a single lookup
+type PromoSourceLookup interface {
+	// LookupSources reports for each source, in Sources order, whether it holds code
+	LookupSources(ctx context.Context, code string) ([]bool, error)
+}
+
+// InMemoryPromoRepository implements ReloadablePromoRepository using in-memory maps
 type InMemoryPromoRepository struct {
+	sources  []PromoSource
+	progress *PromoLoadProgress
+	// codes is the current code set, replaced as a whole on reload
+	codes atomic.Pointer[inMemoryPromoCodes]
+	// reloading guards against concurrent reloads
+	reloading sync.Mutex
+}
+
+// inMemoryPromoCodes is an immutable set of loaded promo codes
+type inMemoryPromoCodes struct {
 	// Each key represents a file number, each value is a map of promo codes in that file
 	filePromoCodes map[int]map[string]struct{}
+	// sources are the source names, indexed by file number - 1
+	sources []string
 }
 
 // Global mutex for synchronizing access to shared data
 var mu sync.Mutex
 
 // NewInMemoryPromoRepository creates a new in-memory promo repository
-func NewInMemoryPromoRepository() (PromoRepository, error) {
-	startTime := time.Now()
-	log.Println("Starting parallel loading of promo code files...")
+// holding the codes of the given sources, numbered from 1 in order.
+// Loading the sources is reported to progress, which may be nil.
+func NewInMemoryPromoRepository(sources []PromoSource, progress *PromoLoadProgress) (ReloadablePromoRepository, error) {
+	if err := validatePromoSources(sources); err != nil {
+		return nil, err
+	}
 
-	// Define promo file paths
-	promoFiles := []string{
-		"internal/repository/promofiles/couponbase1",
-		"internal/repository/promofiles/couponbase2",
-		"internal/repository/promofiles/couponbase3",
+	codes, err := loadInMemoryPromoCodes(sources, progress)
+	if err != nil {
+		return nil, err
 	}
 
+	repo := &InMemoryPromoRepository{sources: sources, progress: progress}
+	repo.codes.Store(codes)
+	return repo, nil
+}
+
+// loadInMemoryPromoCodes reads the codes of every source in parallel
+func loadInMemoryPromoCodes(sources []PromoSource, progress *PromoLoadProgress) (*inMemoryPromoCodes, error) {
+	startTime := time.Now()
+	slog.Info("Loading promo sources", "count", len(sources))
+
 	// Create a channel for results
 	type result struct {
 		fileNumber int
 		promoCodes map[string]struct{}
 		err        error
 	}
-	resultChan := make(chan result, len(promoFiles))
+	resultChan := make(chan result, len(sources))
 
 	// Process files in parallel
 	var wg sync.WaitGroup
-	for i, filePath := range promoFiles {
+	for i, source := range sources {
 		fileNumber := i + 1
 		wg.Add(1)
 
 		// Launch a goroutine to process each file
-		go func(fn int, path string) {
+		go func(fn int, name, path string) {
 			defer wg.Done()
 
 			fileStartTime := time.Now()
-			log.Printf("Starting to load file %d: %s", fn, path)
+			slog.Debug("Loading promo source", "source", name, "path", path)
+			progress.start(name)
 
 			// Process the file
-			promoCodes, err := readPromoCodesOptimized(path)
+			var lines int
+			promoCodes, err := readPromoCodesOptimized(path, func(n int) {
+				lines = n
+				progress.read(name, n)
+			})
+			progress.finish(name, lines, len(promoCodes), err)
 
 			// Send result back through channel
 			if err != nil {
-				log.Printf("Error loading file %d: %v", fn, err)
-				resultChan <- result{fn, nil, fmt.Errorf("failed to load file %d: %w", fn, err)}
+				resultChan <- result{fn, nil, fmt.Errorf("failed to load source %s: %w", name, err)}
 			} else {
 				elapsed := time.Since(fileStartTime)
-				log.Printf("Completed loading file %d (%s) with %d codes in %v",
-					fn, path, len(promoCodes), elapsed)
+				slog.Info("Loaded promo source", "source", name, "path", path, "codes", len(promoCodes), "duration", elapsed)
+				metrics.ObservePromoSourceLoad(name, len(promoCodes), elapsed)
 				resultChan <- result{fn, promoCodes, nil}
 			}
-		}(fileNumber, filePath)
+		}(fileNumber, source.Name, source.Path)
 	}
 
 	// Close result channel when all files are processed
@@ -118,26 +266,31 @@ func NewInMemoryPromoRepository() (PromoRepository, error) {
 	}
 
 	totalElapsed := time.Since(startTime)
-	log.Printf("Successfully loaded all promo files in %v", totalElapsed)
+	slog.Info("Loaded promo sources", "count", len(sources), "duration", totalElapsed)
 
-	return &InMemoryPromoRepository{
+	return &inMemoryPromoCodes{
 		filePromoCodes: filePromoCodes,
+		sources:        sourceNames(sources),
 	}, nil
 }
 
 // readPromoCodesFromFile provides backward compatibility
 func readPromoCodesFromFile(filePath string) (map[string]struct{}, error) {
-	return readPromoCodesOptimized(filePath)
+	return readPromoCodesOptimized(filePath, nil)
 }
 
-// readPromoCodesOptimized reads promo codes from a file using concurrent workers
-func readPromoCodesOptimized(filePath string) (map[string]struct{}, error) {
+// readPromoCodesOptimized reads promo codes from a file using concurrent workers.
+// Like the other promo stores, it skips lines that cannot be promo codes
+// because of their length, see models.ValidPromoCodeLength.
+// onRead, if not nil, is called with the number of lines read every million
+// lines and once the whole file is read.
+func readPromoCodesOptimized(filePath string, onRead func(lines int)) (map[string]struct{}, error) {
 	startTime := time.Now()
 
 	// --- Step 1: Open the file ---
-	file, err := os.Open(filePath)
+	file, err := openPromoSource(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
+		return nil, err
 	}
 	defer file.Close()
 
@@ -178,8 +331,15 @@ func readPromoCodesOptimized(filePath string) (map[string]struct{}, error) {
 
 		for {
 			line, err := reader.ReadString('\n')
+			if err == io.EOF && line != "" {
+				// The last line has no newline, read it before stopping
+				err = nil
+			}
 			if err != nil {
 				if err == io.EOF {
+					if onRead != nil {
+						onRead(lineCount)
+					}
 					break // End of file
 				}
 				// Report error and exit
@@ -191,9 +351,9 @@ func readPromoCodesOptimized(filePath string) (map[string]struct{}, error) {
 				return
 			}
 
-			// Remove newline and trim whitespace
+			// Remove newline and trim whitespace, dropping what cannot be a code
 			trimmed := strings.TrimSpace(line)
-			if trimmed != "" {
+			if models.ValidPromoCodeLength(trimmed) {
 				// Send the line to workers
 				linesChan <- trimmed
 			}
@@ -201,9 +361,12 @@ func readPromoCodesOptimized(filePath string) (map[string]struct{}, error) {
 			// Track progress
 			lineCount++
 			if lineCount%1000000 == 0 { // Every million lines
+				if onRead != nil {
+					onRead(lineCount)
+				}
 				now := time.Now()
 				if now.Sub(lastReport) >= reportInterval {
-					log.Printf("File %s: Read %d million lines...", filePath, lineCount/1000000)
+					slog.Debug("Reading promo file", "path", filePath, "lines", lineCount)
 					lastReport = now
 				}
 			}
@@ -251,14 +414,55 @@ func readPromoCodesOptimized(filePath string) (map[string]struct{}, error) {
 	}
 
 	elapsed := time.Since(startTime)
-	log.Printf("Completed reading %s with %d unique codes in %v",
-		filePath, len(globalMap), elapsed)
+	slog.Debug("Read promo file", "path", filePath, "codes", len(globalMap), "duration", elapsed)
 
 	return globalMap, nil
 }
 
 // ExistsInFile checks if a given promo code exists in a specific file
-func (r *InMemoryPromoRepository) ExistsInFile(code string, fileNumber int) (bool, error) {
+func (r *InMemoryPromoRepository) ExistsInFile(ctx context.Context, code string, fileNumber int) (bool, error) {
+	return r.codes.Load().ExistsInFile(ctx, code, fileNumber)
+}
+
+// Sources returns the names of the promo sources in lookup order
+func (r *InMemoryPromoRepository) Sources() []string {
+	return r.codes.Load().Sources()
+}
+
+// Reload reads every source into a new code set and swaps it in.
+// Lookups that already hold the previous set keep using it.
+func (r *InMemoryPromoRepository) Reload() (*PromoReloadStats, error) {
+	if !r.reloading.TryLock() {
+		return nil, ErrReloadInProgress
+	}
+	defer r.reloading.Unlock()
+
+	startTime := time.Now()
+	codes, err := loadInMemoryPromoCodes(r.sources, r.progress)
+	if err != nil {
+		return nil, err
+	}
+	r.codes.Store(codes)
+
+	stats := &PromoReloadStats{
+		Codes:    make(map[string]int, len(codes.sources)),
+		Duration: time.Since(startTime),
+	}
+	for i, name := range codes.sources {
+		stats.Codes[name] = len(codes.filePromoCodes[i+1])
+	}
+	return stats, nil
+}
+
+// Acquire returns the current code set; it is immutable, so release is a no-op
+func (r *InMemoryPromoRepository) Acquire() (PromoRepository, func()) {
+	return r.codes.Load(), func() {}
+}
+
+// ExistsInFile checks if a given promo code exists in a specific file
+func (r *inMemoryPromoCodes) ExistsInFile(ctx context.Context, code string, fileNumber int) (bool, error) {
+	defer metrics.ObservePromoLookup(metrics.BackendMemory, time.Now())
+
 	// Validate fileNumber
 	if fileNumber < 1 || fileNumber > len(r.filePromoCodes) {
 		return false, ErrInvalidFileNumber
@@ -275,8 +479,18 @@ func (r *InMemoryPromoRepository) ExistsInFile(code string, fileNumber int) (boo
 	return codeExists, nil
 }
 
+// Sources returns the names of the promo sources in lookup order
+func (r *inMemoryPromoCodes) Sources() []string {
+	return append([]string(nil), r.sources...)
+}
+
 // Close implements the Close method for PromoRepository interface
 // For in-memory repository, this is a no-op
 func (r *InMemoryPromoRepository) Close() error {
 	return nil
 }
+
+// Close is a no-op; a code set is released by the garbage collector
+func (r *inMemoryPromoCodes) Close() error {
+	return nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Errors for AsyncPromoRepository
+var (
+	ErrPromoLoading = errors.New("promo codes are still loading")
+	ErrPromoFailed  = errors.New("promo codes failed to load")
+)
+
+// AsyncPromoRepository is a ReloadablePromoRepository opened in the
+// background, so the server can start listening while promo sources load.
+// Lookups and reloads fail with ErrPromoLoading until it is open, and with
+// ErrPromoFailed for good if opening it failed.
+type AsyncPromoRepository struct {
+	sources []string
+	// done is closed once opening finished; repo and err are set before
+	done chan struct{}
+	repo ReloadablePromoRepository
+	err  error
+	// mu orders Close with the end of opening
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewAsyncPromoRepository starts opening a promo repository with open and
+// returns at once
+func NewAsyncPromoRepository(sources []PromoSource, open func() (ReloadablePromoRepository, error)) *AsyncPromoRepository {
+	r := &AsyncPromoRepository{sources: sourceNames(sources), done: make(chan struct{})}
+	go func() {
+		repo, err := open()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if err == nil && r.closed {
+			// Closed while opening, nobody will use it
+			repo.Close()
+		}
+		r.repo, r.err = repo, err
+		close(r.done)
+	}()
+	return r
+}
+
+// Done is closed once opening the repository finished, see Ready for the outcome
+func (r *AsyncPromoRepository) Done() <-chan struct{} {
+	return r.done
+}
+
+// Ready returns nil once the repository is open, ErrPromoLoading before,
+// or ErrPromoFailed with the reason opening it failed
+func (r *AsyncPromoRepository) Ready() error {
+	_, err := r.opened()
+	return err
+}
+
+// opened returns the repository, or the error of Ready if it is not open
+func (r *AsyncPromoRepository) opened() (ReloadablePromoRepository, error) {
+	select {
+	case <-r.done:
+		if r.err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrPromoFailed, r.err)
+		}
+		return r.repo, nil
+	default:
+		return nil, ErrPromoLoading
+	}
+}
+
+// ExistsInFile checks if a given promo code exists in a specific source
+func (r *AsyncPromoRepository) ExistsInFile(ctx context.Context, code string, fileNumber int) (bool, error) {
+	repo, err := r.opened()
+	if err != nil {
+		return false, err
+	}
+	return repo.ExistsInFile(ctx, code, fileNumber)
+}
+
+// Sources returns the names of the promo sources in lookup order
+func (r *AsyncPromoRepository) Sources() []string {
+	return r.sources
+}
+
+// Reload reloads the sources of the open repository
+func (r *AsyncPromoRepository) Reload() (*PromoReloadStats, error) {
+	repo, err := r.opened()
+	if err != nil {
+		return nil, err
+	}
+	return repo.Reload()
+}
+
+// Acquire pins the codes of the open repository. Before it is open, the
+// view is the AsyncPromoRepository itself and its lookups fail.
+func (r *AsyncPromoRepository) Acquire() (PromoRepository, func()) {
+	repo, err := r.opened()
+	if err != nil {
+		return r, func() {}
+	}
+	return repo.Acquire()
+}
+
+// Ping checks that the repository is open and, for a database, reachable
+func (r *AsyncPromoRepository) Ping(ctx context.Context) error {
+	if err := r.Ready(); err != nil {
+		return err
+	}
+	if pinger, ok := r.repo.(interface{ Ping(context.Context) error }); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// Close closes the repository, or has it closed once opening finishes
+func (r *AsyncPromoRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	if repo, err := r.opened(); err == nil {
+		return repo.Close()
+	}
+	return nil
+}
@@ -0,0 +1,1214 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/metrics"
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+// Promo index file format (all integers little endian):
+//
+//	magic        [8]byte "PROMOIDX"
+//	version      uint32
+//	sourceCount  uint32
+//	sources      sourceCount x (nameLen uint16, name, pathLen uint16, path,
+//	             size int64, modTime int64 in Unix nanoseconds)
+//	recordCount  uint64
+//	keyWidth     uint32
+//	bloomBits    uint64, 0 when there is no Bloom filter
+//	bloomHashes  uint32
+//	bloom        ceil(bloomBits / 8) bytes
+//	records      recordCount x (code padded with zero bytes to keyWidth, sources uint64)
+//	checksum     uint32, CRC-32 (IEEE) of everything before it
+//
+// Records are sorted by code, so a lookup is a binary search over the
+// memory-mapped file. Bit i of a record's sources mask is set when the code
+// appears in source i. The path, size and modification time of each source
+// file when the index was built tell whether the index is still current.
+const (
+	promoIndexMagic   = "PROMOIDX"
+	promoIndexVersion = 2
+
+	// MaxPromoIndexSources is the number of sources that fit in a record's mask
+	MaxPromoIndexSources = 64
+	// MaxPromoCodeLength is the longest key an index file may hold. Builds only
+	// store codes of a valid length, see models.ValidPromoCodeLength.
+	MaxPromoCodeLength = 64
+	// DefaultBloomBitsPerKey gives a Bloom filter false positive rate of about 1%
+	DefaultBloomBitsPerKey = 10
+	// DefaultPromoSortBufferSize is how many bytes of codes each source sorts in memory
+	DefaultPromoSortBufferSize = 64 * 1024 * 1024
+
+	// promoIndexMaskSize is the size of a record's sources mask
+	promoIndexMaskSize = 8
+)
+
+// Errors for promo index files
+var (
+	ErrInvalidPromoIndex = errors.New("invalid promo index file")
+)
+
+// PromoIndexOptions controls how a promo index is built
+type PromoIndexOptions struct {
+	// BloomBitsPerKey sizes the Bloom filter that answers most misses
+	// without a binary search; 0 builds no filter
+	BloomBitsPerKey int
+	// SortBufferSize bounds the bytes of codes each source sorts in memory,
+	// larger sources are sorted in runs on disk next to the index; 0 uses
+	// DefaultPromoSortBufferSize
+	SortBufferSize int
+	// Progress, if not nil, is told how reading each source goes
+	Progress *PromoLoadProgress
+}
+
+// promoIndexSource identifies the file a source of an index was built from
+type promoIndexSource struct {
+	name    string
+	path    string
+	size    int64
+	modTime int64
+}
+
+// statPromoSource describes a source file as it is now
+func statPromoSource(source PromoSource) (promoIndexSource, error) {
+	info, err := os.Stat(source.Path)
+	if err != nil {
+		return promoIndexSource{}, err
+	}
+	return promoIndexSource{
+		name:    source.Name,
+		path:    filepath.Clean(source.Path),
+		size:    info.Size(),
+		modTime: info.ModTime().UnixNano(),
+	}, nil
+}
+
+// PromoIndexStats describes a built promo index
+type PromoIndexStats struct {
+	Sources []PromoSourceStats
+	// Records is the number of distinct codes across all sources
+	Records int
+	// Size is the size of the index file in bytes
+	Size     int64
+	Duration time.Duration
+}
+
+// codeList is a sorted, de-duplicated list of codes stored in one byte slice.
+// Code i is data[offsets[i]:offsets[i+1]].
+type codeList struct {
+	data    []byte
+	offsets []uint32
+}
+
+// len returns the number of codes
+func (l *codeList) len() int {
+	return len(l.offsets) - 1
+}
+
+// code returns code i
+func (l *codeList) code(i int) []byte {
+	return l.data[l.offsets[i]:l.offsets[i+1]]
+}
+
+// sortedCodes holds the sorted, de-duplicated codes of a source, in memory
+// or, for sources larger than the sort buffer, in a file of one code per line
+type sortedCodes struct {
+	list *codeList
+	path string
+}
+
+// open returns a cursor at the first code
+func (s *sortedCodes) open() (codeCursor, error) {
+	if s.list != nil {
+		return &listCursor{list: s.list}, nil
+	}
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	cursor := &fileCursor{file: file, reader: bufio.NewReaderSize(file, readerBufferSize)}
+	if err := cursor.next(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return cursor, nil
+}
+
+// codeCursor walks sorted codes
+type codeCursor interface {
+	// code returns the current code, nil past the last one. It is only
+	// valid until next is called.
+	code() []byte
+	next() error
+	close() error
+}
+
+// listCursor walks a codeList
+type listCursor struct {
+	list     *codeList
+	position int
+}
+
+func (c *listCursor) code() []byte {
+	if c.position >= c.list.len() {
+		return nil
+	}
+	return c.list.code(c.position)
+}
+
+func (c *listCursor) next() error {
+	c.position++
+	return nil
+}
+
+func (c *listCursor) close() error {
+	return nil
+}
+
+// fileCursor walks a file of sorted codes written by spillCodes
+type fileCursor struct {
+	file    *os.File
+	reader  *bufio.Reader
+	current []byte
+}
+
+func (c *fileCursor) code() []byte {
+	return c.current
+}
+
+func (c *fileCursor) next() error {
+	line, err := c.reader.ReadSlice('\n')
+	switch {
+	case err == io.EOF && len(line) == 0:
+		c.current = nil
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to read sorted codes: %w", err)
+	}
+	c.current = line[:len(line)-1]
+	return nil
+}
+
+func (c *fileCursor) close() error {
+	return c.file.Close()
+}
+
+// readSortedCodes reads the codes of a source file, sorted and de-duplicated.
+// At most bufferSize bytes of codes are sorted in memory at a time; a larger
+// source is sorted in runs spilled to files in dir, which are then merged.
+func readSortedCodes(path string, stats *PromoSourceStats, progress *PromoLoadProgress, bufferSize int, dir string) (*sortedCodes, error) {
+	file, err := openPromoSource(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var runs []*sortedCodes
+	defer func() {
+		for _, run := range runs {
+			if run.path != "" {
+				os.Remove(run.path)
+			}
+		}
+	}()
+
+	var data []byte
+	var starts []uint32
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		stats.Lines++
+		if stats.Lines%1_000_000 == 0 {
+			progress.read(stats.Name, stats.Lines)
+			slog.Debug("Reading promo source", "source", stats.Name, "lines", stats.Lines)
+		}
+		if !models.ValidPromoCodeLength(string(line)) {
+			stats.Skipped++
+			continue
+		}
+		if len(data)+len(line) > bufferSize && len(starts) > 0 {
+			run, err := spillCodes(dir, sortCodeList(data, starts, stats))
+			if err != nil {
+				return nil, err
+			}
+			runs = append(runs, run)
+			data, starts = data[:0], starts[:0]
+		}
+		starts = append(starts, uint32(len(data)))
+		data = append(data, line...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	list := sortCodeList(data, starts, stats)
+	if len(runs) == 0 {
+		stats.Codes = list.len()
+		return &sortedCodes{list: list}, nil
+	}
+
+	// Merge the spilled runs and the last one into a single sorted file
+	slog.Debug("Merging promo source runs", "source", stats.Name, "runs", len(runs)+1)
+	runs = append(runs, &sortedCodes{list: list})
+	output, err := os.CreateTemp(dir, "source*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sorted codes file: %w", err)
+	}
+	defer output.Close()
+	writer := bufio.NewWriterSize(output, readerBufferSize)
+	err = mergeSortedCodes(runs, func(code []byte, holders []int) error {
+		stats.Codes++
+		stats.Duplicates += len(holders) - 1
+		writer.Write(code)
+		return writer.WriteByte('\n')
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(output.Name())
+		return nil, fmt.Errorf("failed to write sorted codes: %w", err)
+	}
+	return &sortedCodes{path: output.Name()}, nil
+}
+
+// sortCodeList sorts the codes packed in data, starting at starts, into a
+// compact, de-duplicated list, counting the duplicates in stats
+func sortCodeList(data []byte, starts []uint32, stats *PromoSourceStats) *codeList {
+	codeAt := func(i int) []byte {
+		end := uint32(len(data))
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		return data[starts[i]:end]
+	}
+	order := make([]int, len(starts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return bytes.Compare(codeAt(order[a]), codeAt(order[b])) < 0
+	})
+
+	list := &codeList{
+		data:    make([]byte, 0, len(data)),
+		offsets: make([]uint32, 1, len(starts)+1),
+	}
+	var previous []byte
+	for n, i := range order {
+		code := codeAt(i)
+		if n > 0 && bytes.Equal(code, previous) {
+			stats.Duplicates++
+			continue
+		}
+		list.data = append(list.data, code...)
+		list.offsets = append(list.offsets, uint32(len(list.data)))
+		previous = code
+	}
+	return list
+}
+
+// spillCodes writes a sorted list to a new file in dir, one code per line
+func spillCodes(dir string, list *codeList) (*sortedCodes, error) {
+	file, err := os.CreateTemp(dir, "run*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sort run: %w", err)
+	}
+	writer := bufio.NewWriterSize(file, readerBufferSize)
+	for i := 0; i < list.len(); i++ {
+		writer.Write(list.code(i))
+		writer.WriteByte('\n')
+	}
+	err = writer.Flush()
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to write sort run: %w", err)
+	}
+	return &sortedCodes{path: file.Name()}, nil
+}
+
+// cursorHeap orders cursors by their current code
+type cursorHeap []heapCursor
+
+// heapCursor is a cursor with the position of its codes in the merge
+type heapCursor struct {
+	codeCursor
+	index int
+}
+
+func (h cursorHeap) Len() int           { return len(h) }
+func (h cursorHeap) Less(i, j int) bool { return bytes.Compare(h[i].code(), h[j].code()) < 0 }
+func (h cursorHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *cursorHeap) Push(x any)        { *h = append(*h, x.(heapCursor)) }
+func (h *cursorHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// mergeSortedCodes calls fn for every distinct code of the sorted codes in
+// order, with the positions of the ones holding it. Neither code nor holders
+// may be kept after fn returns.
+func mergeSortedCodes(all []*sortedCodes, fn func(code []byte, holders []int) error) (err error) {
+	cursors := make(cursorHeap, 0, len(all))
+	defer func() {
+		for _, cursor := range cursors {
+			cursor.close()
+		}
+	}()
+	for i, codes := range all {
+		cursor, err := codes.open()
+		if err != nil {
+			return err
+		}
+		if cursor.code() == nil {
+			cursor.close()
+			continue
+		}
+		cursors = append(cursors, heapCursor{codeCursor: cursor, index: i})
+	}
+	heap.Init(&cursors)
+
+	var taken []heapCursor
+	var holders []int
+	for len(cursors) > 0 {
+		// Take the smallest code from every cursor that has it
+		code := cursors[0].code()
+		taken, holders = taken[:0], holders[:0]
+		for len(cursors) > 0 && bytes.Equal(cursors[0].code(), code) {
+			cursor := heap.Pop(&cursors).(heapCursor)
+			taken = append(taken, cursor)
+			holders = append(holders, cursor.index)
+		}
+		sort.Ints(holders)
+		err := fn(code, holders)
+
+		// Move the cursors on before handling the error, so they are closed
+		for _, cursor := range taken {
+			if nextErr := cursor.next(); nextErr != nil && err == nil {
+				err = nextErr
+			}
+			if cursor.code() == nil {
+				cursor.close()
+			} else {
+				heap.Push(&cursors, cursor)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergedRecord is a code with the mask of the sources containing it
+type mergedRecord struct {
+	code []byte
+	mask uint64
+}
+
+// mergeCodeLists calls fn for every distinct code of the sources in sorted order
+func mergeCodeLists(lists []*sortedCodes, fn func(mergedRecord) error) error {
+	return mergeSortedCodes(lists, func(code []byte, holders []int) error {
+		record := mergedRecord{code: code}
+		for _, i := range holders {
+			record.mask |= 1 << uint(i)
+		}
+		return fn(record)
+	})
+}
+
+// BuildPromoIndex reads every source and writes a promo index file to path.
+// The file is written next to path and renamed into place once complete.
+// Memory use is bounded by options.SortBufferSize per source, not by the
+// size of the sources.
+func BuildPromoIndex(sources []PromoSource, path string, options PromoIndexOptions) (*PromoIndexStats, error) {
+	if err := validatePromoSources(sources); err != nil {
+		return nil, err
+	}
+	if len(sources) > MaxPromoIndexSources {
+		return nil, fmt.Errorf("%w: an index holds at most %d sources", ErrInvalidPromoSource, MaxPromoIndexSources)
+	}
+	bufferSize := options.SortBufferSize
+	if bufferSize <= 0 {
+		bufferSize = DefaultPromoSortBufferSize
+	}
+	// Offsets into the buffer are 32 bits
+	bufferSize = min(bufferSize, math.MaxUint32)
+
+	startTime := time.Now()
+	slog.Info("Building promo index", "path", path, "sources", len(sources))
+
+	// Record the files before reading them, so a change made while the
+	// build runs makes the index outdated
+	files := make([]promoIndexSource, len(sources))
+	for i, source := range sources {
+		file, err := statPromoSource(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read source %s: %w", source.Name, err)
+		}
+		files[i] = file
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create index directory: %w", err)
+	}
+	sortDir, err := os.MkdirTemp(filepath.Dir(path), filepath.Base(path)+".sort*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sort directory: %w", err)
+	}
+	defer os.RemoveAll(sortDir)
+
+	// Read the sources in parallel
+	stats := &PromoIndexStats{Sources: make([]PromoSourceStats, len(sources))}
+	lists := make([]*sortedCodes, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		stats.Sources[i].Name = source.Name
+		wg.Add(1)
+		go func(i int, source PromoSource) {
+			defer wg.Done()
+			sourceStart := time.Now()
+			options.Progress.start(source.Name)
+			lists[i], errs[i] = readSortedCodes(source.Path, &stats.Sources[i], options.Progress, bufferSize, sortDir)
+			options.Progress.finish(source.Name, stats.Sources[i].Lines, stats.Sources[i].Codes, errs[i])
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("failed to read source %s: %w", source.Name, errs[i])
+				return
+			}
+			metrics.ObservePromoSourceLoad(source.Name, stats.Sources[i].Codes, time.Since(sourceStart))
+		}(i, source)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	// Count the distinct codes and the widest one to lay out the file
+	keyWidth := 1
+	err = mergeCodeLists(lists, func(record mergedRecord) error {
+		stats.Records++
+		if len(record.code) > keyWidth {
+			keyWidth = len(record.code)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var bloom *bloomFilter
+	if options.BloomBitsPerKey > 0 && stats.Records > 0 {
+		bloom = newBloomFilter(stats.Records, options.BloomBitsPerKey)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create index file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	checksum := crc32.NewIEEE()
+	writer := bufio.NewWriterSize(io.MultiWriter(tmp, checksum), readerBufferSize)
+
+	// The Bloom filter precedes the records, so fill it in a first pass
+	if bloom != nil {
+		err := mergeCodeLists(lists, func(record mergedRecord) error {
+			bloom.add(record.code)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writePromoIndexHeader(writer, files, uint64(stats.Records), uint32(keyWidth), bloom); err != nil {
+		return nil, fmt.Errorf("failed to write index: %w", err)
+	}
+	record := make([]byte, keyWidth+promoIndexMaskSize)
+	err = mergeCodeLists(lists, func(r mergedRecord) error {
+		clear(record)
+		copy(record, r.code)
+		binary.LittleEndian.PutUint64(record[keyWidth:], r.mask)
+		_, err := writer.Write(record)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write index: %w", err)
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write index: %w", err)
+	}
+	if err := binary.Write(tmp, binary.LittleEndian, checksum.Sum32()); err != nil {
+		return nil, fmt.Errorf("failed to write index: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync index: %w", err)
+	}
+	if info, err := tmp.Stat(); err == nil {
+		stats.Size = info.Size()
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to move index into place: %w", err)
+	}
+
+	stats.Duration = time.Since(startTime)
+	slog.Info("Built promo index", "path", path, "codes", stats.Records, "bytes", stats.Size, "duration", stats.Duration)
+	return stats, nil
+}
+
+// writePromoIndexHeader writes everything before the records
+func writePromoIndexHeader(w io.Writer, sources []promoIndexSource, recordCount uint64, keyWidth uint32, bloom *bloomFilter) error {
+	var header bytes.Buffer
+	header.WriteString(promoIndexMagic)
+	binary.Write(&header, binary.LittleEndian, uint32(promoIndexVersion))
+	binary.Write(&header, binary.LittleEndian, uint32(len(sources)))
+	for _, source := range sources {
+		if len(source.path) > math.MaxUint16 {
+			return fmt.Errorf("source path %s is too long", source.path)
+		}
+		binary.Write(&header, binary.LittleEndian, uint16(len(source.name)))
+		header.WriteString(source.name)
+		binary.Write(&header, binary.LittleEndian, uint16(len(source.path)))
+		header.WriteString(source.path)
+		binary.Write(&header, binary.LittleEndian, source.size)
+		binary.Write(&header, binary.LittleEndian, source.modTime)
+	}
+	binary.Write(&header, binary.LittleEndian, recordCount)
+	binary.Write(&header, binary.LittleEndian, keyWidth)
+	if bloom == nil {
+		binary.Write(&header, binary.LittleEndian, uint64(0))
+		binary.Write(&header, binary.LittleEndian, uint32(0))
+	} else {
+		binary.Write(&header, binary.LittleEndian, bloom.bitCount)
+		binary.Write(&header, binary.LittleEndian, bloom.hashCount)
+		header.Write(bloom.bits)
+	}
+
+	_, err := w.Write(header.Bytes())
+	return err
+}
+
+// promoIndex is an opened promo index file
+type promoIndex struct {
+	// data is the whole file, memory-mapped where supported
+	data    []byte
+	sources []string
+	// files are the source files the index was built from, in source order
+	files    []promoIndexSource
+	keyWidth int
+	count    int
+	bloom    *bloomFilter
+	// records is the record section of data
+	records []byte
+}
+
+// openPromoIndex maps an index file and checks its layout.
+// The checksum is not verified so that opening does not read every page.
+func openPromoIndex(path string) (*promoIndex, error) {
+	data, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	index, err := parsePromoIndex(data)
+	if err != nil {
+		unmapFile(data)
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return index, nil
+}
+
+// parsePromoIndex reads the header of an index file held in data
+func parsePromoIndex(data []byte) (*promoIndex, error) {
+	reader := bytes.NewReader(data)
+	read := func(value interface{}) error {
+		if err := binary.Read(reader, binary.LittleEndian, value); err != nil {
+			return fmt.Errorf("%w: truncated header", ErrInvalidPromoIndex)
+		}
+		return nil
+	}
+
+	magic := make([]byte, len(promoIndexMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != promoIndexMagic {
+		return nil, fmt.Errorf("%w: bad magic", ErrInvalidPromoIndex)
+	}
+	var version, sourceCount uint32
+	if err := read(&version); err != nil {
+		return nil, err
+	}
+	if version != promoIndexVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidPromoIndex, version)
+	}
+	if err := read(&sourceCount); err != nil {
+		return nil, err
+	}
+	if sourceCount > MaxPromoIndexSources {
+		return nil, fmt.Errorf("%w: %d sources", ErrInvalidPromoIndex, sourceCount)
+	}
+
+	index := &promoIndex{data: data}
+	readString := func() (string, error) {
+		var length uint16
+		if err := read(&length); err != nil {
+			return "", err
+		}
+		value := make([]byte, length)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return "", fmt.Errorf("%w: truncated header", ErrInvalidPromoIndex)
+		}
+		return string(value), nil
+	}
+	for i := uint32(0); i < sourceCount; i++ {
+		var file promoIndexSource
+		var err error
+		if file.name, err = readString(); err != nil {
+			return nil, err
+		}
+		if file.path, err = readString(); err != nil {
+			return nil, err
+		}
+		if err := read(&file.size); err != nil {
+			return nil, err
+		}
+		if err := read(&file.modTime); err != nil {
+			return nil, err
+		}
+		index.sources = append(index.sources, file.name)
+		index.files = append(index.files, file)
+	}
+
+	var recordCount, bloomBits uint64
+	var keyWidth, bloomHashes uint32
+	for _, value := range []interface{}{&recordCount, &keyWidth, &bloomBits, &bloomHashes} {
+		if err := read(value); err != nil {
+			return nil, err
+		}
+	}
+	if keyWidth == 0 || keyWidth > MaxPromoCodeLength {
+		return nil, fmt.Errorf("%w: key width %d", ErrInvalidPromoIndex, keyWidth)
+	}
+	offset := len(data) - reader.Len()
+
+	if bloomBits > 0 {
+		// Check the bit count before converting it, a corrupt one could overflow
+		if bloomBits > uint64(len(data)-offset)*8 {
+			return nil, fmt.Errorf("%w: truncated Bloom filter", ErrInvalidPromoIndex)
+		}
+		if bloomHashes == 0 {
+			return nil, fmt.Errorf("%w: Bloom filter without hash functions", ErrInvalidPromoIndex)
+		}
+		bloomSize := int((bloomBits + 7) / 8)
+		index.bloom = &bloomFilter{
+			bits:      data[offset : offset+bloomSize],
+			bitCount:  bloomBits,
+			hashCount: bloomHashes,
+		}
+		offset += bloomSize
+	}
+
+	recordSize := int(keyWidth) + promoIndexMaskSize
+	if recordCount > uint64(len(data)) || offset+int(recordCount)*recordSize+4 != len(data) {
+		return nil, fmt.Errorf("%w: size does not match %d records", ErrInvalidPromoIndex, recordCount)
+	}
+	index.keyWidth = int(keyWidth)
+	index.count = int(recordCount)
+	index.records = data[offset : offset+index.count*recordSize]
+
+	return index, nil
+}
+
+// key returns the zero padded code of record i
+func (x *promoIndex) key(i int) []byte {
+	start := i * (x.keyWidth + promoIndexMaskSize)
+	return x.records[start : start+x.keyWidth]
+}
+
+// lookup returns the sources mask of a code, or 0 if no source has it
+func (x *promoIndex) lookup(code string) uint64 {
+	defer metrics.ObservePromoLookup(metrics.BackendIndex, time.Now())
+	if len(code) == 0 || len(code) > x.keyWidth {
+		return 0
+	}
+	if x.bloom != nil && !x.bloom.mayContain(code) {
+		return 0
+	}
+
+	// Pad the code like the stored keys so they compare byte for byte
+	padded := make([]byte, x.keyWidth)
+	copy(padded, code)
+	i := sort.Search(x.count, func(i int) bool {
+		return bytes.Compare(x.key(i), padded) >= 0
+	})
+	if i == x.count || !bytes.Equal(x.key(i), padded) {
+		return 0
+	}
+	start := i*(x.keyWidth+promoIndexMaskSize) + x.keyWidth
+	return binary.LittleEndian.Uint64(x.records[start:])
+}
+
+// close unmaps the index file
+func (x *promoIndex) close() error {
+	return unmapFile(x.data)
+}
+
+// sameSources reports whether the index was built from the files of the
+// given sources, in the same order and under the same names
+func (x *promoIndex) sameSources(sources []PromoSource) bool {
+	if len(x.files) != len(sources) {
+		return false
+	}
+	for i, source := range sources {
+		if x.files[i].name != source.Name || x.files[i].path != filepath.Clean(source.Path) {
+			return false
+		}
+	}
+	return true
+}
+
+// current reports whether the source files are unchanged since the index was
+// built, judging by their size and modification time. The caller checks
+// sameSources first.
+func (x *promoIndex) current(sources []PromoSource) bool {
+	for i, source := range sources {
+		file, err := statPromoSource(source)
+		if err != nil || file != x.files[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sourceCounts returns the number of codes in each source
+func (x *promoIndex) sourceCounts() map[string]int {
+	counts := make(map[string]int, len(x.sources))
+	recordSize := x.keyWidth + promoIndexMaskSize
+	for i := 0; i < x.count; i++ {
+		mask := binary.LittleEndian.Uint64(x.records[i*recordSize+x.keyWidth:])
+		for bit, name := range x.sources {
+			if mask&(1<<uint(bit)) != 0 {
+				counts[name]++
+			}
+		}
+	}
+	return counts
+}
+
+// PromoIndexInfo describes a verified promo index file
+type PromoIndexInfo struct {
+	// Sources are the source names in lookup order
+	Sources []string
+	// Codes is the number of codes per source name
+	Codes map[string]int
+	// Records is the number of distinct codes across all sources
+	Records int
+	// Bloom reports whether the index has a Bloom filter
+	Bloom bool
+	Size  int64
+}
+
+// VerifyPromoIndex reads a whole index file and checks its checksum, that
+// its codes are sorted and unique and that every code belongs to a source
+func VerifyPromoIndex(path string) (*PromoIndexInfo, error) {
+	index, err := openPromoIndex(path)
+	if err != nil {
+		return nil, err
+	}
+	defer index.close()
+
+	body := index.data[:len(index.data)-4]
+	stored := binary.LittleEndian.Uint32(index.data[len(body):])
+	if crc32.ChecksumIEEE(body) != stored {
+		return nil, fmt.Errorf("%s: %w: checksum mismatch", path, ErrInvalidPromoIndex)
+	}
+
+	validMask := uint64(1)<<uint(len(index.sources)) - 1
+	if len(index.sources) == MaxPromoIndexSources {
+		validMask = math.MaxUint64
+	}
+	recordSize := index.keyWidth + promoIndexMaskSize
+	for i := 0; i < index.count; i++ {
+		if i > 0 && bytes.Compare(index.key(i-1), index.key(i)) >= 0 {
+			return nil, fmt.Errorf("%s: %w: record %d is out of order", path, ErrInvalidPromoIndex, i)
+		}
+		mask := binary.LittleEndian.Uint64(index.records[i*recordSize+index.keyWidth:])
+		if mask == 0 || mask&^validMask != 0 {
+			return nil, fmt.Errorf("%s: %w: record %d has sources mask %#x", path, ErrInvalidPromoIndex, i, mask)
+		}
+		if index.bloom != nil && !index.bloom.mayContain(string(bytes.TrimRight(index.key(i), "\x00"))) {
+			return nil, fmt.Errorf("%s: %w: record %d is missing from the Bloom filter", path, ErrInvalidPromoIndex, i)
+		}
+	}
+
+	return &PromoIndexInfo{
+		Sources: append([]string(nil), index.sources...),
+		Codes:   index.sourceCounts(),
+		Records: index.count,
+		Bloom:   index.bloom != nil,
+		Size:    int64(len(index.data)),
+	}, nil
+}
+
+// bloomFilter is a Bloom filter over promo codes using double hashing
+type bloomFilter struct {
+	bits      []byte
+	bitCount  uint64
+	hashCount uint32
+}
+
+// newBloomFilter sizes a filter for n keys
+func newBloomFilter(n, bitsPerKey int) *bloomFilter {
+	bitCount := uint64(n) * uint64(bitsPerKey)
+	if bitCount < 64 {
+		bitCount = 64
+	}
+	// k = bits per key * ln 2 minimises false positives
+	hashCount := uint32(math.Round(float64(bitsPerKey) * math.Ln2))
+	if hashCount < 1 {
+		hashCount = 1
+	}
+	if hashCount > 16 {
+		hashCount = 16
+	}
+	return &bloomFilter{
+		bits:      make([]byte, (bitCount+7)/8),
+		bitCount:  bitCount,
+		hashCount: hashCount,
+	}
+}
+
+// bloomHash is 64-bit FNV-1a, split into the two hashes of double hashing
+func bloomHash[T string | []byte](key T) (uint64, uint64) {
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= 1099511628211
+	}
+	return hash, hash>>32 | 1
+}
+
+// add inserts a key
+func (f *bloomFilter) add(key []byte) {
+	h1, h2 := bloomHash(key)
+	for i := uint64(0); i < uint64(f.hashCount); i++ {
+		bit := (h1 + i*h2) % f.bitCount
+		f.bits[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// mayContain reports false only if the key was never added
+func (f *bloomFilter) mayContain(key string) bool {
+	h1, h2 := bloomHash(key)
+	for i := uint64(0); i < uint64(f.hashCount); i++ {
+		bit := (h1 + i*h2) % f.bitCount
+		if f.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// IndexedPromoRepository implements ReloadablePromoRepository with a sorted,
+// memory-mapped index file. Memory use is bounded by the page cache rather
+// than the number of codes, and an existing index opens instantly.
+type IndexedPromoRepository struct {
+	indexPath string
+	sources   []PromoSource
+	options   PromoIndexOptions
+	readOnly  bool
+	// swapMu is held for reading by lookups and for writing while the index is swapped
+	swapMu sync.RWMutex
+	index  *promoIndex
+	// reloading guards against concurrent reloads
+	reloading sync.Mutex
+}
+
+// IndexedPromoConfig contains configuration options for IndexedPromoRepository
+type IndexedPromoConfig struct {
+	// IndexPath is the index file; it is built from Sources if missing or
+	// if it was built from different sources
+	IndexPath string
+	// Sources are the promo code files, numbered from 1 in order
+	Sources []PromoSource
+	// BloomBitsPerKey sizes the Bloom filter of a newly built index
+	BloomBitsPerKey int
+	// ReadOnly opens an index built offline (see cmd/promoctl) and never
+	// builds one; a reload maps the file at IndexPath again
+	ReadOnly bool
+	// Progress, if not nil, is told how reading each source goes
+	Progress *PromoLoadProgress
+}
+
+// NewIndexedPromoRepository opens the promo index, building it first if needed
+func NewIndexedPromoRepository(config IndexedPromoConfig) (ReloadablePromoRepository, error) {
+	if err := validatePromoSources(config.Sources); err != nil {
+		return nil, err
+	}
+	if config.IndexPath == "" {
+		config.IndexPath = "promo_codes.idx"
+	}
+
+	repo := &IndexedPromoRepository{
+		indexPath: config.IndexPath,
+		sources:   config.Sources,
+		options:   PromoIndexOptions{BloomBitsPerKey: config.BloomBitsPerKey, Progress: config.Progress},
+		readOnly:  config.ReadOnly,
+	}
+
+	index, err := openPromoIndex(config.IndexPath)
+	if config.ReadOnly {
+		if index, err = checkPromoIndex(index, err, config.Sources); err != nil {
+			return nil, err
+		}
+		slog.Info("Opened read-only promo index", "path", config.IndexPath, "codes", index.count)
+		repo.reuseSources()
+		repo.index = index
+		return repo, nil
+	}
+	switch {
+	case err == nil && index.sameSources(config.Sources) && index.current(config.Sources):
+		slog.Info("Opened promo index", "path", config.IndexPath, "codes", index.count)
+		repo.reuseSources()
+		repo.index = index
+		return repo, nil
+	case err == nil && index.sameSources(config.Sources):
+		slog.Info("Promo sources changed since the index was built, rebuilding", "path", config.IndexPath)
+		index.close()
+	case err == nil:
+		slog.Info("Promo index was built from other sources, rebuilding", "path", config.IndexPath, "sources", strings.Join(index.sources, ","))
+		index.close()
+	case errors.Is(err, os.ErrNotExist):
+		slog.Info("Promo index not found, building it", "path", config.IndexPath)
+	default:
+		slog.Warn("Promo index is unusable, rebuilding", "path", config.IndexPath, "error", err)
+	}
+
+	if _, err := BuildPromoIndex(config.Sources, config.IndexPath, repo.options); err != nil {
+		return nil, err
+	}
+	if repo.index, err = openPromoIndex(config.IndexPath); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// checkPromoIndex takes the result of openPromoIndex and fails unless the
+// index was built from the files of the given sources. An index built
+// offline is served even if the files changed since, with a warning.
+func checkPromoIndex(index *promoIndex, err error, sources []PromoSource) (*promoIndex, error) {
+	if err != nil {
+		return nil, fmt.Errorf("failed to open promo index: %w", err)
+	}
+	if !index.sameSources(sources) {
+		built := make([]string, len(index.files))
+		for i, file := range index.files {
+			built[i] = file.name + "=" + file.path
+		}
+		expected := make([]string, len(sources))
+		for i, source := range sources {
+			expected[i] = source.Name + "=" + filepath.Clean(source.Path)
+		}
+		index.close()
+		return nil, fmt.Errorf("%w: index was built from sources %s, expected %s", ErrInvalidPromoIndex,
+			strings.Join(built, ", "), strings.Join(expected, ", "))
+	}
+	if !index.current(sources) {
+		slog.Warn("Promo source files differ from the ones the index was built from, rebuild it with promoctl")
+	}
+	return index, nil
+}
+
+// ExistsInFile checks if a given promo code exists in a specific source
+func (r *IndexedPromoRepository) ExistsInFile(ctx context.Context, code string, fileNumber int) (bool, error) {
+	r.swapMu.RLock()
+	defer r.swapMu.RUnlock()
+	return indexExistsInFile(r.index, code, fileNumber)
+}
+
+// LookupSources reports which sources hold a code with a single search
+func (r *IndexedPromoRepository) LookupSources(ctx context.Context, code string) ([]bool, error) {
+	r.swapMu.RLock()
+	defer r.swapMu.RUnlock()
+	return indexLookupSources(r.index, code)
+}
+
+// reuseSources reports every source as loaded from an existing index
+func (r *IndexedPromoRepository) reuseSources() {
+	for _, source := range r.sources {
+		r.options.Progress.reused(source.Name)
+	}
+}
+
+// indexLookupSources expands the sources mask of a code. A nil index is
+// the index of a closed repository.
+func indexLookupSources(index *promoIndex, code string) ([]bool, error) {
+	if index == nil {
+		return nil, ErrPromoClosed
+	}
+	mask := index.lookup(code)
+	found := make([]bool, len(index.sources))
+	for i := range found {
+		found[i] = mask&(1<<uint(i)) != 0
+	}
+	return found, nil
+}
+
+// indexExistsInFile looks a code up in one source of an index. A nil index
+// is the index of a closed repository.
+func indexExistsInFile(index *promoIndex, code string, fileNumber int) (bool, error) {
+	if index == nil {
+		return false, ErrPromoClosed
+	}
+	if fileNumber < 1 || fileNumber > len(index.sources) {
+		return false, ErrInvalidFileNumber
+	}
+	return index.lookup(code)&(1<<uint(fileNumber-1)) != 0, nil
+}
+
+// Sources returns the names of the promo sources in lookup order
+func (r *IndexedPromoRepository) Sources() []string {
+	return sourceNames(r.sources)
+}
+
+// Reload builds a new index from the sources next to the current one and swaps it in
+func (r *IndexedPromoRepository) Reload() (*PromoReloadStats, error) {
+	if !r.reloading.TryLock() {
+		return nil, ErrReloadInProgress
+	}
+	defer r.reloading.Unlock()
+
+	r.swapMu.RLock()
+	closed := r.index == nil
+	r.swapMu.RUnlock()
+	if closed {
+		return nil, ErrPromoClosed
+	}
+
+	if r.readOnly {
+		return r.reopen()
+	}
+
+	// Build under a new name; the current file stays mapped until the swap
+	nextPath := r.indexPath + ".next"
+	stats, err := BuildPromoIndex(r.sources, nextPath, r.options)
+	if err != nil {
+		return nil, err
+	}
+	index, err := openPromoIndex(nextPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r.swapMu.Lock()
+	previous := r.index
+	r.index = index
+	// Renaming keeps the mapping valid, so the new index stays usable
+	if err := os.Rename(nextPath, r.indexPath); err != nil {
+		slog.Error("Failed to move promo index into place", "path", nextPath, "error", err)
+	}
+	previous.close()
+	r.swapMu.Unlock()
+
+	result := &PromoReloadStats{Codes: make(map[string]int, len(stats.Sources)), Duration: stats.Duration}
+	for _, source := range stats.Sources {
+		result.Codes[source.Name] = source.Codes
+	}
+	return result, nil
+}
+
+// reopen maps the index file again, picking up an index rebuilt offline
+func (r *IndexedPromoRepository) reopen() (*PromoReloadStats, error) {
+	startTime := time.Now()
+	index, err := openPromoIndex(r.indexPath)
+	if index, err = checkPromoIndex(index, err, r.sources); err != nil {
+		return nil, err
+	}
+
+	r.swapMu.Lock()
+	previous := r.index
+	r.index = index
+	previous.close()
+	r.swapMu.Unlock()
+
+	slog.Info("Reopened promo index", "path", r.indexPath, "codes", index.count)
+	return &PromoReloadStats{Codes: index.sourceCounts(), Duration: time.Since(startTime)}, nil
+}
+
+// Acquire holds off index swaps until release is called
+func (r *IndexedPromoRepository) Acquire() (PromoRepository, func()) {
+	r.swapMu.RLock()
+	return indexPromoView{r.index}, r.swapMu.RUnlock
+}
+
+// Close unmaps the index file
+func (r *IndexedPromoRepository) Close() error {
+	r.swapMu.Lock()
+	defer r.swapMu.Unlock()
+	if r.index == nil {
+		return nil
+	}
+	err := r.index.close()
+	r.index = nil
+	return err
+}
+
+// indexPromoView looks codes up in one index while its creator holds swapMu for reading
+type indexPromoView struct {
+	index *promoIndex
+}
+
+// ExistsInFile checks if a given promo code exists in a specific source
+func (v indexPromoView) ExistsInFile(ctx context.Context, code string, fileNumber int) (bool, error) {
+	return indexExistsInFile(v.index, code, fileNumber)
+}
+
+// LookupSources reports which sources hold a code with a single search
+func (v indexPromoView) LookupSources(ctx context.Context, code string) ([]bool, error) {
+	return indexLookupSources(v.index, code)
+}
+
+// Sources returns the names of the promo sources in lookup order, none
+// once the repository is closed
+func (v indexPromoView) Sources() []string {
+	if v.index == nil {
+		return nil
+	}
+	return append([]string(nil), v.index.sources...)
+}
+
+// Close is a no-op, the view does not own the index
+func (v indexPromoView) Close() error {
+	return nil
+}
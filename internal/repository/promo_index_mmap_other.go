@@ -0,0 +1,25 @@
+//go:build !unix
+
+package repository
+
+import (
+	"fmt"
+	"os"
+)
+
+// mapFile reads a file into memory where memory mapping is unavailable
+func mapFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty file", ErrInvalidPromoIndex)
+	}
+	return data, nil
+}
+
+// unmapFile releases a file read by mapFile
+func unmapFile(data []byte) error {
+	return nil
+}
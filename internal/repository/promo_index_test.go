@@ -0,0 +1,365 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBuildPromoIndexSortsOnDisk(t *testing.T) {
+	sources := writePromoFixture(t)
+	dir := t.TempDir()
+
+	// A buffer of a few codes makes every source spill several runs
+	inMemory := filepath.Join(dir, "memory.idx")
+	onDisk := filepath.Join(dir, "disk.idx")
+	memoryStats, err := BuildPromoIndex(sources, inMemory, PromoIndexOptions{BloomBitsPerKey: DefaultBloomBitsPerKey})
+	if err != nil {
+		t.Fatalf("BuildPromoIndex() unexpected error: %v", err)
+	}
+	diskStats, err := BuildPromoIndex(sources, onDisk, PromoIndexOptions{BloomBitsPerKey: DefaultBloomBitsPerKey, SortBufferSize: 20})
+	if err != nil {
+		t.Fatalf("BuildPromoIndex() with a small sort buffer unexpected error: %v", err)
+	}
+
+	if !slices.Equal(diskStats.Sources, memoryStats.Sources) || diskStats.Records != memoryStats.Records {
+		t.Errorf("stats = %+v, want %+v", diskStats, memoryStats)
+	}
+	want := []PromoSourceStats{
+		{Name: "alpha", Lines: 9, Codes: 4, Duplicates: 1, Skipped: 4},
+		{Name: "beta", Lines: 4, Codes: 4},
+	}
+	if !slices.Equal(diskStats.Sources, want) {
+		t.Errorf("source stats = %+v, want %+v", diskStats.Sources, want)
+	}
+
+	memoryFile, err := os.ReadFile(inMemory)
+	if err != nil {
+		t.Fatalf("failed to read index: %v", err)
+	}
+	diskFile, err := os.ReadFile(onDisk)
+	if err != nil {
+		t.Fatalf("failed to read index: %v", err)
+	}
+	if !bytes.Equal(diskFile, memoryFile) {
+		t.Error("index sorted on disk differs from the index sorted in memory")
+	}
+
+	// Only the two indexes are left behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list index directory: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("index directory holds %d entries, want the 2 indexes", len(entries))
+	}
+}
+
+func TestOpenPromoIndexRejectsInvalidFiles(t *testing.T) {
+	sources := writePromoFixture(t)
+	path := filepath.Join(t.TempDir(), "promo.idx")
+	if _, err := BuildPromoIndex(sources, path, PromoIndexOptions{BloomBitsPerKey: DefaultBloomBitsPerKey}); err != nil {
+		t.Fatalf("BuildPromoIndex() unexpected error: %v", err)
+	}
+	valid, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read index: %v", err)
+	}
+	index, err := parsePromoIndex(valid)
+	if err != nil {
+		t.Fatalf("parsePromoIndex() unexpected error: %v", err)
+	}
+	recordsStart := len(valid) - 4 - len(index.records)
+
+	tests := []struct {
+		name    string
+		corrupt func(data []byte) []byte
+		// wantVerifyOnly means the file opens and only VerifyPromoIndex notices
+		wantVerifyOnly bool
+	}{
+		{name: "bad magic", corrupt: func(data []byte) []byte { data[0] = 'X'; return data }},
+		{name: "version 1", corrupt: func(data []byte) []byte {
+			binary.LittleEndian.PutUint32(data[len(promoIndexMagic):], 1)
+			return data
+		}},
+		{name: "truncated header", corrupt: func(data []byte) []byte { return data[:len(promoIndexMagic)+6] }},
+		{name: "truncated records", corrupt: func(data []byte) []byte { return data[:len(data)-5] }},
+		{name: "trailing bytes", corrupt: func(data []byte) []byte { return append(data, 0) }},
+		{name: "flipped record byte", corrupt: func(data []byte) []byte { data[recordsStart] ^= 1; return data }, wantVerifyOnly: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corruptPath := filepath.Join(t.TempDir(), "promo.idx")
+			if err := os.WriteFile(corruptPath, tt.corrupt(slices.Clone(valid)), 0644); err != nil {
+				t.Fatalf("failed to write index: %v", err)
+			}
+
+			index, err := openPromoIndex(corruptPath)
+			if tt.wantVerifyOnly {
+				if err != nil {
+					t.Fatalf("openPromoIndex() unexpected error: %v", err)
+				}
+				index.close()
+			} else if !errors.Is(err, ErrInvalidPromoIndex) {
+				t.Fatalf("openPromoIndex() error = %v, want %v", err, ErrInvalidPromoIndex)
+			}
+			if _, err := VerifyPromoIndex(corruptPath); !errors.Is(err, ErrInvalidPromoIndex) {
+				t.Errorf("VerifyPromoIndex() error = %v, want %v", err, ErrInvalidPromoIndex)
+			}
+		})
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		info, err := VerifyPromoIndex(path)
+		if err != nil {
+			t.Fatalf("VerifyPromoIndex() unexpected error: %v", err)
+		}
+		if !slices.Equal(info.Sources, []string{"alpha", "beta"}) || !info.Bloom || info.Records != 6 {
+			t.Errorf("VerifyPromoIndex() = %+v, want sources alpha and beta, a Bloom filter and 6 records", info)
+		}
+		if info.Codes["alpha"] != 4 || info.Codes["beta"] != 4 {
+			t.Errorf("VerifyPromoIndex() codes = %v, want alpha 4 and beta 4", info.Codes)
+		}
+	})
+}
+
+func TestBloomFilter(t *testing.T) {
+	const keys = 10_000
+	filter := newBloomFilter(keys, DefaultBloomBitsPerKey)
+	for i := 0; i < keys; i++ {
+		filter.add([]byte(fmt.Sprintf("CODE%06d", i)))
+	}
+
+	for i := 0; i < keys; i++ {
+		if key := fmt.Sprintf("CODE%06d", i); !filter.mayContain(key) {
+			t.Fatalf("mayContain(%q) = false for an added key", key)
+		}
+	}
+
+	// 10 bits per key should give about 1% false positives
+	falsePositives := 0
+	for i := 0; i < keys; i++ {
+		if filter.mayContain(fmt.Sprintf("MISS%06d", i)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / keys; rate > 0.03 {
+		t.Errorf("false positive rate = %.3f, want about 0.01", rate)
+	}
+}
+
+func TestIndexedPromoRepositoryOpen(t *testing.T) {
+	tests := []struct {
+		name string
+		// change alters the sources after the first start
+		change      func(t *testing.T, sources []PromoSource) []PromoSource
+		wantRebuilt bool
+		// wantNewCode means the first source holds NEWCODE01 after the restart
+		wantNewCode bool
+	}{
+		{
+			name:   "unchanged sources reused",
+			change: func(t *testing.T, sources []PromoSource) []PromoSource { return sources },
+		},
+		{
+			name: "changed file rebuilt",
+			change: func(t *testing.T, sources []PromoSource) []PromoSource {
+				writeTestSource(t, sources[0].Path, "NEWCODE01\n")
+				return sources
+			},
+			wantRebuilt: true,
+			wantNewCode: true,
+		},
+		{
+			name: "moved file rebuilt",
+			change: func(t *testing.T, sources []PromoSource) []PromoSource {
+				moved := filepath.Join(filepath.Dir(sources[0].Path), "moved.txt")
+				writeTestSource(t, moved, "NEWCODE01\n")
+				return []PromoSource{{Name: sources[0].Name, Path: moved}, sources[1]}
+			},
+			wantRebuilt: true,
+			wantNewCode: true,
+		},
+		{
+			name: "renamed source rebuilt",
+			change: func(t *testing.T, sources []PromoSource) []PromoSource {
+				return []PromoSource{{Name: "gamma", Path: sources[0].Path}, sources[1]}
+			},
+			wantRebuilt: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := writePromoFixture(t)
+			indexPath := filepath.Join(t.TempDir(), "promo.idx")
+			repo, err := NewIndexedPromoRepository(IndexedPromoConfig{IndexPath: indexPath, Sources: sources})
+			if err != nil {
+				t.Fatalf("NewIndexedPromoRepository() unexpected error: %v", err)
+			}
+			repo.Close()
+			built, err := os.Stat(indexPath)
+			if err != nil {
+				t.Fatalf("failed to stat index: %v", err)
+			}
+
+			sources = tt.change(t, sources)
+			repo, err = NewIndexedPromoRepository(IndexedPromoConfig{IndexPath: indexPath, Sources: sources})
+			if err != nil {
+				t.Fatalf("NewIndexedPromoRepository() on restart unexpected error: %v", err)
+			}
+			defer repo.Close()
+
+			opened, err := os.Stat(indexPath)
+			if err != nil {
+				t.Fatalf("failed to stat index: %v", err)
+			}
+			if rebuilt := !os.SameFile(built, opened); rebuilt != tt.wantRebuilt {
+				t.Errorf("index rebuilt = %v, want %v", rebuilt, tt.wantRebuilt)
+			}
+			assertPromoCode(t, repo, "NEWCODE01", tt.wantNewCode)
+		})
+	}
+}
+
+func TestIndexedPromoRepositoryReadOnly(t *testing.T) {
+	sources := writePromoFixture(t)
+	indexPath := filepath.Join(t.TempDir(), "promo.idx")
+	if _, err := BuildPromoIndex(sources, indexPath, PromoIndexOptions{}); err != nil {
+		t.Fatalf("BuildPromoIndex() unexpected error: %v", err)
+	}
+
+	t.Run("built from other files", func(t *testing.T) {
+		moved := []PromoSource{sources[0], {Name: "beta", Path: sources[1].Path + ".moved"}}
+		_, err := NewIndexedPromoRepository(IndexedPromoConfig{IndexPath: indexPath, Sources: moved, ReadOnly: true})
+		if !errors.Is(err, ErrInvalidPromoIndex) {
+			t.Fatalf("NewIndexedPromoRepository() error = %v, want %v", err, ErrInvalidPromoIndex)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		missing := filepath.Join(t.TempDir(), "missing.idx")
+		_, err := NewIndexedPromoRepository(IndexedPromoConfig{IndexPath: missing, Sources: sources, ReadOnly: true})
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("NewIndexedPromoRepository() error = %v, want %v", err, os.ErrNotExist)
+		}
+		if _, err := os.Stat(missing); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("read-only repository built the index")
+		}
+	})
+
+	t.Run("changed file served until rebuilt", func(t *testing.T) {
+		repo, err := NewIndexedPromoRepository(IndexedPromoConfig{IndexPath: indexPath, Sources: sources, ReadOnly: true})
+		if err != nil {
+			t.Fatalf("NewIndexedPromoRepository() unexpected error: %v", err)
+		}
+		defer repo.Close()
+		writeTestSource(t, sources[0].Path, "NEWCODE01\n")
+		assertPromoCode(t, repo, "NEWCODE01", false)
+	})
+}
+
+func TestIndexedPromoRepositoryReload(t *testing.T) {
+	tests := []struct {
+		name     string
+		readOnly bool
+	}{
+		{name: "rebuilds from the sources"},
+		{name: "reopens a read-only index", readOnly: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := writePromoFixture(t)
+			indexPath := filepath.Join(t.TempDir(), "promo.idx")
+			if _, err := BuildPromoIndex(sources, indexPath, PromoIndexOptions{}); err != nil {
+				t.Fatalf("BuildPromoIndex() unexpected error: %v", err)
+			}
+			repo, err := NewIndexedPromoRepository(IndexedPromoConfig{IndexPath: indexPath, Sources: sources, ReadOnly: tt.readOnly})
+			if err != nil {
+				t.Fatalf("NewIndexedPromoRepository() unexpected error: %v", err)
+			}
+			defer repo.Close()
+
+			writeTestSource(t, sources[0].Path, "NEWCODE01\n")
+			if tt.readOnly {
+				// Offline, promoctl rebuilds the index in place
+				if _, err := BuildPromoIndex(sources, indexPath, PromoIndexOptions{}); err != nil {
+					t.Fatalf("BuildPromoIndex() unexpected error: %v", err)
+				}
+			}
+
+			stats, err := repo.Reload()
+			if err != nil {
+				t.Fatalf("Reload() unexpected error: %v", err)
+			}
+			if stats.Codes["alpha"] != 1 || stats.Codes["beta"] != 4 {
+				t.Errorf("Reload() codes = %v, want alpha 1 and beta 4", stats.Codes)
+			}
+			assertPromoCode(t, repo, "NEWCODE01", true)
+			assertPromoCode(t, repo, "HAPPYHRS", false)
+
+			repo.Close()
+			if _, err := repo.Reload(); !errors.Is(err, ErrPromoClosed) {
+				t.Errorf("Reload() after Close() error = %v, want %v", err, ErrPromoClosed)
+			}
+		})
+	}
+
+	t.Run("read-only index from other sources kept", func(t *testing.T) {
+		sources := writePromoFixture(t)
+		indexPath := filepath.Join(t.TempDir(), "promo.idx")
+		if _, err := BuildPromoIndex(sources, indexPath, PromoIndexOptions{}); err != nil {
+			t.Fatalf("BuildPromoIndex() unexpected error: %v", err)
+		}
+		repo, err := NewIndexedPromoRepository(IndexedPromoConfig{IndexPath: indexPath, Sources: sources, ReadOnly: true})
+		if err != nil {
+			t.Fatalf("NewIndexedPromoRepository() unexpected error: %v", err)
+		}
+		defer repo.Close()
+
+		if _, err := BuildPromoIndex(sources[:1], indexPath, PromoIndexOptions{}); err != nil {
+			t.Fatalf("BuildPromoIndex() unexpected error: %v", err)
+		}
+		if _, err := repo.Reload(); !errors.Is(err, ErrInvalidPromoIndex) {
+			t.Fatalf("Reload() error = %v, want %v", err, ErrInvalidPromoIndex)
+		}
+		assertPromoCode(t, repo, "BETAONLY1", false)
+		if exists, err := repo.ExistsInFile(context.Background(), "BETAONLY1", 2); err != nil || !exists {
+			t.Errorf("ExistsInFile(BETAONLY1, 2) = %v, %v, want the previous index kept", exists, err)
+		}
+	})
+}
+
+// writeTestSource replaces a promo source file, moving its modification time
+// on so that the change shows even on coarse file system clocks
+func writeTestSource(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write promo source: %v", err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatalf("failed to touch promo source: %v", err)
+	}
+}
+
+// assertPromoCode checks whether the first source of repo holds code
+func assertPromoCode(t *testing.T, repo PromoRepository, code string, want bool) {
+	t.Helper()
+	exists, err := repo.ExistsInFile(context.Background(), code, 1)
+	if err != nil {
+		t.Fatalf("ExistsInFile(%q) unexpected error: %v", code, err)
+	}
+	if exists != want {
+		t.Errorf("ExistsInFile(%q) = %v, want %v", code, exists, want)
+	}
+}
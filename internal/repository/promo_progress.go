@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"sync"
+	"time"
+)
+
+// Promo source load states
+const (
+	PromoSourcePending = "pending"
+	PromoSourceLoading = "loading"
+	PromoSourceLoaded  = "loaded"
+	PromoSourceFailed  = "failed"
+)
+
+// PromoSourceProgress is how far loading a promo source got
+type PromoSourceProgress struct {
+	Name  string
+	State string
+	// Lines is the number of lines read so far
+	Lines int
+	// Codes is the number of distinct codes read, once loaded. It is 0 for a
+	// source loaded by an earlier start and reused as is.
+	Codes      int
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// Error is why loading failed
+	Error string
+}
+
+// PromoLoadProgress tracks the loading of promo sources, on startup and on
+// reload, for status reports. A nil *PromoLoadProgress tracks nothing.
+type PromoLoadProgress struct {
+	mu      sync.Mutex
+	sources []PromoSourceProgress
+}
+
+// NewPromoLoadProgress creates a tracker with every source pending
+func NewPromoLoadProgress(sources []PromoSource) *PromoLoadProgress {
+	p := &PromoLoadProgress{sources: make([]PromoSourceProgress, len(sources))}
+	for i, source := range sources {
+		p.sources[i] = PromoSourceProgress{Name: source.Name, State: PromoSourcePending}
+	}
+	return p
+}
+
+// Sources returns the progress of every source in lookup order
+func (p *PromoLoadProgress) Sources() []PromoSourceProgress {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PromoSourceProgress(nil), p.sources...)
+}
+
+// update applies fn to the progress of a source
+func (p *PromoLoadProgress) update(name string, fn func(*PromoSourceProgress)) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i := range p.sources {
+		if p.sources[i].Name == name {
+			fn(&p.sources[i])
+			return
+		}
+	}
+}
+
+// start records that loading a source began
+func (p *PromoLoadProgress) start(name string) {
+	p.update(name, func(s *PromoSourceProgress) {
+		*s = PromoSourceProgress{Name: name, State: PromoSourceLoading, StartedAt: time.Now()}
+	})
+}
+
+// read records the number of lines of a source read so far
+func (p *PromoLoadProgress) read(name string, lines int) {
+	p.update(name, func(s *PromoSourceProgress) {
+		s.Lines = lines
+	})
+}
+
+// finish records the outcome of loading a source
+func (p *PromoLoadProgress) finish(name string, lines, codes int, err error) {
+	p.update(name, func(s *PromoSourceProgress) {
+		s.FinishedAt = time.Now()
+		s.Lines = lines
+		s.Codes = codes
+		if err != nil {
+			s.State = PromoSourceFailed
+			s.Error = err.Error()
+			return
+		}
+		s.State = PromoSourceLoaded
+	})
+}
+
+// reused records that a source loaded by an earlier start is served as is
+func (p *PromoLoadProgress) reused(name string) {
+	p.update(name, func(s *PromoSourceProgress) {
+		*s = PromoSourceProgress{Name: name, State: PromoSourceLoaded}
+	})
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"errors"
+	"time"
+)
+
+// Errors for promo reloads
+var (
+	ErrReloadInProgress = errors.New("promo reload already in progress")
+)
+
+// PromoReloadStats describes a completed promo reload
+type PromoReloadStats struct {
+	// Codes is the number of codes loaded per source name
+	Codes map[string]int
+	// Duration is how long building the new code set took
+	Duration time.Duration
+}
+
+// ReloadablePromoRepository is a PromoRepository whose codes can be rebuilt
+// from its sources while it keeps serving lookups
+type ReloadablePromoRepository interface {
+	PromoRepository
+
+	// Reload reads every source again and atomically swaps the new codes in.
+	// Lookups keep using the previous codes until the swap. Only one reload
+	// runs at a time, a concurrent call returns ErrReloadInProgress.
+	Reload() (*PromoReloadStats, error)
+
+	// Acquire pins the current codes so that several lookups see the same
+	// version, even if a reload completes in between. release must be called
+	// once the lookups are done.
+	Acquire() (view PromoRepository, release func())
+}
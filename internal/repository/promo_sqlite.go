@@ -2,27 +2,41 @@ package repository
 
 import (
 	"bufio"
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
-	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/jilani-go/glofox/internal/metrics"
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/tracing"
 )
 
-// SQLitePromoRepository implements PromoRepository using SQLite database
+// SQLitePromoRepository implements ReloadablePromoRepository using SQLite database.
+// Every code is stored once in promo_codes with a bitmask of the sources
+// holding it, so finding all sources of a code is one primary key lookup.
+// Sources are loaded into staging tables and then merged into promo_codes
+// in a single transaction, so lookups never see a partial load.
 type SQLitePromoRepository struct {
-	db            *sql.DB
-	databasePath  string
-	fileCount     int
-	batchSize     int
-	workerCount   int
-	createIndexes bool
+	db           *sql.DB
+	databasePath string
+	sources      []PromoSource
+	// bits are the mask bits of the sources, indexed by file number - 1
+	bits        []uint
+	batchSize   int
+	workerCount int
+	readOnly    bool
+	progress    *PromoLoadProgress
+	// swapMu is held for reading by lookups and for writing while tables are swapped
+	swapMu sync.RWMutex
+	// reloading guards against concurrent reloads
+	reloading sync.Mutex
 }
 
 // SQLitePromoConfig contains configuration options for SQLitePromoRepository
@@ -33,14 +47,69 @@ type SQLitePromoConfig struct {
 	BatchSize int
 	// WorkerCount controls the number of parallel workers for loading data
 	WorkerCount int
-	// CreateIndexes determines whether to create indexes after loading data
+	// CreateIndexes is no longer used: codes are always looked up through
+	// the primary key of promo_codes
 	CreateIndexes bool
+	// Sources are the promo code files, numbered from 1 in order
+	Sources []PromoSource
+	// ReadOnly serves a database built offline (see cmd/promoctl): it is
+	// opened read-only, its schema is checked but not migrated, sources are
+	// never loaded and startup fails if one has not been loaded yet
+	ReadOnly bool
+	// Progress, if not nil, is told how loading each source goes
+	Progress *PromoLoadProgress
 }
 
 // NewSQLitePromoRepository creates a new SQLite-based promo repository
-func NewSQLitePromoRepository(config SQLitePromoConfig) (PromoRepository, error) {
+func NewSQLitePromoRepository(config SQLitePromoConfig) (ReloadablePromoRepository, error) {
 	startTime := time.Now()
 
+	repo, err := openSQLitePromoRepository(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the sources that are new or whose file moved since the last start
+	pending, err := repo.pendingSources()
+	if err != nil {
+		repo.Close()
+		return nil, fmt.Errorf("failed to check if tables are populated: %w", err)
+	}
+
+	// A read-only database must have been built with every source
+	if len(pending) > 0 && config.ReadOnly {
+		repo.Close()
+		return nil, fmt.Errorf("%w: source(s) %s not loaded into %s", ErrPromoReadOnly,
+			strings.Join(sourceNames(pending), ", "), repo.databasePath)
+	}
+
+	for _, source := range config.Sources {
+		if !containsSource(pending, source.Name) {
+			repo.progress.reused(source.Name)
+		}
+	}
+
+	// Load data for those sources only
+	if len(pending) > 0 {
+		slog.Info("Loading promo sources into SQLite", "pending", len(pending))
+		if _, err := repo.loadPromoFiles(pending); err != nil {
+			repo.Close()
+			return nil, fmt.Errorf("failed to load promo files: %w", err)
+		}
+	}
+
+	slog.Info("SQLite promo repository ready", "sources", len(config.Sources), "duration", time.Since(startTime))
+
+	return repo, nil
+}
+
+// openSQLitePromoRepository opens the database and brings its schema up to
+// date without loading any source
+func openSQLitePromoRepository(config SQLitePromoConfig) (*SQLitePromoRepository, error) {
+	if err := validatePromoSources(config.Sources); err != nil {
+		return nil, err
+	}
+
 	// Set default configuration values if not provided
 	if config.DatabasePath == "" {
 		config.DatabasePath = "promo_codes.db"
@@ -52,35 +121,35 @@ func NewSQLitePromoRepository(config SQLitePromoConfig) (PromoRepository, error)
 		config.WorkerCount = runtime.NumCPU()
 	}
 
-	// Ensure the directory exists
-	dbDir := filepath.Dir(config.DatabasePath)
-	if dbDir != "" && dbDir != "." {
-		if err := os.MkdirAll(dbDir, 0755); err != nil {
-			return nil, fmt.Errorf("failed to create database directory: %w", err)
+	// A read-only database is built offline, never create or change it here
+	var db *sql.DB
+	var err error
+	if config.ReadOnly {
+		db, err = openSQLiteDatabaseReadOnly(config.DatabasePath)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrPromoReadOnly, err)
+		}
+	} else {
+		// Open the database, creating its directory if needed
+		db, err = openSQLiteDatabase(config.DatabasePath)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	// Open database connection with explicit connection string parameters
-	dbConnectionString := fmt.Sprintf("%s?_journal_mode=WAL&_synchronous=NORMAL&_cache_size=10000&_foreign_keys=ON", config.DatabasePath)
-	db, err := sql.Open("sqlite3", dbConnectionString)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
-	}
-
-	// Verify connection is working with ping
-	if err := db.Ping(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to ping SQLite database: %w", err)
-	}
-
-	// Set pragmas for better performance with validation
+	// Set pragmas for better performance with validation; the journal
+	// mode and page size are settings of the file and stay as built
 	pragmas := []string{
-		"PRAGMA journal_mode = WAL",
-		"PRAGMA synchronous = NORMAL",
 		"PRAGMA cache_size = 10000",
 		"PRAGMA temp_store = MEMORY",
 		"PRAGMA mmap_size = 30000000000",
-		"PRAGMA page_size = 4096",
+	}
+	if !config.ReadOnly {
+		pragmas = append(pragmas,
+			"PRAGMA journal_mode = WAL",
+			"PRAGMA synchronous = NORMAL",
+			"PRAGMA page_size = 4096",
+		)
 	}
 
 	for _, pragma := range pragmas {
@@ -98,197 +167,616 @@ func NewSQLitePromoRepository(config SQLitePromoConfig) (PromoRepository, error)
 		}
 	}
 
-	// Define promo file paths
-	promoFiles := []string{
-		"internal/repository/promofiles/couponbase1",
-		"internal/repository/promofiles/couponbase2",
-		"internal/repository/promofiles/couponbase3",
-	}
-
 	// Create repository instance
 	repo := &SQLitePromoRepository{
-		db:            db,
-		databasePath:  config.DatabasePath,
-		fileCount:     len(promoFiles),
-		batchSize:     config.BatchSize,
-		workerCount:   config.WorkerCount,
-		createIndexes: config.CreateIndexes,
-	}
-
-	// Initialize database schema
-	if err := repo.initializeSchema(); err != nil {
-		db.Close()
-		log.Println("Failed to initialize database schema, attempting to repair...", err)
-		return nil, fmt.Errorf("failed to initialize database schema: %w", err)
+		db:           db,
+		databasePath: config.DatabasePath,
+		sources:      config.Sources,
+		batchSize:    config.BatchSize,
+		workerCount:  config.WorkerCount,
+		readOnly:     config.ReadOnly,
+		progress:     config.Progress,
 	}
 
-	// Check if tables are already populated
-	populated, err := repo.areTablesPopulated()
-	if err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to check if tables are populated: %w", err)
-	}
+	// Initialize database schema, which a read-only database must already have
+	if config.ReadOnly {
+		if err := NewMigrator(db, "promo", promoMigrations(config.Sources)).Check(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("%w: %v", ErrPromoReadOnly, err)
+		}
+	} else {
+		if err := repo.initializeSchema(); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to initialize database schema: %w", err)
+		}
 
-	// Load data if tables are not populated
-	if !populated {
-		log.Println("Promo code tables are empty, loading data...")
-		if err := repo.loadPromoFiles(promoFiles); err != nil {
+		// Forget the sources no longer configured, freeing their bits
+		if err := repo.pruneRemovedSources(); err != nil {
 			db.Close()
-			return nil, fmt.Errorf("failed to load promo files: %w", err)
+			return nil, err
 		}
 	}
 
-	_ = time.Since(startTime)
+	// Give every source its bit in the codes' sources mask
+	if err := repo.assignSourceBits(); err != nil {
+		db.Close()
+		return nil, err
+	}
 
 	return repo, nil
 }
 
-// initializeSchema creates the necessary tables in the database
-func (r *SQLitePromoRepository) initializeSchema() error {
-	log.Printf("Creating tables for %d promo files", r.fileCount)
+// promoMigrations versions the schema used by SQLitePromoRepository.
+// The first version creates one code table per promo file. The second
+// replaces them with tables per named source, created on demand, and a
+// promo_sources table recording which file each source was loaded from.
+// The third merges the per-source tables into a single promo_codes table
+// holding each code once with a bitmask of its sources.
+// The first version numbered the tables by position, so the second takes
+// the Nth file table to hold the codes of the Nth configured source.
+func promoMigrations(sources []PromoSource) []Migration {
+	fileCount := len(sources)
+	return []Migration{
+		{
+			Version: 1,
+			Name:    "create_promo_code_tables",
+			Up: func(tx *sql.Tx) error {
+				for i := 1; i <= fileCount; i++ {
+					query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS promo_codes_file%d ( code TEXT PRIMARY KEY )", i)
+					if _, err := tx.Exec(query); err != nil {
+						return fmt.Errorf("failed to create table for file %d: %w", i, err)
+					}
+				}
+				return nil
+			},
+			Down: func(tx *sql.Tx) error {
+				for i := 1; i <= fileCount; i++ {
+					if _, err := tx.Exec(fmt.Sprintf("DROP TABLE IF EXISTS promo_codes_file%d", i)); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			Version: 2,
+			Name:    "named_promo_sources",
+			Up: func(tx *sql.Tx) error {
+				err := execStatements(`CREATE TABLE IF NOT EXISTS promo_sources (
+					name TEXT PRIMARY KEY,
+					path TEXT NOT NULL,
+					code_count INTEGER NOT NULL,
+					loaded_at INTEGER NOT NULL
+				)`)(tx)
+				if err != nil {
+					return err
+				}
 
-	// Create tables for each promo file
-	for i := 1; i <= r.fileCount; i++ {
-		tableName := fmt.Sprintf("promo_codes_file%d", i)
-		query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ( code TEXT PRIMARY KEY )", tableName)
+				// Keep the codes of every loaded file as those of the source at its position
+				loadedAt := time.Now().Unix()
+				moves := make(map[string]string)
+				for i, source := range sources {
+					table := fmt.Sprintf("promo_codes_file%d", i+1)
+					exists, err := tableExists(tx, table)
+					if err != nil {
+						return err
+					}
+					if !exists {
+						continue
+					}
+					var count int
+					if err := tx.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count); err != nil {
+						return fmt.Errorf("failed to count codes of file %d: %w", i+1, err)
+					}
+					if count == 0 {
+						continue
+					}
+					_, err = tx.Exec(`INSERT INTO promo_sources (name, path, code_count, loaded_at) VALUES (?, ?, ?, ?)`,
+						source.Name, source.Path, count, loadedAt)
+					if err != nil {
+						return fmt.Errorf("failed to record source %s: %w", source.Name, err)
+					}
+					moves[table] = sourceTable(source.Name)
+				}
 
-		log.Printf("Executing SQL: %s", query)
-		result, err := r.db.Exec(query)
-		if err != nil {
-			return fmt.Errorf("failed to create table for file %d: %w", i, err)
-		}
+				// Files beyond the configured sources, or never loaded, are dropped
+				return replaceTables(tx, moves, func() error {
+					return dropTables(tx, "promo_codes_file")
+				})
+			},
+			Down: func(tx *sql.Tx) error {
+				names, err := queryStrings(tx, `SELECT name FROM promo_sources`)
+				if err != nil {
+					return err
+				}
 
-		// Log success message with any affected rows (usually 0 for CREATE TABLE IF NOT EXISTS)
-		rowsAffected, _ := result.RowsAffected()
-		log.Printf("Table %s created or already exists (rows affected: %d)", tableName, rowsAffected)
+				// Give the codes of the configured sources back their file table
+				moves := make(map[string]string)
+				for i, source := range sources {
+					if slices.Contains(names, source.Name) {
+						moves[sourceTable(source.Name)] = fmt.Sprintf("promo_codes_file%d", i+1)
+					}
+				}
+				err = replaceTables(tx, moves, func() error {
+					for _, name := range names {
+						if _, err := tx.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", sourceTable(name))); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				for i := 1; i <= fileCount; i++ {
+					query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS promo_codes_file%d ( code TEXT PRIMARY KEY )", i)
+					if _, err := tx.Exec(query); err != nil {
+						return err
+					}
+				}
+				return execStatements(`DROP TABLE IF EXISTS promo_sources`)(tx)
+			},
+		},
+		{
+			Version: 3,
+			Name:    "single_promo_code_table",
+			Up: func(tx *sql.Tx) error {
+				err := execStatements(
+					`ALTER TABLE promo_sources ADD COLUMN bit INTEGER`,
+					`CREATE TABLE promo_codes (
+						code TEXT PRIMARY KEY,
+						sources INTEGER NOT NULL
+					) WITHOUT ROWID`,
+				)(tx)
+				if err != nil {
+					return err
+				}
 
-		// Verify the table was actually created
-		verifyQuery := fmt.Sprintf("SELECT name FROM sqlite_master WHERE type='table' AND name='%s'", tableName)
-		var name string
-		err = r.db.QueryRow(verifyQuery).Scan(&name)
-		if err != nil {
-			return fmt.Errorf("failed to verify table %s was created: %w", tableName, err)
-		}
+				// Number the loaded sources and move their codes over
+				names, err := queryStrings(tx, `SELECT name FROM promo_sources ORDER BY name`)
+				if err != nil {
+					return err
+				}
+				if len(names) > MaxPromoIndexSources {
+					return fmt.Errorf("%d promo sources loaded, at most %d fit in a sources mask", len(names), MaxPromoIndexSources)
+				}
+				for bit, name := range names {
+					if _, err := tx.Exec(`UPDATE promo_sources SET bit = ? WHERE name = ?`, bit, name); err != nil {
+						return err
+					}
+					exists, err := tableExists(tx, sourceTable(name))
+					if err != nil {
+						return err
+					}
+					if !exists {
+						continue
+					}
+					merge := fmt.Sprintf(`INSERT INTO promo_codes (code, sources) SELECT code, ? FROM %s WHERE true
+						ON CONFLICT (code) DO UPDATE SET sources = sources | excluded.sources`, sourceTable(name))
+					if _, err := tx.Exec(merge, sourceMask(uint(bit))); err != nil {
+						return fmt.Errorf("failed to merge codes of source %s: %w", name, err)
+					}
+				}
 
-		if name != tableName {
-			return fmt.Errorf("table %s was not created successfully", tableName)
+				// Drop the per-source tables, including those of sources no longer recorded
+				for _, prefix := range []string{"promo_codes_", "promo_staging_"} {
+					if err := dropTables(tx, prefix); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Down: func(tx *sql.Tx) error {
+				rows, err := tx.Query(`SELECT name, bit FROM promo_sources WHERE bit IS NOT NULL`)
+				if err != nil {
+					return err
+				}
+				bits := make(map[string]uint)
+				for rows.Next() {
+					var name string
+					var bit uint
+					if err := rows.Scan(&name, &bit); err != nil {
+						rows.Close()
+						return err
+					}
+					bits[name] = bit
+				}
+				rows.Close()
+				if err := rows.Err(); err != nil {
+					return err
+				}
+
+				// Split the codes back into a table per source
+				for name, bit := range bits {
+					table := sourceTable(name)
+					err := execStatements(fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s ( code TEXT PRIMARY KEY )", table))(tx)
+					if err != nil {
+						return err
+					}
+					split := fmt.Sprintf(`INSERT OR IGNORE INTO %s (code) SELECT code FROM promo_codes WHERE sources & ? != 0`, table)
+					if _, err := tx.Exec(split, sourceMask(bit)); err != nil {
+						return fmt.Errorf("failed to split codes of source %s: %w", name, err)
+					}
+				}
+				return execStatements(
+					`DROP TABLE IF EXISTS promo_codes`,
+					`ALTER TABLE promo_sources DROP COLUMN bit`,
+				)(tx)
+			},
+		},
+	}
+}
+
+// queryStrings returns the single text column of every row of a query
+func queryStrings(tx *sql.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
 		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
 
-		log.Printf("Verified table %s exists in database", tableName)
+// sourceMask returns the sources mask with only the given bit set, as
+// stored in SQLite's signed 64-bit integers
+func sourceMask(bit uint) int64 {
+	return int64(uint64(1) << bit)
+}
+
+// likeEscaper escapes the LIKE wildcards of a literal, with \ as ESCAPE character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// tableExists reports whether a table of exactly the given name exists
+func tableExists(tx *sql.Tx, name string) (bool, error) {
+	var count int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count)
+	return count > 0, err
+}
+
+// listTables returns the names of the tables starting with a prefix, taken literally
+func listTables(tx *sql.Tx, prefix string) ([]string, error) {
+	return queryStrings(tx, `SELECT name FROM sqlite_master WHERE type = 'table' AND name LIKE ? ESCAPE '\'`,
+		likeEscaper.Replace(prefix)+"%")
+}
+
+// dropTables drops every table starting with a prefix
+func dropTables(tx *sql.Tx, prefix string) error {
+	tables, err := listTables(tx, prefix)
+	if err != nil {
+		return err
 	}
+	for _, table := range tables {
+		if _, err := tx.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %q", table)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// replaceTables renames tables from the keys to the values of moves. The
+// tables are moved aside while drop removes the tables left behind, so a
+// new name may be the old name of another table.
+func replaceTables(tx *sql.Tx, moves map[string]string, drop func() error) error {
+	aside := make(map[string]string, len(moves))
+	for from := range moves {
+		aside[from] = "promo_migrating_" + from
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %q RENAME TO %q", from, aside[from])); err != nil {
+			return fmt.Errorf("failed to rename table %s: %w", from, err)
+		}
+	}
+	if err := drop(); err != nil {
+		return err
+	}
+	for from, to := range moves {
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %q RENAME TO %q", aside[from], to)); err != nil {
+			return fmt.Errorf("failed to rename table %s to %s: %w", from, to, err)
+		}
+	}
 	return nil
 }
 
-// areTablesPopulated checks if the promo code tables already have data
-func (r *SQLitePromoRepository) areTablesPopulated() (bool, error) {
-	for i := 2; i <= r.fileCount; i++ {
-		var count int
-		tableName := fmt.Sprintf("promo_codes_file%d", i)
+// sourceTable returns the code table of a source before the single table
+// schema; names are validated by validatePromoSources
+func sourceTable(name string) string {
+	return "promo_codes_" + name
+}
+
+// stagingTable returns the table a source is loaded into before being swapped in
+func stagingTable(name string) string {
+	return "promo_staging_" + name
+}
+
+// initializeSchema brings the database schema up to date
+func (r *SQLitePromoRepository) initializeSchema() error {
+	return NewMigrator(r.db, "promo", promoMigrations(r.sources)).Up()
+}
 
-		// Verify the table name doesn't contain unexpected characters
-		if strings.ContainsAny(tableName, ";\"`'") {
-			return false, fmt.Errorf("invalid table name format: %s", tableName)
+// assignSourceBits reads the mask bit of every source loaded before and
+// picks a free bit for new sources; it is recorded once they are loaded
+func (r *SQLitePromoRepository) assignSourceBits() error {
+	rows, err := r.db.Query(`SELECT name, bit FROM promo_sources WHERE bit IS NOT NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to read promo source bits: %w", err)
+	}
+	recorded := make(map[string]uint)
+	used := make(map[uint]bool)
+	for rows.Next() {
+		var name string
+		var bit uint
+		if err := rows.Scan(&name, &bit); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read promo source bits: %w", err)
 		}
+		recorded[name] = bit
+		used[bit] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read promo source bits: %w", err)
+	}
 
-		// Construct safe query
-		query := fmt.Sprintf("SELECT COUNT(*) FROM %s LIMIT 1", tableName)
+	r.bits = make([]uint, len(r.sources))
+	next := uint(0)
+	for i, source := range r.sources {
+		if bit, ok := recorded[source.Name]; ok {
+			r.bits[i] = bit
+			continue
+		}
+		for next < MaxPromoIndexSources && used[next] {
+			next++
+		}
+		if next == MaxPromoIndexSources {
+			return fmt.Errorf("%w: no free sources mask bit for %s, at most %d sources can be stored",
+				ErrInvalidPromoSource, source.Name, MaxPromoIndexSources)
+		}
+		r.bits[i] = next
+		used[next] = true
+	}
+	return nil
+}
 
-		// Execute query with proper error handling
-		err := r.db.QueryRow(query).Scan(&count)
-		if err != nil {
-			// Check specifically for "no such table" error
-			if strings.Contains(err.Error(), "no such table") {
-				return false, nil
-			}
-			return false, fmt.Errorf("failed to check if table %d is populated: %w", i, err)
+// bitOf returns the mask bit of a configured source
+func (r *SQLitePromoRepository) bitOf(name string) uint {
+	for i, source := range r.sources {
+		if source.Name == name {
+			return r.bits[i]
 		}
+	}
+	panic("unknown promo source " + name)
+}
 
-		if count > 0 {
-			return true, nil
+// pendingSources returns the sources that have not been loaded from their
+// configured file yet: new sources, and sources whose path has changed
+func (r *SQLitePromoRepository) pendingSources() ([]PromoSource, error) {
+	var pending []PromoSource
+	for _, source := range r.sources {
+		var path string
+		err := r.db.QueryRow(`SELECT path FROM promo_sources WHERE name = ?`, source.Name).Scan(&path)
+		switch {
+		case err == sql.ErrNoRows:
+			pending = append(pending, source)
+		case err != nil:
+			return nil, fmt.Errorf("failed to check if source %s is populated: %w", source.Name, err)
+		case path != source.Path:
+			slog.Info("Promo source moved, reloading", "source", source.Name, "from", path, "to", source.Path)
+			pending = append(pending, source)
 		}
 	}
-	return false, nil
+	return pending, nil
 }
 
-// loadPromoFiles loads promo codes from files into the database
-func (r *SQLitePromoRepository) loadPromoFiles(promoFiles []string) error {
+// loadPromoFiles loads promo codes from source files into staging tables
+// and swaps them in together. It returns the stats of every source in order.
+func (r *SQLitePromoRepository) loadPromoFiles(sources []PromoSource) ([]PromoSourceStats, error) {
 	startTime := time.Now()
-	log.Println("Starting to load promo files into SQLite...")
+	slog.Debug("Loading promo files into SQLite", "count", len(sources))
 
 	// Process each file in parallel
 	var wg sync.WaitGroup
-	errChan := make(chan error, len(promoFiles))
+	stats := make([]PromoSourceStats, len(sources))
+	errChan := make(chan error, len(sources))
 
-	for i, filePath := range promoFiles {
-		if i == 0 {
-			continue
-		}
-		fileNumber := i + 1
+	for i, source := range sources {
 		wg.Add(1)
-		go func(fn int, path string) {
+		go func(i int, source PromoSource) {
 			defer wg.Done()
-			if err := r.loadPromoFile(fn, path); err != nil {
-				errChan <- fmt.Errorf("error loading file %d: %w", fn, err)
+			r.progress.start(source.Name)
+			sourceStats, err := r.loadPromoFile(source)
+			if err != nil {
+				r.progress.finish(source.Name, 0, 0, err)
+				errChan <- fmt.Errorf("error loading source %s: %w", source.Name, err)
+				return
 			}
-		}(fileNumber, filePath)
+			r.progress.finish(source.Name, sourceStats.Lines, sourceStats.Codes, nil)
+			stats[i] = *sourceStats
+		}(i, source)
 	}
 
 	// Wait for all loading to complete
 	wg.Wait()
 	close(errChan)
 
-	// Check for errors
+	// Check for errors, leaving the live tables untouched
 	if len(errChan) > 0 {
-		return <-errChan
+		r.dropStagingTables(sources)
+		return nil, <-errChan
 	}
 
-	// Create indexes if configured to do so
-	if r.createIndexes {
-		log.Println("Creating indexes on promo code tables...")
-		for i := 1; i <= r.fileCount; i++ {
-			query := fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_code_file%d ON promo_codes_file%d(code)", i, i)
-			if _, err := r.db.Exec(query); err != nil {
-				return fmt.Errorf("failed to create index for file %d: %w", i, err)
-			}
-		}
+	if err := r.swapInSources(sources, stats); err != nil {
+		r.dropStagingTables(sources)
+		return nil, err
 	}
 
 	elapsed := time.Since(startTime)
-	log.Printf("Completed loading all promo files in %v", elapsed)
+	slog.Info("Loaded promo sources", "count", len(sources), "duration", elapsed)
+	return stats, nil
+}
+
+// swapInSources replaces the codes of sources with their staging tables
+// in one transaction, blocking lookups only while it commits
+func (r *SQLitePromoRepository) swapInSources(sources []PromoSource, stats []PromoSourceStats) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	loadedAt := time.Now().Unix()
+	for i, source := range sources {
+		bit := r.bitOf(source.Name)
+		mask := sourceMask(bit)
+
+		// Clear the source's bit everywhere, then set it on the codes it holds now
+		if _, err := tx.Exec(`UPDATE promo_codes SET sources = sources & ~? WHERE sources & ? != 0`, mask, mask); err != nil {
+			return fmt.Errorf("failed to clear source %s: %w", source.Name, err)
+		}
+		merge := fmt.Sprintf(`INSERT INTO promo_codes (code, sources) SELECT code, ? FROM %s WHERE true
+			ON CONFLICT (code) DO UPDATE SET sources = sources | excluded.sources`, stagingTable(source.Name))
+		if _, err := tx.Exec(merge, mask); err != nil {
+			return fmt.Errorf("failed to swap in source %s: %w", source.Name, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("DROP TABLE %s", stagingTable(source.Name))); err != nil {
+			return fmt.Errorf("failed to drop staging table of source %s: %w", source.Name, err)
+		}
+
+		// Record where the source was loaded from
+		_, err := tx.Exec(`INSERT OR REPLACE INTO promo_sources (name, path, code_count, loaded_at, bit) VALUES (?, ?, ?, ?, ?)`,
+			source.Name, source.Path, stats[i].Codes, loadedAt, bit)
+		if err != nil {
+			return fmt.Errorf("failed to record source %s: %w", source.Name, err)
+		}
+	}
+	// Codes dropped by every source they were in
+	if err := r.removeUnconfiguredSources(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM promo_codes WHERE sources = 0`); err != nil {
+		return fmt.Errorf("failed to remove dropped codes: %w", err)
+	}
+
+	// Readers see the previous codes until the commit, but views acquired
+	// for several lookups must not see it happen in between
+	r.swapMu.Lock()
+	defer r.swapMu.Unlock()
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit swap: %w", err)
+	}
 	return nil
 }
 
-// loadPromoFile loads a single promo file into the database
-func (r *SQLitePromoRepository) loadPromoFile(fileNumber int, filePath string) error {
-	startTime := time.Now()
-	log.Printf("Starting to load file %d: %s", fileNumber, filePath)
+// pruneRemovedSources removes the sources no longer configured and the
+// codes only they held
+func (r *SQLitePromoRepository) pruneRemovedSources() error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.removeUnconfiguredSources(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM promo_codes WHERE sources = 0`); err != nil {
+		return fmt.Errorf("failed to remove dropped codes: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit removed sources: %w", err)
+	}
+	return nil
+}
 
-	// Check if file exists
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		// Create empty file for testing if it doesn't exist
-		log.Printf("File %s doesn't exist, creating empty test file", filePath)
-		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-			return fmt.Errorf("failed to create directory: %w", err)
+// removeUnconfiguredSources clears the bits of the sources recorded in the
+// database but no longer configured and forgets them. Codes left without a
+// source are not deleted here.
+func (r *SQLitePromoRepository) removeUnconfiguredSources(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT name, bit FROM promo_sources`)
+	if err != nil {
+		return fmt.Errorf("failed to read promo sources: %w", err)
+	}
+	removed := make(map[string]sql.NullInt64)
+	for rows.Next() {
+		var name string
+		var bit sql.NullInt64
+		if err := rows.Scan(&name, &bit); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read promo sources: %w", err)
 		}
-		if err := os.WriteFile(filePath, []byte("testcode1\ntestcode2\ntestcode3\n"), 0644); err != nil {
-			return fmt.Errorf("failed to create test file: %w", err)
+		if !containsSource(r.sources, name) {
+			removed[name] = bit
 		}
-	} else if err != nil {
-		return fmt.Errorf("failed to check if file exists: %w", err)
 	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read promo sources: %w", err)
+	}
+
+	for name, bit := range removed {
+		if bit.Valid {
+			mask := sourceMask(uint(bit.Int64))
+			if _, err := tx.Exec(`UPDATE promo_codes SET sources = sources & ~? WHERE sources & ? != 0`, mask, mask); err != nil {
+				return fmt.Errorf("failed to clear removed source %s: %w", name, err)
+			}
+		}
+		if _, err := tx.Exec(`DELETE FROM promo_sources WHERE name = ?`, name); err != nil {
+			return fmt.Errorf("failed to remove source %s: %w", name, err)
+		}
+		slog.Info("Removed promo source no longer configured", "source", name)
+	}
+	return nil
+}
 
-	// Open the file
-	file, err := os.Open(filePath)
+// dropStagingTables removes the staging tables left by a failed load
+func (r *SQLitePromoRepository) dropStagingTables(sources []PromoSource) {
+	for _, source := range sources {
+		if _, err := r.db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", stagingTable(source.Name))); err != nil {
+			slog.Warn("Failed to drop staging table", "source", source.Name, "error", err)
+		}
+	}
+}
+
+// loadPromoFile loads a single promo source file into its staging table
+// and returns what was read
+func (r *SQLitePromoRepository) loadPromoFile(source PromoSource) (*PromoSourceStats, error) {
+	startTime := time.Now()
+	filePath := source.Path
+	slog.Debug("Loading promo source", "source", source.Name, "path", filePath)
+
+	// A missing source is an error rather than a source without codes
+	if _, err := os.Stat(filePath); err != nil {
+		return nil, fmt.Errorf("promo source %s: %w", source.Name, err)
+	}
+
+	// Open the file, decompressing it if gzipped
+	file, err := openPromoSource(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+		return nil, err
 	}
 	defer file.Close()
 
+	// Start from an empty staging table
+	tableName := stagingTable(source.Name)
+	for _, query := range []string{
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", tableName),
+		fmt.Sprintf("CREATE TABLE %s ( code TEXT PRIMARY KEY )", tableName),
+	} {
+		if _, err := r.db.Exec(query); err != nil {
+			return nil, fmt.Errorf("failed to create staging table: %w", err)
+		}
+	}
+
 	// Begin transaction
 	tx, err := r.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer func() {
 		if tx != nil {
@@ -297,14 +785,14 @@ func (r *SQLitePromoRepository) loadPromoFile(fileNumber int, filePath string) e
 	}()
 
 	// Prepare insert statement
-	tableName := fmt.Sprintf("promo_codes_file%d", fileNumber)
 	stmt, err := tx.Prepare(fmt.Sprintf("INSERT OR IGNORE INTO %s (code) VALUES (?)", tableName))
 	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
+		return nil, fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
 	// Process lines
+	stats := &PromoSourceStats{Name: source.Name}
 	lineCount := 0
 	batchCount := 0
 	scanner := bufio.NewScanner(file)
@@ -314,35 +802,44 @@ func (r *SQLitePromoRepository) loadPromoFile(fileNumber int, filePath string) e
 
 	for scanner.Scan() {
 		code := strings.TrimSpace(scanner.Text())
-		if code != "" {
-			if _, err := stmt.Exec(code); err != nil {
-				return fmt.Errorf("error inserting code: %w", err)
+		if code != "" && !models.ValidPromoCodeLength(code) {
+			stats.Skipped++
+			lineCount++
+		} else if code != "" {
+			result, err := stmt.Exec(code)
+			if err != nil {
+				return nil, fmt.Errorf("error inserting code: %w", err)
+			}
+			// INSERT OR IGNORE affects no row for a repeated code
+			if inserted, err := result.RowsAffected(); err == nil && inserted == 0 {
+				stats.Duplicates++
 			}
 			lineCount++
 
 			// Commit every batchSize records
 			if lineCount > 0 && lineCount%r.batchSize == 0 {
 				if err := tx.Commit(); err != nil {
-					return fmt.Errorf("error committing transaction: %w", err)
+					return nil, fmt.Errorf("error committing transaction: %w", err)
 				}
 
 				batchCount++
+				r.progress.read(source.Name, lineCount)
 				if batchCount%10 == 0 {
-					log.Printf("File %d: Inserted %d codes...", fileNumber, lineCount)
+					slog.Debug("Inserting promo codes", "source", source.Name, "lines", lineCount)
 				}
 
 				// Start new transaction
 				tx = nil
 				tx, err = r.db.Begin()
 				if err != nil {
-					return fmt.Errorf("failed to begin new transaction: %w", err)
+					return nil, fmt.Errorf("failed to begin new transaction: %w", err)
 				}
 
 				// Prepare new statement
 				stmt.Close() // Close previous statement
 				stmt, err = tx.Prepare(fmt.Sprintf("INSERT OR IGNORE INTO %s (code) VALUES (?)", tableName))
 				if err != nil {
-					return fmt.Errorf("failed to prepare statement: %w", err)
+					return nil, fmt.Errorf("failed to prepare statement: %w", err)
 				}
 			}
 		}
@@ -350,54 +847,221 @@ func (r *SQLitePromoRepository) loadPromoFile(fileNumber int, filePath string) e
 
 	// Check for scanner errors
 	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading file: %w", err)
+		return nil, fmt.Errorf("error reading file: %w", err)
 	}
 
 	// Commit final batch
 	if tx != nil {
 		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("error committing final transaction: %w", err)
+			return nil, fmt.Errorf("error committing final transaction: %w", err)
 		}
 		tx = nil
 	}
 
+	stats.Lines = lineCount
+	stats.Codes = lineCount - stats.Duplicates - stats.Skipped
+
 	elapsed := time.Since(startTime)
-	log.Printf("Completed loading file %d with %d codes in %v", fileNumber, lineCount, elapsed)
-	return nil
+	slog.Info("Loaded promo source", "source", source.Name, "codes", stats.Codes, "duration", elapsed)
+	metrics.ObservePromoSourceLoad(source.Name, stats.Codes, elapsed)
+	return stats, nil
+}
+
+// ExistsInFile checks if a given promo code exists in a specific source
+func (r *SQLitePromoRepository) ExistsInFile(ctx context.Context, code string, fileNumber int) (bool, error) {
+	r.swapMu.RLock()
+	defer r.swapMu.RUnlock()
+	return r.existsInFile(ctx, code, fileNumber)
 }
 
-// ExistsInFile checks if a given promo code exists in a specific file
-func (r *SQLitePromoRepository) ExistsInFile(code string, fileNumber int) (bool, error) {
+// existsInFile looks a code up; the caller must hold swapMu for reading
+func (r *SQLitePromoRepository) existsInFile(ctx context.Context, code string, fileNumber int) (bool, error) {
 	// Validate fileNumber
-	if fileNumber < 1 || fileNumber > r.fileCount {
+	if fileNumber < 1 || fileNumber > len(r.sources) {
 		return false, ErrInvalidFileNumber
 	}
 
-	// Sanitize table name for security
-	tableName := fmt.Sprintf("promo_codes_file%d", fileNumber)
-	if strings.ContainsAny(tableName, ";\"`'") {
-		return false, fmt.Errorf("invalid table name format: %s", tableName)
+	mask, err := r.sourcesMask(ctx, code)
+	if err != nil {
+		return false, err
 	}
+	return mask&uint64(sourceMask(r.bits[fileNumber-1])) != 0, nil
+}
 
-	// Construct the safe parameterized query
-	query := fmt.Sprintf("SELECT 1 FROM %s WHERE code = ? LIMIT 1", tableName)
+// sourcesMask returns the mask of the sources holding a code, 0 if none does
+func (r *SQLitePromoRepository) sourcesMask(ctx context.Context, code string) (_ uint64, err error) {
+	ctx, span := startSpan(ctx, "SQLitePromoRepository.sourcesMask", "promo_codes")
+	defer func() { tracing.End(span, err) }()
 
-	// Execute the query with proper parameter binding for security
-	var exists int
-	err := r.db.QueryRow(query, code).Scan(&exists)
+	defer metrics.ObservePromoLookup(metrics.BackendSQLite, time.Now())
 
+	var mask int64
+	err = r.db.QueryRowContext(ctx, `SELECT sources FROM promo_codes WHERE code = ?`, code).Scan(&mask)
 	if err == sql.ErrNoRows {
 		// Code doesn't exist
-		return false, nil
+		return 0, nil
 	} else if err != nil {
-		// Handle table doesn't exist error specifically
-		if strings.Contains(err.Error(), "no such table") {
-			return false, nil
+		return 0, fmt.Errorf("error looking up promo code: %w", err)
+	}
+	return uint64(mask), nil
+}
+
+// LookupSources reports which sources hold a code with a single query
+func (r *SQLitePromoRepository) LookupSources(ctx context.Context, code string) ([]bool, error) {
+	r.swapMu.RLock()
+	defer r.swapMu.RUnlock()
+	return r.lookupSources(ctx, code)
+}
+
+// lookupSources finds the sources of a code; the caller must hold swapMu for reading
+func (r *SQLitePromoRepository) lookupSources(ctx context.Context, code string) ([]bool, error) {
+	mask, err := r.sourcesMask(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	found := make([]bool, len(r.sources))
+	for i, bit := range r.bits {
+		found[i] = mask&uint64(sourceMask(bit)) != 0
+	}
+	return found, nil
+}
+
+// Sources returns the names of the promo sources in lookup order
+func (r *SQLitePromoRepository) Sources() []string {
+	return sourceNames(r.sources)
+}
+
+// Reload reads every source into staging tables and swaps them in
+func (r *SQLitePromoRepository) Reload() (*PromoReloadStats, error) {
+	if !r.reloading.TryLock() {
+		return nil, ErrReloadInProgress
+	}
+	defer r.reloading.Unlock()
+
+	// A read-only database is rebuilt offline and its tables swapped by the builder
+	if r.readOnly {
+		return nil, ErrPromoReadOnly
+	}
+
+	startTime := time.Now()
+	stats, err := r.loadPromoFiles(r.sources)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(stats))
+	for _, source := range stats {
+		counts[source.Name] = source.Codes
+	}
+	return &PromoReloadStats{Codes: counts, Duration: time.Since(startTime)}, nil
+}
+
+// BuildSQLitePromoDatabase loads every source into the database at
+// config.DatabasePath, replacing codes loaded before, and closes it.
+// A server using the database sees the new codes once they are swapped in.
+func BuildSQLitePromoDatabase(config SQLitePromoConfig) ([]PromoSourceStats, error) {
+	repo, err := openSQLitePromoRepository(config)
+	if err != nil {
+		return nil, err
+	}
+	defer repo.Close()
+
+	return repo.loadPromoFiles(repo.sources)
+}
+
+// VerifySQLitePromoDatabase checks the integrity of a promo database and
+// that every source was loaded from its configured path with the recorded
+// number of codes. It returns the number of codes per source.
+func VerifySQLitePromoDatabase(databasePath string, sources []PromoSource) (map[string]int, error) {
+	if err := validatePromoSources(sources); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(databasePath); err != nil {
+		return nil, err
+	}
+	db, err := openSQLiteDatabase(databasePath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var integrity string
+	if err := db.QueryRow(`PRAGMA integrity_check`).Scan(&integrity); err != nil {
+		return nil, fmt.Errorf("failed to check integrity: %w", err)
+	}
+	if integrity != "ok" {
+		return nil, fmt.Errorf("integrity check failed: %s", integrity)
+	}
+
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'promo_codes'`).Scan(&tables); err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	if tables == 0 {
+		return nil, fmt.Errorf("database predates the single promo_codes table, rebuild it to migrate")
+	}
+
+	counts := make(map[string]int, len(sources))
+	for _, source := range sources {
+		var path string
+		var recorded int
+		err := db.QueryRow(`SELECT path, code_count FROM promo_sources WHERE name = ?`, source.Name).Scan(&path, &recorded)
+		switch {
+		case err == sql.ErrNoRows:
+			return nil, fmt.Errorf("source %s is not loaded", source.Name)
+		case err != nil:
+			return nil, fmt.Errorf("failed to read source %s: %w", source.Name, err)
+		case path != source.Path:
+			return nil, fmt.Errorf("source %s was loaded from %s, expected %s", source.Name, path, source.Path)
+		}
+
+		var count int
+		err = db.QueryRow(`SELECT COUNT(*) FROM promo_codes WHERE sources & (1 << (SELECT bit FROM promo_sources WHERE name = ?)) != 0`,
+			source.Name).Scan(&count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count codes of source %s: %w", source.Name, err)
 		}
-		return false, fmt.Errorf("error checking if code exists in file %d: %w", fileNumber, err)
+		if count != recorded {
+			return nil, fmt.Errorf("source %s holds %d codes, %d recorded", source.Name, count, recorded)
+		}
+		counts[source.Name] = count
 	}
+	return counts, nil
+}
+
+// Acquire holds off table swaps until release is called
+func (r *SQLitePromoRepository) Acquire() (PromoRepository, func()) {
+	r.swapMu.RLock()
+	return sqlitePromoView{r}, r.swapMu.RUnlock
+}
+
+// sqlitePromoView looks codes up while its creator holds swapMu for reading
+type sqlitePromoView struct {
+	repo *SQLitePromoRepository
+}
+
+// ExistsInFile checks if a given promo code exists in a specific source
+func (v sqlitePromoView) ExistsInFile(ctx context.Context, code string, fileNumber int) (bool, error) {
+	return v.repo.existsInFile(ctx, code, fileNumber)
+}
+
+// LookupSources reports which sources hold a code with a single query
+func (v sqlitePromoView) LookupSources(ctx context.Context, code string) ([]bool, error) {
+	return v.repo.lookupSources(ctx, code)
+}
+
+// Sources returns the names of the promo sources in lookup order
+func (v sqlitePromoView) Sources() []string {
+	return v.repo.Sources()
+}
+
+// Close is a no-op, the view does not own the database
+func (v sqlitePromoView) Close() error {
+	return nil
+}
 
-	return true, nil
+// Ping checks that the database can be reached
+func (r *SQLitePromoRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
 }
 
 // Close closes the database connection
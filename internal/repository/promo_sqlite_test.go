@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSQLitePromoMigrationKeepsBaselineCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		// baseline holds the codes of the tables the baseline schema numbered by file
+		baseline map[int][]string
+		// sources are configured in this order, each source file holding FRESHCODE
+		sources []string
+		// want lists, per source, the codes it holds after the upgrade
+		want map[string][]string
+	}{
+		{
+			name:     "codes kept by position",
+			baseline: map[int][]string{1: {"OLDALPHA1", "SHAREDOLD"}, 2: {"OLDBETA01", "SHAREDOLD"}},
+			sources:  []string{"alpha", "beta"},
+			want: map[string][]string{
+				"alpha": {"OLDALPHA1", "SHAREDOLD"},
+				"beta":  {"OLDBETA01", "SHAREDOLD"},
+			},
+		},
+		{
+			name:     "empty file table is loaded",
+			baseline: map[int][]string{1: {"OLDALPHA1"}, 2: nil},
+			sources:  []string{"alpha", "beta"},
+			want: map[string][]string{
+				"alpha": {"OLDALPHA1"},
+				"beta":  {"FRESHCODE"},
+			},
+		},
+		{
+			name:     "file tables beyond the sources are dropped",
+			baseline: map[int][]string{1: {"OLDALPHA1"}, 2: {"OLDBETA01"}},
+			sources:  []string{"alpha"},
+			want:     map[string][]string{"alpha": {"OLDALPHA1"}},
+		},
+		{
+			name:     "source names clashing with file tables",
+			baseline: map[int][]string{1: {"OLDFIRST1"}, 2: {"OLDSECOND"}},
+			sources:  []string{"file2", "file1"},
+			want: map[string][]string{
+				"file2": {"OLDFIRST1"},
+				"file1": {"OLDSECOND"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			databasePath := filepath.Join(dir, "promo.db")
+
+			// Write the baseline tables, and one the LIKE wildcards would match
+			db, err := openSQLiteDatabase(databasePath)
+			if err != nil {
+				t.Fatalf("failed to open database: %v", err)
+			}
+			statements := []string{`CREATE TABLE promo_codesXfile9 ( code TEXT PRIMARY KEY )`}
+			for file, codes := range tt.baseline {
+				statements = append(statements, fmt.Sprintf(`CREATE TABLE promo_codes_file%d ( code TEXT PRIMARY KEY )`, file))
+				for _, code := range codes {
+					statements = append(statements, fmt.Sprintf(`INSERT INTO promo_codes_file%d (code) VALUES ('%s')`, file, code))
+				}
+			}
+			for _, statement := range statements {
+				if _, err := db.Exec(statement); err != nil {
+					t.Fatalf("failed to write baseline: %v", err)
+				}
+			}
+			db.Close()
+
+			sources := make([]PromoSource, len(tt.sources))
+			for i, name := range tt.sources {
+				path := filepath.Join(dir, name+".txt")
+				if err := os.WriteFile(path, []byte("FRESHCODE\n"), 0644); err != nil {
+					t.Fatalf("failed to write promo source: %v", err)
+				}
+				sources[i] = PromoSource{Name: name, Path: path}
+			}
+			repo, err := NewSQLitePromoRepository(SQLitePromoConfig{DatabasePath: databasePath, Sources: sources})
+			if err != nil {
+				t.Fatalf("NewSQLitePromoRepository() unexpected error: %v", err)
+			}
+			defer repo.Close()
+
+			ctx := context.Background()
+			for i, name := range tt.sources {
+				for _, code := range []string{"OLDALPHA1", "OLDBETA01", "SHAREDOLD", "OLDFIRST1", "OLDSECOND", "FRESHCODE"} {
+					exists, err := repo.ExistsInFile(ctx, code, i+1)
+					if err != nil {
+						t.Fatalf("ExistsInFile() unexpected error: %v", err)
+					}
+					if want := slices.Contains(tt.want[name], code); exists != want {
+						t.Errorf("source %s holds %s = %v, want %v", name, code, exists, want)
+					}
+				}
+			}
+
+			tables, err := listTestTables(databasePath)
+			if err != nil {
+				t.Fatalf("failed to list tables: %v", err)
+			}
+			if !slices.Contains(tables, "promo_codesXfile9") {
+				t.Errorf("tables = %v, want promo_codesXfile9 kept", tables)
+			}
+			for _, table := range tables {
+				if strings.HasPrefix(table, "promo_codes_file") || strings.HasPrefix(table, "promo_migrating_") {
+					t.Errorf("tables = %v, want no baseline or migrating table left", tables)
+				}
+			}
+		})
+	}
+}
+
+// listTestTables returns the names of the tables of a database
+func listTestTables(databasePath string) ([]string, error) {
+	db, err := openSQLiteDatabase(databasePath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table'`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
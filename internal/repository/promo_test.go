@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// promoFixture is the content of the promo sources shared by the promo
+// repository tests, by source name
+var promoFixture = map[string]string{
+	// Codes of every length, padded, repeated and without a final newline
+	"alpha": "HAPPYHRS\n  FIFTYOFF10  \n\nSHORT\nWAYTOOLONGCODE\nHAPPYHRS\r\nBUYGETONE\nSEVEN77\nELEVENCHARS\nLASTLINE1",
+	// Gzipped, sharing some codes with alpha
+	"beta": "FIFTYOFF10\nBUYGETONE\nBETAONLY1\n1234567890\n",
+}
+
+// writePromoFixture writes the fixture sources into a temporary directory,
+// gzipping beta, and returns them in name order
+func writePromoFixture(t *testing.T) []PromoSource {
+	t.Helper()
+	dir := t.TempDir()
+	var sources []PromoSource
+	for _, name := range []string{"alpha", "beta"} {
+		path := filepath.Join(dir, name+".txt")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatalf("failed to create promo source: %v", err)
+		}
+		if name == "beta" {
+			writer := gzip.NewWriter(file)
+			_, err = writer.Write([]byte(promoFixture[name]))
+			if closeErr := writer.Close(); err == nil {
+				err = closeErr
+			}
+		} else {
+			_, err = file.Write([]byte(promoFixture[name]))
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			t.Fatalf("failed to write promo source: %v", err)
+		}
+		sources = append(sources, PromoSource{Name: name, Path: path})
+	}
+	return sources
+}
+
+// promoRepositories returns a repository of every backend serving the sources
+func promoRepositories(t *testing.T, sources []PromoSource) map[string]ReloadablePromoRepository {
+	t.Helper()
+	dir := t.TempDir()
+
+	memoryRepo, err := NewInMemoryPromoRepository(sources, nil)
+	if err != nil {
+		t.Fatalf("failed to create in-memory promo repository: %v", err)
+	}
+
+	sqliteRepo, err := NewSQLitePromoRepository(SQLitePromoConfig{
+		DatabasePath: filepath.Join(dir, "promo.db"),
+		Sources:      sources,
+	})
+	if err != nil {
+		t.Fatalf("failed to create SQLite promo repository: %v", err)
+	}
+
+	indexPath := filepath.Join(dir, "promo.idx")
+	if _, err := BuildPromoIndex(sources, indexPath, PromoIndexOptions{}); err != nil {
+		t.Fatalf("failed to build promo index: %v", err)
+	}
+	indexRepo, err := NewIndexedPromoRepository(IndexedPromoConfig{
+		IndexPath: indexPath,
+		Sources:   sources,
+		ReadOnly:  true,
+	})
+	if err != nil {
+		t.Fatalf("failed to open promo index: %v", err)
+	}
+
+	repos := map[string]ReloadablePromoRepository{
+		"memory": memoryRepo,
+		"sqlite": sqliteRepo,
+		"index":  indexRepo,
+	}
+	t.Cleanup(func() {
+		for _, repo := range repos {
+			repo.Close()
+		}
+	})
+	return repos
+}
+
+func TestPromoRepositories(t *testing.T) {
+	tests := []struct {
+		code string
+		// want lists whether alpha and beta hold the code
+		want []bool
+	}{
+		{code: "HAPPYHRS", want: []bool{true, false}},
+		{code: "FIFTYOFF10", want: []bool{true, true}},
+		{code: "BUYGETONE", want: []bool{true, true}},
+		{code: "LASTLINE1", want: []bool{true, false}},
+		{code: "BETAONLY1", want: []bool{false, true}},
+		{code: "1234567890", want: []bool{false, true}},
+		{code: "UNKNOWN01", want: []bool{false, false}},
+		{code: "happyhrs", want: []bool{false, false}},
+		// Lines outside 8 to 10 characters are never stored
+		{code: "SHORT", want: []bool{false, false}},
+		{code: "SEVEN77", want: []bool{false, false}},
+		{code: "ELEVENCHARS", want: []bool{false, false}},
+		{code: "WAYTOOLONGCODE", want: []bool{false, false}},
+		{code: "", want: []bool{false, false}},
+	}
+
+	sources := writePromoFixture(t)
+	for name, repo := range promoRepositories(t, sources) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if got := repo.Sources(); !slices.Equal(got, []string{"alpha", "beta"}) {
+				t.Fatalf("Sources() = %v, want alpha and beta", got)
+			}
+
+			for _, tt := range tests {
+				got := make([]bool, len(sources))
+				for i := range sources {
+					exists, err := repo.ExistsInFile(ctx, tt.code, i+1)
+					if err != nil {
+						t.Fatalf("ExistsInFile(%q, %d) unexpected error: %v", tt.code, i+1, err)
+					}
+					got[i] = exists
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("ExistsInFile(%q) = %v, want %v", tt.code, got, tt.want)
+				}
+
+				if lookup, ok := repo.(PromoSourceLookup); ok {
+					found, err := lookup.LookupSources(ctx, tt.code)
+					if err != nil {
+						t.Fatalf("LookupSources(%q) unexpected error: %v", tt.code, err)
+					}
+					if !slices.Equal(found, tt.want) {
+						t.Errorf("LookupSources(%q) = %v, want %v", tt.code, found, tt.want)
+					}
+				}
+			}
+		})
+	}
+}
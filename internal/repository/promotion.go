@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+// InMemoryPromotionRepository implements PromotionRepository using in-memory storage
+type InMemoryPromotionRepository struct {
+	promotions map[string]models.Promotion
+	mutex      sync.RWMutex
+}
+
+// NewInMemoryPromotionRepository creates a new repository holding the given
+// promotions. A later promotion for the same code replaces an earlier one.
+func NewInMemoryPromotionRepository(promotions []models.Promotion) *InMemoryPromotionRepository {
+	repo := &InMemoryPromotionRepository{
+		promotions: make(map[string]models.Promotion, len(promotions)),
+	}
+	for _, promotion := range promotions {
+		repo.promotions[promotion.Code] = promotion
+	}
+	return repo
+}
+
+// DefaultPromotions returns the built-in promotions used when no promotions file is configured
+func DefaultPromotions() []models.Promotion {
+	return []models.Promotion{
+		{
+			Code: "HAPPYHOURS",
+			Discount: models.Discount{
+				Type:        models.DiscountPercentage,
+				Percentage:  18,
+				Description: "18% off the whole order",
+			},
+		},
+		{
+			Code: "BUYGETONE",
+			Discount: models.Discount{
+				Type:        models.DiscountFreeCheapestItem,
+				Description: "Cheapest item for free",
+			},
+		},
+	}
+}
+
+// FindByCode returns the promotion attached to a promo code
+func (r *InMemoryPromotionRepository) FindByCode(ctx context.Context, code string) (*models.Promotion, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	promotion, ok := r.promotions[code]
+	if !ok {
+		return nil, nil // Not found
+	}
+
+	// Return a copy to prevent data races
+	promotionCopy := promotion
+	return &promotionCopy, nil
+}
@@ -1,16 +1,82 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/jilani-go/glofox/internal/models"
 )
 
 // ProductRepository defines the interface for product data operations
 type ProductRepository interface {
-	FindAll() ([]models.Product, error)
-	FindByID(id string) (*models.Product, error)
+	FindAll(ctx context.Context) ([]models.Product, error)
+	FindByID(ctx context.Context, id string) (*models.Product, error)
+	// FindByIDs returns the products with the given IDs by ID, leaving out
+	// the IDs that do not exist
+	FindByIDs(ctx context.Context, ids []string) (map[string]models.Product, error)
+	// Create adds a product, generating an ID when none is set.
+	// It fails with ErrProductExists when the ID is already taken.
+	Create(ctx context.Context, product *models.Product) (*models.Product, error)
+	// Update replaces an existing product, returning nil if it does not exist
+	Update(ctx context.Context, product *models.Product) (*models.Product, error)
+	// Delete removes a product, reporting whether it existed
+	Delete(ctx context.Context, id string) (bool, error)
 }
 
 // OrderRepository defines the interface for order data operations
 type OrderRepository interface {
-	Create(order *models.Order) (*models.Order, error)
+	// Create adds an order. An order with a coupon code also records a
+	// redemption of the code, atomically with the order.
+	Create(ctx context.Context, order *models.Order) (*models.Order, error)
+	FindByID(ctx context.Context, id string) (*models.Order, error)
+	List(ctx context.Context, filter models.OrderFilter) ([]models.Order, int, error)
+	// UpdateStatus moves an order from one status to another, failing with
+	// ErrOrderStatusConflict if the order is no longer in the from status
+	UpdateStatus(ctx context.Context, id string, from, to models.OrderStatus, at time.Time) (*models.Order, error)
+	// CreateRedeeming adds an order with a coupon code like Create, after
+	// counting the code's redemptions and passing the count to check. The
+	// order is only created if check returns nil, whose error is returned
+	// otherwise. No other order, not even from another process, can redeem
+	// the code between the count and the creation.
+	CreateRedeeming(ctx context.Context, order *models.Order, check func(models.RedemptionCount) error) (*models.Order, error)
+	// CountRedemptions returns how often a promo code was redeemed in total and
+	// by one customer. Orders that were cancelled or refunded are not counted.
+	CountRedemptions(ctx context.Context, code, customerID string) (models.RedemptionCount, error)
+}
+
+// PromotionRepository defines the interface for looking up the discount attached to promo codes
+type PromotionRepository interface {
+	FindByCode(ctx context.Context, code string) (*models.Promotion, error)
+}
+
+// CampaignRepository defines the interface for looking up the campaign a promo code belongs to
+type CampaignRepository interface {
+	FindByCode(ctx context.Context, code string) (*models.Campaign, error)
+}
+
+// IdempotencyRepository defines the interface for storing requests made with an idempotency key
+type IdempotencyRepository interface {
+	// Reserve stores a new in-progress record unless the key already has an
+	// unexpired record, which is returned instead. It returns nil once reserved.
+	// In-progress records created before staleBefore are taken over.
+	Reserve(ctx context.Context, record models.IdempotencyRecord, staleBefore time.Time) (*models.IdempotencyRecord, error)
+	// Complete stores the response of a key reserved by owner. It fails with
+	// ErrIdempotencyKeyNotOwned once the key is held by another owner or gone.
+	Complete(ctx context.Context, key, owner string, statusCode int, body []byte) error
+	// Delete removes the record of a key reserved by owner so the key can be
+	// used again. It fails with ErrIdempotencyKeyNotOwned like Complete.
+	Delete(ctx context.Context, key, owner string) error
+	// DeleteExpired removes every record expired at the given time
+	DeleteExpired(ctx context.Context, at time.Time) (int, error)
+}
+
+// APIKeyRepository defines the interface for API key data operations
+type APIKeyRepository interface {
+	// FindByHash returns the key with the given hash, see models.HashAPIKey
+	FindByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	FindAll(ctx context.Context) ([]models.APIKey, error)
+	// Create adds a key. It fails with ErrAPIKeyExists when the ID or hash is taken.
+	Create(ctx context.Context, key *models.APIKey) (*models.APIKey, error)
+	// Revoke marks a key as revoked from the given time, reporting whether it exists
+	Revoke(ctx context.Context, id string, at time.Time) (bool, error)
 }
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/tracing"
+	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// openSQLiteDatabase opens (and creates if needed) the SQLite database at path
+// and verifies the connection is working
+func openSQLiteDatabase(databasePath string) (*sql.DB, error) {
+	// Ensure the directory exists
+	dbDir := filepath.Dir(databasePath)
+	if dbDir != "" && dbDir != "." {
+		if err := os.MkdirAll(dbDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create database directory: %w", err)
+		}
+	}
+
+	// Open database connection with explicit connection string parameters.
+	// Writers from other connections or processes are waited for instead of
+	// failing right away with "database is locked".
+	dbConnectionString := fmt.Sprintf("%s?_journal_mode=WAL&_synchronous=NORMAL&_cache_size=10000&_foreign_keys=ON&_busy_timeout=5000", databasePath)
+	return pingSQLiteDatabase(dbConnectionString)
+}
+
+// openSQLiteDatabaseReadOnly opens the existing SQLite database at path
+// for reading only; it is never created and every write fails
+func openSQLiteDatabaseReadOnly(databasePath string) (*sql.DB, error) {
+	if _, err := os.Stat(databasePath); err != nil {
+		return nil, err
+	}
+	return pingSQLiteDatabase(fmt.Sprintf("file:%s?mode=ro&_cache_size=10000&_busy_timeout=5000", databasePath))
+}
+
+// pingSQLiteDatabase opens a database by connection string and verifies
+// the connection is working
+func pingSQLiteDatabase(dbConnectionString string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", dbConnectionString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+
+	// Verify connection is working with ping
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping SQLite database: %w", err)
+	}
+
+	return db, nil
+}
+
+// withQueryTimeout bounds a database operation by timeout, on top of any
+// deadline ctx already carries. A zero timeout leaves ctx as it is.
+func withQueryTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// immediateTx runs fn in a BEGIN IMMEDIATE transaction on one connection
+// of db. The transaction takes the database's write lock up front, so what fn
+// reads cannot be changed by another connection or process before it commits.
+// The transaction is rolled back if fn fails.
+func immediateTx(ctx context.Context, db *sql.DB, fn func(conn *sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// Roll back even when ctx is done, so the connection goes back to the
+	// pool without an open transaction
+	if err := fn(conn); err != nil {
+		conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// startSpan starts the span of a database operation on table
+func startSpan(ctx context.Context, name, table string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, name,
+		attribute.String("db.system.name", "sqlite"),
+		attribute.String("db.collection.name", table))
+}
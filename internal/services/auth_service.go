@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/repository"
+)
+
+// Errors for AuthService
+var (
+	// ErrUnauthenticated means the API key is missing, unknown, expired or revoked
+	ErrUnauthenticated = errors.New("invalid or missing API key")
+	// ErrForbidden means the API key does not grant the required scope
+	ErrForbidden = errors.New("API key lacks the required scope")
+)
+
+// AuthService defines the interface for API key authentication
+type AuthService interface {
+	// Authenticate returns the active API key matching a key sent by a client.
+	// It fails with ErrUnauthenticated.
+	Authenticate(ctx context.Context, key string) (*models.APIKey, error)
+
+	// Authorize fails with ErrForbidden unless the key grants the scope
+	Authorize(key *models.APIKey, scope models.Scope) error
+}
+
+// AuthServiceImpl implements AuthService
+type AuthServiceImpl struct {
+	keyRepo repository.APIKeyRepository
+}
+
+// NewAuthService creates a new auth service
+func NewAuthService(keyRepo repository.APIKeyRepository) AuthService {
+	return &AuthServiceImpl{
+		keyRepo: keyRepo,
+	}
+}
+
+// Authenticate returns the active API key matching a key sent by a client
+func (s *AuthServiceImpl) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	if key == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	// Keys are looked up by hash, so no plain key is ever compared or stored
+	apiKey, err := s.keyRepo.FindByHash(ctx, models.HashAPIKey(key))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if apiKey == nil || apiKey.Expired(now) || apiKey.Revoked(now) {
+		return nil, ErrUnauthenticated
+	}
+	return apiKey, nil
+}
+
+// Authorize fails with ErrForbidden unless the key grants the scope
+func (s *AuthServiceImpl) Authorize(key *models.APIKey, scope models.Scope) error {
+	if key == nil || !key.HasScope(scope) {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"errors"
+	"math/bits"
+	"sort"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+// Errors for discount calculation
+var (
+	ErrInvalidDiscount = errors.New("invalid discount definition")
+)
+
+// pricedLine pairs an order item with the catalog product it refers to
+type pricedLine struct {
+	item    *models.OrderItem
+	product *models.Product
+}
+
+// applyDiscount sets the per-line discount of every eligible line.
+// Lines must already carry their unit price and line total.
+func applyDiscount(lines []pricedLine, discount models.Discount) error {
+	eligible := make([]pricedLine, 0, len(lines))
+	for _, line := range lines {
+		if discount.AppliesTo(*line.product) {
+			eligible = append(eligible, line)
+		}
+	}
+	if len(eligible) == 0 {
+		return nil
+	}
+
+	switch discount.Type {
+	case models.DiscountPercentage:
+		return applyPercentage(eligible, discount.Percentage)
+	case models.DiscountFixedAmount:
+		return applyFixedAmount(eligible, discount.Amount)
+	case models.DiscountFreeCheapestItem:
+		return applyFreeUnits(eligible, 1)
+	case models.DiscountBuyXGetY:
+		if discount.BuyQuantity < 1 || discount.GetQuantity < 1 {
+			return ErrInvalidDiscount
+		}
+		units := 0
+		for _, line := range eligible {
+			units += line.item.Quantity
+		}
+		free := units / (discount.BuyQuantity + discount.GetQuantity) * discount.GetQuantity
+		return applyFreeUnits(eligible, free)
+	default:
+		return ErrInvalidDiscount
+	}
+}
+
+// applyPercentage takes a percentage off every line
+func applyPercentage(lines []pricedLine, percent int) error {
+	if percent < 1 || percent > 100 {
+		return ErrInvalidDiscount
+	}
+	for _, line := range lines {
+		discount, err := line.item.LineTotal.Percentage(percent)
+		if err != nil {
+			return err
+		}
+		line.item.Discount = discount
+	}
+	return nil
+}
+
+// applyFixedAmount spreads a fixed amount over the lines in proportion to
+// their totals. The amount is capped at the sum of the lines.
+func applyFixedAmount(lines []pricedLine, amount models.Money) error {
+	if amount.IsNegative() || amount.IsZero() {
+		return ErrInvalidDiscount
+	}
+
+	eligibleTotal := models.NewMoney(0, lines[0].item.LineTotal.Currency)
+	for _, line := range lines {
+		var err error
+		if eligibleTotal, err = eligibleTotal.Add(line.item.LineTotal); err != nil {
+			return err
+		}
+	}
+	if !amount.SameCurrency(eligibleTotal) {
+		return models.ErrCurrencyMismatch
+	}
+	if eligibleTotal.IsZero() {
+		return nil
+	}
+	amount = amount.Min(eligibleTotal)
+
+	// Give every line its rounded-down share first
+	remaining := amount
+	for _, line := range lines {
+		share := proportion(amount.Amount, line.item.LineTotal.Amount, eligibleTotal.Amount)
+		line.item.Discount = models.NewMoney(share, amount.Currency)
+		remaining = remaining.Sub(line.item.Discount)
+	}
+
+	// Then hand out the leftover minor units to lines that still have room
+	for i := 0; remaining.Amount > 0; i = (i + 1) % len(lines) {
+		item := lines[i].item
+		if item.Discount.Amount < item.LineTotal.Amount {
+			item.Discount.Amount++
+			remaining.Amount--
+		}
+	}
+
+	return nil
+}
+
+// proportion returns amount * part / total rounded down. The product is taken
+// in 128 bits, so it cannot overflow while amount and part are at most total.
+func proportion(amount, part, total int64) int64 {
+	hi, lo := bits.Mul64(uint64(amount), uint64(part))
+	quotient, _ := bits.Div64(hi, lo, uint64(total))
+	return int64(quotient)
+}
+
+// applyFreeUnits makes the given number of units free, cheapest units first
+func applyFreeUnits(lines []pricedLine, free int) error {
+	sorted := make([]pricedLine, len(lines))
+	copy(sorted, lines)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].item.UnitPrice.Amount < sorted[j].item.UnitPrice.Amount
+	})
+
+	for _, line := range sorted {
+		if free <= 0 {
+			break
+		}
+		units := line.item.Quantity
+		if units > free {
+			units = free
+		}
+		discount, err := line.item.UnitPrice.Multiply(units)
+		if err != nil {
+			return err
+		}
+		line.item.Discount = discount
+		free -= units
+	}
+
+	return nil
+}
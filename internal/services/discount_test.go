@@ -0,0 +1,283 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/jilani-go/glofox/internal/models"
+)
+
+// line describes an order line for the discount tests
+type line struct {
+	category  string
+	unitPrice int64
+	quantity  int
+}
+
+// pricedLines builds priced lines in USD from line descriptions
+func pricedLines(lines []line) []pricedLine {
+	priced := make([]pricedLine, len(lines))
+	for i, l := range lines {
+		unitPrice := models.NewMoney(l.unitPrice, "USD")
+		priced[i] = pricedLine{
+			item: &models.OrderItem{
+				Quantity:  l.quantity,
+				UnitPrice: unitPrice,
+				LineTotal: models.NewMoney(l.unitPrice*int64(l.quantity), "USD"),
+			},
+			product: &models.Product{Category: l.category, Price: unitPrice},
+		}
+	}
+	return priced
+}
+
+// discounts returns the discount of every line in minor units
+func discounts(lines []pricedLine) []int64 {
+	amounts := make([]int64, len(lines))
+	for i, l := range lines {
+		amounts[i] = l.item.Discount.Amount
+	}
+	return amounts
+}
+
+// checkDiscounts compares the discounts of the lines with want
+func checkDiscounts(t *testing.T, lines []pricedLine, want []int64) {
+	t.Helper()
+	got := discounts(lines)
+	if len(got) != len(want) {
+		t.Fatalf("discounts = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("discounts = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestApplyDiscount(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []line
+		discount models.Discount
+		want     []int64
+		wantErr  error
+	}{
+		{
+			name:     "percentage on every line",
+			lines:    []line{{"Waffle", 1000, 1}, {"Drink", 250, 2}},
+			discount: models.Discount{Type: models.DiscountPercentage, Percentage: 18},
+			want:     []int64{180, 90},
+		},
+		{
+			name:     "percentage limited to a category",
+			lines:    []line{{"Waffle", 1000, 1}, {"Drink", 500, 1}},
+			discount: models.Discount{Type: models.DiscountPercentage, Percentage: 10, Category: "Waffle"},
+			want:     []int64{100, 0},
+		},
+		{
+			name:     "percentage of the largest amount",
+			lines:    []line{{"Waffle", math.MaxInt64, 1}},
+			discount: models.Discount{Type: models.DiscountPercentage, Percentage: 100},
+			want:     []int64{math.MaxInt64},
+		},
+		{
+			name:     "no eligible line",
+			lines:    []line{{"Drink", 500, 1}},
+			discount: models.Discount{Type: models.DiscountPercentage, Percentage: 10, Category: "Waffle"},
+			want:     []int64{0},
+		},
+		{
+			name:     "percentage out of range",
+			lines:    []line{{"Waffle", 1000, 1}},
+			discount: models.Discount{Type: models.DiscountPercentage, Percentage: 101},
+			want:     []int64{0},
+			wantErr:  ErrInvalidDiscount,
+		},
+		{
+			name:     "fixed amount",
+			lines:    []line{{"Waffle", 300, 1}, {"Drink", 100, 1}},
+			discount: models.Discount{Type: models.DiscountFixedAmount, Amount: models.NewMoney(100, "USD")},
+			want:     []int64{75, 25},
+		},
+		{
+			name:     "free cheapest item",
+			lines:    []line{{"Waffle", 500, 2}, {"Drink", 200, 3}},
+			discount: models.Discount{Type: models.DiscountFreeCheapestItem},
+			want:     []int64{0, 200},
+		},
+		{
+			name:     "buy two get one",
+			lines:    []line{{"Waffle", 300, 5}},
+			discount: models.Discount{Type: models.DiscountBuyXGetY, BuyQuantity: 2, GetQuantity: 1},
+			want:     []int64{300},
+		},
+		{
+			name:     "buy one get one across lines",
+			lines:    []line{{"Waffle", 500, 1}, {"Waffle", 300, 2}, {"Drink", 100, 4}},
+			discount: models.Discount{Type: models.DiscountBuyXGetY, BuyQuantity: 1, GetQuantity: 1, Category: "Waffle"},
+			want:     []int64{0, 300, 0},
+		},
+		{
+			name:     "buy x get y without quantities",
+			lines:    []line{{"Waffle", 300, 2}},
+			discount: models.Discount{Type: models.DiscountBuyXGetY},
+			want:     []int64{0},
+			wantErr:  ErrInvalidDiscount,
+		},
+		{
+			name:     "unknown type",
+			lines:    []line{{"Waffle", 300, 1}},
+			discount: models.Discount{Type: "bogus"},
+			want:     []int64{0},
+			wantErr:  ErrInvalidDiscount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := pricedLines(tt.lines)
+			err := applyDiscount(lines, tt.discount)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("applyDiscount() error = %v, want %v", err, tt.wantErr)
+			}
+			checkDiscounts(t, lines, tt.want)
+		})
+	}
+}
+
+func TestApplyFixedAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []line
+		amount  models.Money
+		want    []int64
+		wantErr error
+	}{
+		{
+			name:   "single line",
+			lines:  []line{{"Waffle", 650, 1}},
+			amount: models.NewMoney(500, "USD"),
+			want:   []int64{500},
+		},
+		{
+			name:   "proportional shares",
+			lines:  []line{{"Waffle", 300, 1}, {"Drink", 100, 1}},
+			amount: models.NewMoney(100, "USD"),
+			want:   []int64{75, 25},
+		},
+		{
+			name:   "leftover minor units",
+			lines:  []line{{"Waffle", 100, 1}, {"Waffle", 100, 1}, {"Waffle", 100, 1}},
+			amount: models.NewMoney(100, "USD"),
+			want:   []int64{34, 33, 33},
+		},
+		{
+			name:   "capped at the lines total",
+			lines:  []line{{"Waffle", 300, 1}, {"Drink", 100, 1}},
+			amount: models.NewMoney(1000, "USD"),
+			want:   []int64{300, 100},
+		},
+		{
+			name:   "free lines",
+			lines:  []line{{"Waffle", 0, 1}},
+			amount: models.NewMoney(100, "USD"),
+			want:   []int64{0},
+		},
+		{
+			name:   "large amounts",
+			lines:  []line{{"Waffle", 6_000_000_000_000_000, 1}, {"Drink", 2_000_000_000_000_000, 1}},
+			amount: models.NewMoney(4_000_000_000_000_000, "USD"),
+			want:   []int64{3_000_000_000_000_000, 1_000_000_000_000_000},
+		},
+		{
+			name:    "lines total overflows",
+			lines:   []line{{"Waffle", math.MaxInt64, 1}, {"Drink", 1, 1}},
+			amount:  models.NewMoney(100, "USD"),
+			want:    []int64{0, 0},
+			wantErr: models.ErrAmountOverflow,
+		},
+		{
+			name:    "other currency",
+			lines:   []line{{"Waffle", 300, 1}},
+			amount:  models.NewMoney(100, "EUR"),
+			want:    []int64{0},
+			wantErr: models.ErrCurrencyMismatch,
+		},
+		{
+			name:    "zero amount",
+			lines:   []line{{"Waffle", 300, 1}},
+			amount:  models.NewMoney(0, "USD"),
+			want:    []int64{0},
+			wantErr: ErrInvalidDiscount,
+		},
+		{
+			name:    "negative amount",
+			lines:   []line{{"Waffle", 300, 1}},
+			amount:  models.NewMoney(-100, "USD"),
+			want:    []int64{0},
+			wantErr: ErrInvalidDiscount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := pricedLines(tt.lines)
+			err := applyFixedAmount(lines, tt.amount)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("applyFixedAmount() error = %v, want %v", err, tt.wantErr)
+			}
+			checkDiscounts(t, lines, tt.want)
+		})
+	}
+}
+
+func TestApplyFreeUnits(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []line
+		free  int
+		want  []int64
+	}{
+		{
+			name:  "none free",
+			lines: []line{{"Waffle", 500, 2}},
+			free:  0,
+			want:  []int64{0},
+		},
+		{
+			name:  "cheapest unit first",
+			lines: []line{{"Waffle", 500, 2}, {"Drink", 200, 1}},
+			free:  1,
+			want:  []int64{0, 200},
+		},
+		{
+			name:  "spills over to the next cheapest line",
+			lines: []line{{"Waffle", 500, 2}, {"Drink", 200, 1}},
+			free:  2,
+			want:  []int64{500, 200},
+		},
+		{
+			name:  "more free units than ordered",
+			lines: []line{{"Waffle", 500, 2}, {"Drink", 200, 1}},
+			free:  5,
+			want:  []int64{1000, 200},
+		},
+		{
+			name:  "ties keep the order of the lines",
+			lines: []line{{"Waffle", 300, 1}, {"Cake", 300, 1}},
+			free:  1,
+			want:  []int64{300, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := pricedLines(tt.lines)
+			if err := applyFreeUnits(lines, tt.free); err != nil {
+				t.Fatalf("applyFreeUnits() unexpected error: %v", err)
+			}
+			checkDiscounts(t, lines, tt.want)
+		})
+	}
+}
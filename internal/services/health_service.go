@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/repository"
+)
+
+// HealthCheck is a dependency the service needs to handle requests
+type HealthCheck struct {
+	Name string
+	// Check returns nil when the dependency is usable
+	Check func(ctx context.Context) error
+}
+
+// HealthCheckResult is the outcome of a health check
+type HealthCheckResult struct {
+	Name string
+	// Error is why the check failed, empty if it passed
+	Error string
+}
+
+// ServiceStatus describes the state of the running service
+type ServiceStatus struct {
+	Ready     bool
+	StartedAt time.Time
+	Checks    []HealthCheckResult
+	// PromoSources is the load progress of every promo source
+	PromoSources []repository.PromoSourceProgress
+}
+
+// HealthService defines the interface for reporting the service's health
+type HealthService interface {
+	// Ready runs every check and reports whether they all passed
+	Ready(ctx context.Context) (bool, []HealthCheckResult)
+
+	// Status reports readiness together with the promo source load progress
+	Status(ctx context.Context) ServiceStatus
+}
+
+// HealthServiceImpl implements HealthService
+type HealthServiceImpl struct {
+	checks        []HealthCheck
+	promoProgress *repository.PromoLoadProgress
+	startedAt     time.Time
+}
+
+// NewHealthService creates a new health service. The service is ready when
+// every check passes; promoProgress tracks the promo sources being loaded.
+func NewHealthService(checks []HealthCheck, promoProgress *repository.PromoLoadProgress) HealthService {
+	return &HealthServiceImpl{
+		checks:        checks,
+		promoProgress: promoProgress,
+		startedAt:     time.Now(),
+	}
+}
+
+// Ready runs every check in order and reports whether they all passed
+func (s *HealthServiceImpl) Ready(ctx context.Context) (bool, []HealthCheckResult) {
+	ready := true
+	results := make([]HealthCheckResult, len(s.checks))
+	for i, check := range s.checks {
+		results[i].Name = check.Name
+		if err := check.Check(ctx); err != nil {
+			results[i].Error = err.Error()
+			ready = false
+		}
+	}
+	return ready, results
+}
+
+// Status reports readiness together with the promo source load progress
+func (s *HealthServiceImpl) Status(ctx context.Context) ServiceStatus {
+	ready, checks := s.Ready(ctx)
+	return ServiceStatus{
+		Ready:        ready,
+		StartedAt:    s.startedAt,
+		Checks:       checks,
+		PromoSources: s.promoProgress.Sources(),
+	}
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/repository"
+)
+
+// Errors for IdempotencyService
+var (
+	// ErrIdempotencyKeyReused means a key was sent again with a different payload
+	ErrIdempotencyKeyReused = errors.New("idempotency key was used with a different request")
+	// ErrIdempotencyKeyInProgress means the first request with a key has not finished yet
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is in progress")
+	// ErrIdempotencyKeyTakenOver means a request's key was taken over by a retry
+	// after the lock timed out, so the request can no longer settle it
+	ErrIdempotencyKeyTakenOver = errors.New("idempotency key was taken over by another request")
+)
+
+const (
+	// idempotencyLockTimeout is how long an unfinished request holds its key
+	idempotencyLockTimeout = time.Minute
+	// idempotencyPurgeInterval is how often expired keys are deleted
+	idempotencyPurgeInterval = time.Minute
+)
+
+// IdempotencyService defines the interface for replaying requests made with an idempotency key
+type IdempotencyService interface {
+	// Begin claims a key for a request identified by its fingerprint. It returns
+	// the owner token of the claim if the request should run, and the completed
+	// record to replay if the same request was made before. It fails with
+	// ErrIdempotencyKeyReused or ErrIdempotencyKeyInProgress.
+	Begin(ctx context.Context, key, fingerprint string) (owner string, replay *models.IdempotencyRecord, err error)
+
+	// Complete stores the response of a request claimed by Begin with owner.
+	// It fails with ErrIdempotencyKeyTakenOver if the claim was lost meanwhile.
+	Complete(ctx context.Context, key, owner string, statusCode int, body []byte) error
+
+	// Abandon releases a key claimed by Begin with owner without storing a
+	// response, so the request can be retried. It fails like Complete.
+	Abandon(ctx context.Context, key, owner string) error
+}
+
+// IdempotencyServiceImpl implements IdempotencyService
+type IdempotencyServiceImpl struct {
+	repo repository.IdempotencyRepository
+	ttl  time.Duration
+
+	purgeMutex sync.Mutex
+	lastPurge  time.Time
+}
+
+// NewIdempotencyService creates a new idempotency service keeping keys for ttl
+func NewIdempotencyService(repo repository.IdempotencyRepository, ttl time.Duration) IdempotencyService {
+	return &IdempotencyServiceImpl{
+		repo: repo,
+		ttl:  ttl,
+	}
+}
+
+// Begin claims a key for a request, or returns the response to replay
+func (s *IdempotencyServiceImpl) Begin(ctx context.Context, key, fingerprint string) (string, *models.IdempotencyRecord, error) {
+	now := time.Now().UTC()
+	s.purgeExpired(ctx, now)
+
+	record := models.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint,
+		Owner:       uuid.New().String(),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+	// A request unfinished after idempotencyLockTimeout is assumed lost
+	// (e.g. in a crash), so a retry takes its key over
+	existing, err := s.repo.Reserve(ctx, record, now.Add(-idempotencyLockTimeout))
+	if err != nil {
+		return "", nil, err
+	}
+	if existing == nil {
+		return record.Owner, nil, nil
+	}
+
+	if existing.Fingerprint != fingerprint {
+		return "", nil, ErrIdempotencyKeyReused
+	}
+	if !existing.Completed() {
+		return "", nil, ErrIdempotencyKeyInProgress
+	}
+	return "", existing, nil
+}
+
+// Complete stores the response of a claimed request
+func (s *IdempotencyServiceImpl) Complete(ctx context.Context, key, owner string, statusCode int, body []byte) error {
+	return mapIdempotencyError(s.repo.Complete(ctx, key, owner, statusCode, body))
+}
+
+// Abandon releases a claimed key without storing a response
+func (s *IdempotencyServiceImpl) Abandon(ctx context.Context, key, owner string) error {
+	return mapIdempotencyError(s.repo.Delete(ctx, key, owner))
+}
+
+// mapIdempotencyError maps a lost claim to ErrIdempotencyKeyTakenOver
+func mapIdempotencyError(err error) error {
+	if errors.Is(err, repository.ErrIdempotencyKeyNotOwned) {
+		return ErrIdempotencyKeyTakenOver
+	}
+	return err
+}
+
+// purgeExpired deletes expired keys, at most once per idempotencyPurgeInterval
+func (s *IdempotencyServiceImpl) purgeExpired(ctx context.Context, now time.Time) {
+	s.purgeMutex.Lock()
+	if now.Sub(s.lastPurge) < idempotencyPurgeInterval {
+		s.purgeMutex.Unlock()
+		return
+	}
+	s.lastPurge = now
+	s.purgeMutex.Unlock()
+
+	if deleted, err := s.repo.DeleteExpired(ctx, now); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete expired idempotency keys", "error", err)
+	} else if deleted > 0 {
+		slog.InfoContext(ctx, "Deleted expired idempotency keys", "count", deleted)
+	}
+}
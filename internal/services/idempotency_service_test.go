@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/repository"
+)
+
+func TestIdempotencyServiceBegin(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare claims the key before the request under test, and may settle it
+		prepare     func(t *testing.T, service IdempotencyService)
+		fingerprint string
+		wantReplay  bool
+		wantErr     error
+	}{
+		{
+			name:        "new key",
+			prepare:     func(t *testing.T, service IdempotencyService) {},
+			fingerprint: "a",
+		},
+		{
+			name: "replay completed request",
+			prepare: func(t *testing.T, service IdempotencyService) {
+				owner := begin(t, service, "a")
+				if err := service.Complete(context.Background(), "key", owner, http.StatusCreated, []byte(`{}`)); err != nil {
+					t.Fatalf("Complete() unexpected error: %v", err)
+				}
+			},
+			fingerprint: "a",
+			wantReplay:  true,
+		},
+		{
+			name: "reused with another payload",
+			prepare: func(t *testing.T, service IdempotencyService) {
+				owner := begin(t, service, "a")
+				if err := service.Complete(context.Background(), "key", owner, http.StatusCreated, []byte(`{}`)); err != nil {
+					t.Fatalf("Complete() unexpected error: %v", err)
+				}
+			},
+			fingerprint: "b",
+			wantErr:     ErrIdempotencyKeyReused,
+		},
+		{
+			name:        "in progress",
+			prepare:     func(t *testing.T, service IdempotencyService) { begin(t, service, "a") },
+			fingerprint: "a",
+			wantErr:     ErrIdempotencyKeyInProgress,
+		},
+		{
+			name: "abandoned",
+			prepare: func(t *testing.T, service IdempotencyService) {
+				owner := begin(t, service, "a")
+				if err := service.Abandon(context.Background(), "key", owner); err != nil {
+					t.Fatalf("Abandon() unexpected error: %v", err)
+				}
+			},
+			fingerprint: "b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewIdempotencyService(repository.NewInMemoryIdempotencyRepository(), time.Hour)
+			tt.prepare(t, service)
+
+			owner, replay, err := service.Begin(context.Background(), "key", tt.fingerprint)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Begin() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if tt.wantReplay {
+				if replay == nil || replay.StatusCode != http.StatusCreated || owner != "" {
+					t.Fatalf("Begin() = %q, %+v, want a replay of the stored response", owner, replay)
+				}
+				return
+			}
+			if replay != nil || owner == "" {
+				t.Fatalf("Begin() = %q, %+v, want a new claim", owner, replay)
+			}
+		})
+	}
+}
+
+func TestIdempotencyServiceTakenOver(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewInMemoryIdempotencyRepository()
+	service := NewIdempotencyService(repo, time.Hour)
+
+	// A claim older than the lock timeout is taken over by the next request
+	now := time.Now().UTC()
+	stale := models.IdempotencyRecord{
+		Key:         "key",
+		Fingerprint: "a",
+		Owner:       "stale",
+		CreatedAt:   now.Add(-2 * idempotencyLockTimeout),
+		ExpiresAt:   now.Add(time.Hour),
+	}
+	if _, err := repo.Reserve(ctx, stale, stale.CreatedAt); err != nil {
+		t.Fatalf("Reserve() unexpected error: %v", err)
+	}
+	owner, replay, err := service.Begin(ctx, "key", "a")
+	if err != nil || replay != nil || owner == "" {
+		t.Fatalf("Begin() = %q, %+v, %v, want the stale key taken over", owner, replay, err)
+	}
+
+	tests := []struct {
+		name   string
+		settle func() error
+	}{
+		{"complete", func() error { return service.Complete(ctx, "key", "stale", http.StatusCreated, nil) }},
+		{"abandon", func() error { return service.Abandon(ctx, "key", "stale") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.settle(); !errors.Is(err, ErrIdempotencyKeyTakenOver) {
+				t.Fatalf("%s by the stale owner error = %v, want %v", tt.name, err, ErrIdempotencyKeyTakenOver)
+			}
+		})
+	}
+
+	// The new owner still holds the key
+	if err := service.Complete(ctx, "key", owner, http.StatusCreated, []byte(`{}`)); err != nil {
+		t.Fatalf("Complete() by the new owner unexpected error: %v", err)
+	}
+}
+
+// begin claims "key" with the given fingerprint and returns the owner token
+func begin(t *testing.T, service IdempotencyService, fingerprint string) string {
+	t.Helper()
+	owner, replay, err := service.Begin(context.Background(), "key", fingerprint)
+	if err != nil || replay != nil {
+		t.Fatalf("Begin() = %+v, %v, want a new claim", replay, err)
+	}
+	return owner
+}
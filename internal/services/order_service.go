@@ -1,63 +1,388 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"time"
 
+	"github.com/jilani-go/glofox/internal/metrics"
 	"github.com/jilani-go/glofox/internal/models"
 	"github.com/jilani-go/glofox/internal/repository"
+	"github.com/jilani-go/glofox/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Custom errors
 var (
-	ErrProductNotFound = errors.New("one or more products not found")
+	ErrProductNotFound    = errors.New("one or more products not found")
+	ErrInvalidOrderFilter = errors.New("invalid order filter")
+	ErrOrderNotFound      = errors.New("order not found")
+	ErrInvalidOrderStatus = errors.New("unknown order status")
+	ErrInvalidTransition  = errors.New("order status transition not allowed")
+	ErrOrderStatusChanged = errors.New("order status was changed concurrently")
+	// ErrPromoCodeExhausted means the coupon reached its total redemption limit
+	ErrPromoCodeExhausted = errors.New("promo code has been fully redeemed")
+	// ErrCustomerLimitReached means the customer reached the coupon's per-customer limit
+	ErrCustomerLimitReached = errors.New("promo code redemption limit reached for this customer")
+	// ErrCustomerRequired means the coupon has a per-customer limit but no customer was given
+	ErrCustomerRequired = errors.New("promo code requires a customer ID")
+)
+
+// orderTransitions lists, for every status, the statuses an order may move to next.
+// Completed orders can only be refunded; cancelled orders may still be refunded
+// when the customer already paid; refunded orders are final.
+var orderTransitions = map[models.OrderStatus][]models.OrderStatus{
+	models.OrderStatusPlaced:    {models.OrderStatusAccepted, models.OrderStatusCancelled},
+	models.OrderStatusAccepted:  {models.OrderStatusPreparing, models.OrderStatusCancelled},
+	models.OrderStatusPreparing: {models.OrderStatusReady, models.OrderStatusCancelled},
+	models.OrderStatusReady:     {models.OrderStatusCompleted, models.OrderStatusCancelled},
+	models.OrderStatusCompleted: {models.OrderStatusRefunded},
+	models.OrderStatusCancelled: {models.OrderStatusRefunded},
+	models.OrderStatusRefunded:  {},
+}
+
+// CanTransition reports whether an order may move from one status to another
+func CanTransition(from, to models.OrderStatus) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Paging limits for order listings
+const (
+	// DefaultOrderPageSize is used when no limit is requested
+	DefaultOrderPageSize = 20
+	// MaxOrderPageSize caps the number of orders returned at once
+	MaxOrderPageSize = 100
 )
 
 // OrderService defines the interface for order business logic
 type OrderService interface {
-	// CreateOrder validates and creates a new order
-	CreateOrder(order *models.Order) (*models.Order, error)
-	
+	// CreateOrder validates, prices and creates a new order.
+	// A rejected coupon fails with a *PromoRejectedError.
+	CreateOrder(ctx context.Context, order *models.Order) (*models.Order, error)
+
+	// QuoteOrder validates and prices an order like CreateOrder, including the
+	// coupon's redemption limits, but stores nothing
+	QuoteOrder(ctx context.Context, order *models.Order) (*models.Order, error)
+
+	// PreviewPromoCode validates a promo code without placing an order. With
+	// items, the code is checked against the cart and the cart is priced
+	// with the code's discount if it is valid.
+	PreviewPromoCode(ctx context.Context, code string, items []models.OrderItem) (*PromoPreview, error)
+
 	// ValidateOrderItems checks if all products in the order exist
-	ValidateOrderItems(items []models.OrderItem) error
+	ValidateOrderItems(ctx context.Context, items []models.OrderItem) error
+
+	// GetOrder returns an order by its ID, or nil if it does not exist
+	GetOrder(ctx context.Context, id string) (*models.Order, error)
+
+	// ListOrders returns a page of orders matching the filter and the total number of matches
+	ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.Order, int, error)
+
+	// TransitionOrder moves an order to a new lifecycle status
+	TransitionOrder(ctx context.Context, id string, status models.OrderStatus) (*models.Order, error)
+}
+
+// PromoPreview is the outcome of validating a promo code before ordering
+type PromoPreview struct {
+	PromoValidation
+	// Promotion is the discount the code grants, nil if it is invalid or grants none
+	Promotion *models.Promotion
+	// Order is the priced cart, nil when no items were given
+	Order *models.Order
 }
 
 // OrderServiceImpl implements OrderService
 type OrderServiceImpl struct {
-	orderRepo   repository.OrderRepository
-	productRepo repository.ProductRepository
+	orderRepo      repository.OrderRepository
+	productRepo    repository.ProductRepository
+	pricingService PricingService
+	promoService   PromoService
 }
 
 // NewOrderService creates a new order service
-func NewOrderService(orderRepo repository.OrderRepository, productRepo repository.ProductRepository) OrderService {
+func NewOrderService(orderRepo repository.OrderRepository, productRepo repository.ProductRepository, pricingService PricingService, promoService PromoService) OrderService {
 	return &OrderServiceImpl{
-		orderRepo:   orderRepo,
-		productRepo: productRepo,
+		orderRepo:      orderRepo,
+		productRepo:    productRepo,
+		pricingService: pricingService,
+		promoService:   promoService,
+	}
+}
+
+// CreateOrder validates, prices and creates a new order
+func (s *OrderServiceImpl) CreateOrder(ctx context.Context, order *models.Order) (_ *models.Order, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.CreateOrder", orderAttributes(order)...)
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.prepareOrder(ctx, order); err != nil {
+		return nil, err
+	}
+
+	// Every order starts its lifecycle as placed
+	now := time.Now().UTC()
+	order.CreatedAt = now
+	order.Status = models.OrderStatusPlaced
+	order.StatusHistory = []models.StatusChange{{Status: models.OrderStatusPlaced, At: now}}
+
+	created, err := s.createOrder(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	metrics.ObserveOrderCreated(created)
+	return created, nil
+}
+
+// createOrder stores a prepared order, redeeming its coupon
+func (s *OrderServiceImpl) createOrder(ctx context.Context, order *models.Order) (*models.Order, error) {
+	if order.CouponCode == "" {
+		return s.orderRepo.Create(ctx, order)
+	}
+
+	limits, err := s.promoService.RedemptionLimits(ctx, order.CouponCode)
+	if err != nil {
+		return nil, err
+	}
+	if limits.IsZero() {
+		return s.orderRepo.Create(ctx, order)
+	}
+	if limits.MaxPerCustomer > 0 && order.CustomerID == "" {
+		return nil, ErrCustomerRequired
 	}
+
+	// The repository counts the redemptions and creates the order atomically,
+	// so concurrent orders cannot both take the last redemption
+	return s.orderRepo.CreateRedeeming(ctx, order, func(count models.RedemptionCount) error {
+		return checkRedemptionCount(limits, count)
+	})
 }
 
-// CreateOrder validates and creates a new order
-func (s *OrderServiceImpl) CreateOrder(order *models.Order) (*models.Order, error) {
+// QuoteOrder validates and prices an order without storing it.
+// The redemption limits are checked without reserving a redemption, so
+// placing the quoted order can still fail when another order takes it first.
+func (s *OrderServiceImpl) QuoteOrder(ctx context.Context, order *models.Order) (_ *models.Order, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.QuoteOrder", orderAttributes(order)...)
+	defer func() { tracing.End(span, err) }()
+
+	if err := s.prepareOrder(ctx, order); err != nil {
+		return nil, err
+	}
+	if order.CouponCode == "" {
+		return order, nil
+	}
+
+	limits, err := s.promoService.RedemptionLimits(ctx, order.CouponCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRedemptionLimits(ctx, order.CouponCode, order.CustomerID, limits); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// prepareOrder validates the items and coupon of an order and prices it
+func (s *OrderServiceImpl) prepareOrder(ctx context.Context, order *models.Order) error {
 	// First validate all order items exist
-	if err := s.ValidateOrderItems(order.Items); err != nil {
+	if err := s.ValidateOrderItems(ctx, order.Items); err != nil {
+		return err
+	}
+
+	// Look up what the coupon, if any, takes off the order and which
+	// products its campaign limits the discount to
+	promotion, err := s.promoService.GetPromotion(ctx, order.CouponCode)
+	if err != nil {
+		return err
+	}
+	campaign, err := s.promoService.GetCampaign(ctx, order.CouponCode)
+	if err != nil {
+		return err
+	}
+
+	// Price the order from the current catalog
+	if err := s.pricingService.PriceOrder(ctx, order, promotion, campaign); err != nil {
+		return err
+	}
+
+	// Check the coupon against the promo sources and its campaign,
+	// which needs the priced order for the minimum order value
+	validation, err := s.promoService.ValidatePromoCode(ctx, order.CouponCode, order)
+	if err != nil {
+		return err
+	}
+	if !validation.Valid {
+		return &PromoRejectedError{Reason: validation.Reason}
+	}
+	return nil
+}
+
+// PreviewPromoCode validates a promo code and, with items, prices the cart.
+// Redemption limits are only checked when an order is placed.
+func (s *OrderServiceImpl) PreviewPromoCode(ctx context.Context, code string, items []models.OrderItem) (_ *PromoPreview, err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.PreviewPromoCode", attribute.Int("order.item_count", len(items)))
+	defer func() { tracing.End(span, err) }()
+
+	if len(items) == 0 {
+		validation, err := s.promoService.ValidatePromoCode(ctx, code, nil)
+		if err != nil {
+			return nil, err
+		}
+		preview := &PromoPreview{PromoValidation: *validation}
+		if validation.Valid {
+			if preview.Promotion, err = s.promoService.GetPromotion(ctx, code); err != nil {
+				return nil, err
+			}
+		}
+		return preview, nil
+	}
+
+	if err := s.ValidateOrderItems(ctx, items); err != nil {
+		return nil, err
+	}
+	order := &models.Order{
+		Items:      append([]models.OrderItem(nil), items...),
+		CouponCode: code,
+	}
+	promotion, err := s.promoService.GetPromotion(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	campaign, err := s.promoService.GetCampaign(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.pricingService.PriceOrder(ctx, order, promotion, campaign); err != nil {
+		return nil, err
+	}
+
+	validation, err := s.promoService.ValidatePromoCode(ctx, code, order)
+	if err != nil {
 		return nil, err
 	}
-	
-	// Then create the order
-	return s.orderRepo.Create(order)
+	preview := &PromoPreview{PromoValidation: *validation, Order: order}
+	if validation.Valid {
+		preview.Promotion = promotion
+	} else if promotion != nil {
+		// Show what the cart costs without the rejected code
+		if err := s.pricingService.PriceOrder(ctx, order, nil, nil); err != nil {
+			return nil, err
+		}
+	}
+	return preview, nil
+}
+
+// checkRedemptionLimits fails if redeeming the code once more would exceed its limits
+func (s *OrderServiceImpl) checkRedemptionLimits(ctx context.Context, code, customerID string, limits models.RedemptionLimits) error {
+	if limits.IsZero() {
+		return nil
+	}
+	if limits.MaxPerCustomer > 0 && customerID == "" {
+		return ErrCustomerRequired
+	}
+
+	count, err := s.orderRepo.CountRedemptions(ctx, code, customerID)
+	if err != nil {
+		return err
+	}
+	return checkRedemptionCount(limits, count)
+}
+
+// checkRedemptionCount fails if one more redemption on top of count would exceed limits
+func checkRedemptionCount(limits models.RedemptionLimits, count models.RedemptionCount) error {
+	if global := limits.GlobalLimit(); global > 0 && count.Total >= global {
+		return ErrPromoCodeExhausted
+	}
+	if limits.MaxPerCustomer > 0 && count.Customer >= limits.MaxPerCustomer {
+		return ErrCustomerLimitReached
+	}
+	return nil
 }
 
 // ValidateOrderItems checks if all products in the order exist
-func (s *OrderServiceImpl) ValidateOrderItems(items []models.OrderItem) error {
+func (s *OrderServiceImpl) ValidateOrderItems(ctx context.Context, items []models.OrderItem) (err error) {
+	ctx, span := tracing.Start(ctx, "OrderService.ValidateOrderItems", attribute.Int("order.item_count", len(items)))
+	defer func() { tracing.End(span, err) }()
+
 	for _, item := range items {
-		product, err := s.productRepo.FindByID(item.ProductID)
+		product, err := s.productRepo.FindByID(ctx, item.ProductID)
 		if err != nil {
 			return err
 		}
-		
+
 		if product == nil {
 			return ErrProductNotFound
 		}
 	}
-	
+
 	return nil
 }
+
+// orderAttributes describes an order on its spans
+func orderAttributes(order *models.Order) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.Int("order.item_count", len(order.Items)),
+		attribute.Bool("order.has_coupon", order.CouponCode != ""),
+	}
+}
+
+// GetOrder returns an order by its ID, or nil if it does not exist
+func (s *OrderServiceImpl) GetOrder(ctx context.Context, id string) (*models.Order, error) {
+	return s.orderRepo.FindByID(ctx, id)
+}
+
+// ListOrders returns a page of orders matching the filter and the total number of matches.
+// The page size defaults to DefaultOrderPageSize and is capped at MaxOrderPageSize.
+func (s *OrderServiceImpl) ListOrders(ctx context.Context, filter models.OrderFilter) ([]models.Order, int, error) {
+	if filter.Offset < 0 || filter.Limit < 0 {
+		return nil, 0, ErrInvalidOrderFilter
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, 0, ErrInvalidOrderFilter
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = DefaultOrderPageSize
+	}
+	if filter.Limit > MaxOrderPageSize {
+		filter.Limit = MaxOrderPageSize
+	}
+
+	return s.orderRepo.List(ctx, filter)
+}
+
+// TransitionOrder moves an order to a new lifecycle status.
+// It fails with ErrInvalidTransition when the move is not in the transition table
+// and with ErrOrderStatusChanged when the order changed concurrently.
+func (s *OrderServiceImpl) TransitionOrder(ctx context.Context, id string, status models.OrderStatus) (*models.Order, error) {
+	if _, known := orderTransitions[status]; !known {
+		return nil, ErrInvalidOrderStatus
+	}
+
+	order, err := s.orderRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+
+	if !CanTransition(order.Status, status) {
+		return nil, ErrInvalidTransition
+	}
+
+	updated, err := s.orderRepo.UpdateStatus(ctx, id, order.Status, status, time.Now().UTC())
+	if errors.Is(err, repository.ErrOrderStatusConflict) {
+		return nil, ErrOrderStatusChanged
+	} else if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrOrderNotFound
+	}
+
+	return updated, nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/repository"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from models.OrderStatus
+		to   models.OrderStatus
+		want bool
+	}{
+		{models.OrderStatusPlaced, models.OrderStatusAccepted, true},
+		{models.OrderStatusPlaced, models.OrderStatusCancelled, true},
+		{models.OrderStatusPlaced, models.OrderStatusPreparing, false},
+		{models.OrderStatusPlaced, models.OrderStatusCompleted, false},
+		{models.OrderStatusPlaced, models.OrderStatusRefunded, false},
+		{models.OrderStatusAccepted, models.OrderStatusPreparing, true},
+		{models.OrderStatusAccepted, models.OrderStatusCancelled, true},
+		{models.OrderStatusAccepted, models.OrderStatusPlaced, false},
+		{models.OrderStatusPreparing, models.OrderStatusReady, true},
+		{models.OrderStatusPreparing, models.OrderStatusCancelled, true},
+		{models.OrderStatusReady, models.OrderStatusCompleted, true},
+		{models.OrderStatusReady, models.OrderStatusCancelled, true},
+		{models.OrderStatusReady, models.OrderStatusRefunded, false},
+		{models.OrderStatusCompleted, models.OrderStatusRefunded, true},
+		{models.OrderStatusCompleted, models.OrderStatusCancelled, false},
+		{models.OrderStatusCancelled, models.OrderStatusRefunded, true},
+		{models.OrderStatusCancelled, models.OrderStatusPlaced, false},
+		{models.OrderStatusRefunded, models.OrderStatusPlaced, false},
+		{models.OrderStatusRefunded, models.OrderStatusCancelled, false},
+		{models.OrderStatusPlaced, models.OrderStatusPlaced, false},
+		{"unknown", models.OrderStatusAccepted, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.from)+"->"+string(tt.to), func(t *testing.T) {
+			if got := CanTransition(tt.from, tt.to); got != tt.want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransitionOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  models.OrderStatus
+		to      models.OrderStatus
+		wantErr error
+	}{
+		{name: "accept", status: models.OrderStatusPlaced, to: models.OrderStatusAccepted},
+		{name: "cancel", status: models.OrderStatusPreparing, to: models.OrderStatusCancelled},
+		{name: "refund", status: models.OrderStatusCompleted, to: models.OrderStatusRefunded},
+		{name: "skip a step", status: models.OrderStatusPlaced, to: models.OrderStatusReady, wantErr: ErrInvalidTransition},
+		{name: "leave refunded", status: models.OrderStatusRefunded, to: models.OrderStatusPlaced, wantErr: ErrInvalidTransition},
+		{name: "unknown status", status: models.OrderStatusPlaced, to: "shipped", wantErr: ErrInvalidOrderStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := repository.NewInMemoryOrderRepository(nil)
+			order, err := repo.Create(ctx, &models.Order{Status: tt.status})
+			if err != nil {
+				t.Fatalf("Create() unexpected error: %v", err)
+			}
+			service := NewOrderService(repo, nil, nil, nil)
+
+			updated, err := service.TransitionOrder(ctx, order.ID, tt.to)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TransitionOrder() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if updated.Status != tt.to {
+				t.Errorf("TransitionOrder() status = %s, want %s", updated.Status, tt.to)
+			}
+			if n := len(updated.StatusHistory); n == 0 || updated.StatusHistory[n-1].Status != tt.to {
+				t.Errorf("TransitionOrder() history = %v, want it to end with %s", updated.StatusHistory, tt.to)
+			}
+		})
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		service := NewOrderService(repository.NewInMemoryOrderRepository(nil), nil, nil, nil)
+		if _, err := service.TransitionOrder(context.Background(), "missing", models.OrderStatusAccepted); !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("TransitionOrder() error = %v, want %v", err, ErrOrderNotFound)
+		}
+	})
+}
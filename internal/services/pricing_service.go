@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/repository"
+	"github.com/jilani-go/glofox/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PricingService defines the interface for order pricing logic
+type PricingService interface {
+	// PriceOrder fills in line totals, discounts and totals of an order,
+	// applying the promotion when one is given. A campaign limits the
+	// discount to the campaign's products, nil means every product.
+	PriceOrder(ctx context.Context, order *models.Order, promotion *models.Promotion, campaign *models.Campaign) error
+}
+
+// PricingServiceImpl implements PricingService using catalog prices
+type PricingServiceImpl struct {
+	productRepo repository.ProductRepository
+}
+
+// NewPricingService creates a new pricing service
+func NewPricingService(productRepo repository.ProductRepository) PricingService {
+	return &PricingServiceImpl{
+		productRepo: productRepo,
+	}
+}
+
+// PriceOrder fills in line totals, discounts and totals of an order.
+// Unit prices are always taken from the catalog, never from the caller.
+// All products of an order must be priced in the same currency. Amounts too
+// large to represent fail with models.ErrAmountOverflow.
+func (s *PricingServiceImpl) PriceOrder(ctx context.Context, order *models.Order, promotion *models.Promotion, campaign *models.Campaign) (err error) {
+	ctx, span := tracing.Start(ctx, "PricingService.PriceOrder",
+		attribute.Int("order.item_count", len(order.Items)),
+		attribute.Bool("order.has_promotion", promotion != nil))
+	defer func() { tracing.End(span, err) }()
+
+	var subtotal models.Money
+	lines := make([]pricedLine, 0, len(order.Items))
+	for i := range order.Items {
+		item := &order.Items[i]
+
+		product, err := s.productRepo.FindByID(ctx, item.ProductID)
+		if err != nil {
+			return err
+		}
+		if product == nil {
+			return ErrProductNotFound
+		}
+
+		// The first line decides the currency of the order
+		if i == 0 {
+			subtotal = models.NewMoney(0, product.Price.Currency)
+		} else if !subtotal.SameCurrency(product.Price) {
+			return models.ErrCurrencyMismatch
+		}
+
+		item.UnitPrice = product.Price
+		if item.LineTotal, err = product.Price.Multiply(item.Quantity); err != nil {
+			return err
+		}
+		item.Discount = models.NewMoney(0, product.Price.Currency)
+		if subtotal, err = subtotal.Add(item.LineTotal); err != nil {
+			return err
+		}
+		// Only the campaign's products are discounted
+		if campaign == nil || campaign.AppliesTo(*product) {
+			lines = append(lines, pricedLine{item: item, product: product})
+		}
+	}
+
+	// Work out the discount of every line
+	order.Promotion = promotion
+	if promotion != nil {
+		if err := applyDiscount(lines, promotion.Discount); err != nil {
+			return err
+		}
+	}
+
+	discount := models.NewMoney(0, subtotal.Currency)
+	for i := range order.Items {
+		item := &order.Items[i]
+		item.Total = item.LineTotal.Sub(item.Discount)
+		if discount, err = discount.Add(item.Discount); err != nil {
+			return err
+		}
+	}
+
+	order.Subtotal = subtotal
+	order.Discount = discount
+	order.Total = order.Subtotal.Sub(order.Discount)
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/repository"
+)
+
+func TestPriceOrder(t *testing.T) {
+	ctx := context.Background()
+	productRepo := repository.NewInMemoryProductRepository(false)
+	for _, product := range []models.Product{
+		{ID: "waffle", Name: "Waffle", Price: models.NewMoney(650, "USD"), Category: "Waffle"},
+		{ID: "tea", Name: "Tea", Price: models.NewMoney(250, "USD"), Category: "Drink"},
+		{ID: "yacht", Name: "Yacht", Price: models.NewMoney(math.MaxInt64/2, "USD"), Category: "Boat"},
+		{ID: "matcha", Name: "Matcha", Price: models.NewMoney(500, "JPY"), Category: "Drink"},
+	} {
+		if _, err := productRepo.Create(ctx, &product); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		items     []models.OrderItem
+		promotion *models.Promotion
+		campaign  *models.Campaign
+		// wantTotals are the subtotal, discount and total in minor units
+		wantTotals [3]int64
+		wantErr    error
+	}{
+		{
+			name:       "catalog prices",
+			items:      []models.OrderItem{{ProductID: "waffle", Quantity: 2}, {ProductID: "tea", Quantity: 1}},
+			wantTotals: [3]int64{1550, 0, 1550},
+		},
+		{
+			name:  "with a promotion",
+			items: []models.OrderItem{{ProductID: "waffle", Quantity: 2}, {ProductID: "tea", Quantity: 1}},
+			promotion: &models.Promotion{
+				Discount: models.Discount{Type: models.DiscountPercentage, Percentage: 10, Category: "Waffle"},
+			},
+			wantTotals: [3]int64{1550, 130, 1420},
+		},
+		{
+			name:  "campaign limits the discount",
+			items: []models.OrderItem{{ProductID: "waffle", Quantity: 2}, {ProductID: "tea", Quantity: 1}},
+			promotion: &models.Promotion{
+				Discount: models.Discount{Type: models.DiscountPercentage, Percentage: 10},
+			},
+			campaign:   &models.Campaign{Categories: []string{"Drink"}},
+			wantTotals: [3]int64{1550, 25, 1525},
+		},
+		{
+			name:  "campaign and discount scopes combine",
+			items: []models.OrderItem{{ProductID: "waffle", Quantity: 2}, {ProductID: "tea", Quantity: 1}},
+			promotion: &models.Promotion{
+				Discount: models.Discount{Type: models.DiscountFixedAmount, Amount: models.NewMoney(500, "USD"), Category: "Waffle"},
+			},
+			campaign:   &models.Campaign{ProductIDs: []string{"tea"}},
+			wantTotals: [3]int64{1550, 0, 1550},
+		},
+		{
+			name:  "fixed amount spread over the campaign's products",
+			items: []models.OrderItem{{ProductID: "waffle", Quantity: 2}, {ProductID: "tea", Quantity: 1}},
+			promotion: &models.Promotion{
+				Discount: models.Discount{Type: models.DiscountFixedAmount, Amount: models.NewMoney(500, "USD")},
+			},
+			campaign:   &models.Campaign{ProductIDs: []string{"tea"}},
+			wantTotals: [3]int64{1550, 250, 1300},
+		},
+		{
+			name:    "unknown product",
+			items:   []models.OrderItem{{ProductID: "scone", Quantity: 1}},
+			wantErr: ErrProductNotFound,
+		},
+		{
+			name:    "mixed currencies",
+			items:   []models.OrderItem{{ProductID: "waffle", Quantity: 1}, {ProductID: "matcha", Quantity: 1}},
+			wantErr: models.ErrCurrencyMismatch,
+		},
+		{
+			name:    "line total overflows",
+			items:   []models.OrderItem{{ProductID: "yacht", Quantity: 3}},
+			wantErr: models.ErrAmountOverflow,
+		},
+		{
+			name:    "subtotal overflows",
+			items:   []models.OrderItem{{ProductID: "yacht", Quantity: 2}, {ProductID: "waffle", Quantity: 1}},
+			wantErr: models.ErrAmountOverflow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := &models.Order{Items: tt.items}
+			err := NewPricingService(productRepo).PriceOrder(ctx, order, tt.promotion, tt.campaign)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("PriceOrder() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			got := [3]int64{order.Subtotal.Amount, order.Discount.Amount, order.Total.Amount}
+			if got != tt.wantTotals {
+				t.Errorf("PriceOrder() subtotal, discount, total = %v, want %v", got, tt.wantTotals)
+			}
+		})
+	}
+}
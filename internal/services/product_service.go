@@ -1,17 +1,40 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/jilani-go/glofox/internal/models"
 	"github.com/jilani-go/glofox/internal/repository"
 )
 
+// Errors for ProductService
+var (
+	ErrProductExists       = errors.New("product already exists")
+	ErrProductDoesNotExist = errors.New("product not found")
+)
+
 // ProductService defines the interface for product business logic
 type ProductService interface {
 	// GetAllProducts returns all available products
-	GetAllProducts() ([]models.Product, error)
-	
+	GetAllProducts(ctx context.Context) ([]models.Product, error)
+
 	// GetProductByID returns a product by its ID
-	GetProductByID(id string) (*models.Product, error)
+	GetProductByID(ctx context.Context, id string) (*models.Product, error)
+
+	// GetProductsByIDs returns the products with the given IDs by ID,
+	// leaving out the IDs that do not exist
+	GetProductsByIDs(ctx context.Context, ids []string) (map[string]models.Product, error)
+
+	// CreateProduct adds a product to the catalog
+	CreateProduct(ctx context.Context, product *models.Product) (*models.Product, error)
+
+	// UpdateProduct replaces the details of a catalog product
+	UpdateProduct(ctx context.Context, product *models.Product) (*models.Product, error)
+
+	// DeleteProduct removes a product from the catalog
+	DeleteProduct(ctx context.Context, id string) error
 }
 
 // ProductServiceImpl implements ProductService
@@ -27,11 +50,65 @@ func NewProductService(repo repository.ProductRepository) ProductService {
 }
 
 // GetAllProducts returns all available products
-func (s *ProductServiceImpl) GetAllProducts() ([]models.Product, error) {
-	return s.repo.FindAll()
+func (s *ProductServiceImpl) GetAllProducts(ctx context.Context) ([]models.Product, error) {
+	return s.repo.FindAll(ctx)
 }
 
 // GetProductByID returns a product by its ID
-func (s *ProductServiceImpl) GetProductByID(id string) (*models.Product, error) {
-	return s.repo.FindByID(id)
+func (s *ProductServiceImpl) GetProductByID(ctx context.Context, id string) (*models.Product, error) {
+	return s.repo.FindByID(ctx, id)
+}
+
+// GetProductsByIDs returns the products with the given IDs by ID
+func (s *ProductServiceImpl) GetProductsByIDs(ctx context.Context, ids []string) (map[string]models.Product, error) {
+	return s.repo.FindByIDs(ctx, ids)
+}
+
+// CreateProduct adds a product to the catalog and stamps its creation time
+func (s *ProductServiceImpl) CreateProduct(ctx context.Context, product *models.Product) (*models.Product, error) {
+	now := time.Now().UTC()
+	product.CreatedAt = now
+	product.UpdatedAt = now
+
+	created, err := s.repo.Create(ctx, product)
+	if errors.Is(err, repository.ErrProductExists) {
+		return nil, ErrProductExists
+	}
+	return created, err
+}
+
+// UpdateProduct replaces the details of a catalog product.
+// The creation time is kept and the update time is refreshed.
+func (s *ProductServiceImpl) UpdateProduct(ctx context.Context, product *models.Product) (*models.Product, error) {
+	existing, err := s.repo.FindByID(ctx, product.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrProductDoesNotExist
+	}
+
+	product.CreatedAt = existing.CreatedAt
+	product.UpdatedAt = time.Now().UTC()
+
+	updated, err := s.repo.Update(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrProductDoesNotExist
+	}
+	return updated, nil
+}
+
+// DeleteProduct removes a product from the catalog
+func (s *ProductServiceImpl) DeleteProduct(ctx context.Context, id string) error {
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrProductDoesNotExist
+	}
+	return nil
 }
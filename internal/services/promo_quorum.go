@@ -0,0 +1,74 @@
+package services
+
+// QuorumMode selects how matches in several promo sources are combined
+type QuorumMode string
+
+// Supported quorum modes
+const (
+	// QuorumNOfM requires a code in at least Required sources
+	QuorumNOfM QuorumMode = "n_of_m"
+	// QuorumAll requires a code in every source
+	QuorumAll QuorumMode = "all"
+	// QuorumAny requires a code in at least one source
+	QuorumAny QuorumMode = "any"
+	// QuorumWeighted requires the weights of the matching sources to add up to Required
+	QuorumWeighted QuorumMode = "weighted"
+)
+
+// PromoQuorum is the rule a promo code must satisfy across the promo sources
+type PromoQuorum struct {
+	Mode QuorumMode
+	// Required is the number of sources (n_of_m) or the total weight (weighted) a code needs
+	Required int
+	// Weights are the source weights by name for weighted mode; missing sources weigh 1
+	Weights map[string]int
+}
+
+// QuorumOutcome is how a promo code fared against the quorum rule
+type QuorumOutcome struct {
+	Mode QuorumMode
+	// Required is the number of sources or total weight the code needed
+	Required int
+	// Matched is the number of sources holding the code, Weight their total weight
+	Matched int
+	Weight  int
+	// Satisfied reports whether the code meets the quorum
+	Satisfied bool
+}
+
+// Satisfied reports whether a code found in the given sources meets the quorum.
+// sources and found are parallel: found[i] tells whether the code is in sources[i].
+func (q PromoQuorum) Satisfied(sources []string, found []bool) bool {
+	return q.Evaluate(sources, found).Satisfied
+}
+
+// Evaluate applies the quorum to a code found in the given sources, which are
+// parallel to found as for Satisfied
+func (q PromoQuorum) Evaluate(sources []string, found []bool) QuorumOutcome {
+	outcome := QuorumOutcome{Mode: q.Mode, Required: q.Required}
+	for i, ok := range found {
+		if !ok {
+			continue
+		}
+		outcome.Matched++
+		if w, exists := q.Weights[sources[i]]; exists {
+			outcome.Weight += w
+		} else {
+			outcome.Weight++
+		}
+	}
+
+	switch q.Mode {
+	case QuorumAll:
+		outcome.Required = len(sources)
+		outcome.Satisfied = outcome.Matched == len(sources)
+	case QuorumAny:
+		outcome.Required = 1
+		outcome.Satisfied = outcome.Matched > 0
+	case QuorumNOfM:
+		outcome.Satisfied = outcome.Matched >= q.Required
+	case QuorumWeighted:
+		outcome.Satisfied = outcome.Weight >= q.Required
+	}
+	return outcome
+}
@@ -0,0 +1,81 @@
+package services
+
+import "testing"
+
+func TestPromoQuorumEvaluate(t *testing.T) {
+	sources := []string{"a", "b", "c"}
+
+	tests := []struct {
+		name   string
+		quorum PromoQuorum
+		found  []bool
+		want   QuorumOutcome
+	}{
+		{
+			name:   "all satisfied",
+			quorum: PromoQuorum{Mode: QuorumAll},
+			found:  []bool{true, true, true},
+			want:   QuorumOutcome{Mode: QuorumAll, Required: 3, Matched: 3, Weight: 3, Satisfied: true},
+		},
+		{
+			name:   "all missing one source",
+			quorum: PromoQuorum{Mode: QuorumAll},
+			found:  []bool{true, false, true},
+			want:   QuorumOutcome{Mode: QuorumAll, Required: 3, Matched: 2, Weight: 2},
+		},
+		{
+			name:   "any satisfied",
+			quorum: PromoQuorum{Mode: QuorumAny},
+			found:  []bool{false, false, true},
+			want:   QuorumOutcome{Mode: QuorumAny, Required: 1, Matched: 1, Weight: 1, Satisfied: true},
+		},
+		{
+			name:   "any not found",
+			quorum: PromoQuorum{Mode: QuorumAny},
+			found:  []bool{false, false, false},
+			want:   QuorumOutcome{Mode: QuorumAny, Required: 1},
+		},
+		{
+			name:   "n of m satisfied",
+			quorum: PromoQuorum{Mode: QuorumNOfM, Required: 2},
+			found:  []bool{true, false, true},
+			want:   QuorumOutcome{Mode: QuorumNOfM, Required: 2, Matched: 2, Weight: 2, Satisfied: true},
+		},
+		{
+			name:   "n of m short",
+			quorum: PromoQuorum{Mode: QuorumNOfM, Required: 2},
+			found:  []bool{false, false, true},
+			want:   QuorumOutcome{Mode: QuorumNOfM, Required: 2, Matched: 1, Weight: 1},
+		},
+		{
+			name:   "weighted satisfied by one heavy source",
+			quorum: PromoQuorum{Mode: QuorumWeighted, Required: 3, Weights: map[string]int{"a": 3}},
+			found:  []bool{true, false, false},
+			want:   QuorumOutcome{Mode: QuorumWeighted, Required: 3, Matched: 1, Weight: 3, Satisfied: true},
+		},
+		{
+			name:   "weighted missing sources weigh one",
+			quorum: PromoQuorum{Mode: QuorumWeighted, Required: 3, Weights: map[string]int{"a": 3}},
+			found:  []bool{false, true, true},
+			want:   QuorumOutcome{Mode: QuorumWeighted, Required: 3, Matched: 2, Weight: 2},
+		},
+		{
+			name:   "weighted zero weight source",
+			quorum: PromoQuorum{Mode: QuorumWeighted, Required: 1, Weights: map[string]int{"b": 0}},
+			found:  []bool{false, true, false},
+			want:   QuorumOutcome{Mode: QuorumWeighted, Required: 1, Matched: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.quorum.Evaluate(sources, tt.found)
+			if got != tt.want {
+				t.Errorf("Evaluate(%v) = %+v, want %+v", tt.found, got, tt.want)
+			}
+			if satisfied := tt.quorum.Satisfied(sources, tt.found); satisfied != tt.want.Satisfied {
+				t.Errorf("Satisfied(%v) = %v, want %v", tt.found, satisfied, tt.want.Satisfied)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/repository"
+)
+
+// Errors for PromoReloadService
+var (
+	ErrReloadNotSupported    = errors.New("promo repository does not support reloading")
+	ErrPromoReloadInProgress = errors.New("promo reload already in progress")
+)
+
+// PromoReloadStatus describes the running or most recent promo reload
+type PromoReloadStatus struct {
+	Running bool
+	// Trigger is what started the reload, e.g. "signal", "api" or "watcher"
+	Trigger    string
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Duration   time.Duration
+	// Codes is the number of codes loaded per source by the last successful reload
+	Codes map[string]int
+	// Error is the failure of the last reload, empty if it succeeded
+	Error string
+}
+
+// PromoReloadService defines the interface for reloading promo codes while serving
+type PromoReloadService interface {
+	// StartReload rebuilds the promo codes in the background
+	StartReload(trigger string) error
+
+	// Status returns the state of the running or most recent reload
+	Status() PromoReloadStatus
+
+	// WatchSources polls the source files and starts a reload when one has
+	// changed and stayed unchanged for one interval. It returns when ctx is done.
+	WatchSources(ctx context.Context, paths []string, interval time.Duration)
+}
+
+// PromoReloadServiceImpl implements PromoReloadService
+type PromoReloadServiceImpl struct {
+	promoRepo repository.PromoRepository
+	mu        sync.Mutex
+	status    PromoReloadStatus
+}
+
+// NewPromoReloadService creates a new promo reload service
+func NewPromoReloadService(promoRepo repository.PromoRepository) PromoReloadService {
+	return &PromoReloadServiceImpl{
+		promoRepo: promoRepo,
+	}
+}
+
+// StartReload rebuilds the promo codes in the background.
+// Lookups keep using the current codes until the new ones are swapped in.
+func (s *PromoReloadServiceImpl) StartReload(trigger string) error {
+	reloadable, ok := s.promoRepo.(repository.ReloadablePromoRepository)
+	if !ok {
+		return ErrReloadNotSupported
+	}
+	// The codes loading on startup cannot be reloaded until they are in
+	if async, ok := reloadable.(*repository.AsyncPromoRepository); ok && async.Ready() != nil {
+		return ErrPromoUnavailable
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status.Running {
+		return ErrPromoReloadInProgress
+	}
+	s.status.Running = true
+	s.status.Trigger = trigger
+	s.status.StartedAt = time.Now()
+	s.status.FinishedAt = time.Time{}
+	s.status.Duration = 0
+	s.status.Error = ""
+
+	go s.reload(reloadable, trigger)
+	return nil
+}
+
+// reload runs one reload and records its outcome
+func (s *PromoReloadServiceImpl) reload(repo repository.ReloadablePromoRepository, trigger string) {
+	slog.Info("Reloading promo codes", "trigger", trigger)
+	stats, err := repo.Reload()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Running = false
+	s.status.FinishedAt = time.Now()
+	s.status.Duration = s.status.FinishedAt.Sub(s.status.StartedAt)
+	if err != nil {
+		s.status.Error = err.Error()
+		slog.Error("Promo reload failed, keeping the previous codes", "trigger", trigger, "error", err)
+		return
+	}
+	s.status.Codes = stats.Codes
+	slog.Info("Promo reload completed", "trigger", trigger, "duration", stats.Duration)
+}
+
+// Status returns the state of the running or most recent reload
+func (s *PromoReloadServiceImpl) Status() PromoReloadStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := s.status
+	status.Codes = make(map[string]int, len(s.status.Codes))
+	for name, count := range s.status.Codes {
+		status.Codes[name] = count
+	}
+	return status
+}
+
+// fileStamp identifies a version of a file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// stampFiles returns the current stamps of paths; missing files get a zero stamp
+func stampFiles(paths []string) []fileStamp {
+	stamps := make([]fileStamp, len(paths))
+	for i, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			stamps[i] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+// sameStamps reports whether two stamp lists are equal
+func sameStamps(a, b []fileStamp) bool {
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
+
+// WatchSources polls the source files and starts a reload after a change
+func (s *PromoReloadServiceImpl) WatchSources(ctx context.Context, paths []string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	loaded := stampFiles(paths)
+	previous := loaded
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current := stampFiles(paths)
+		// Wait for the files to settle so a half-written drop is not loaded
+		if !sameStamps(current, loaded) && sameStamps(current, previous) {
+			err := s.StartReload("watcher")
+			switch {
+			case err == nil:
+				loaded = current
+			case errors.Is(err, ErrPromoReloadInProgress), errors.Is(err, ErrPromoUnavailable):
+				// Try again on the next tick
+			default:
+				slog.Error("Promo source watcher stopped", "error", err)
+				return
+			}
+		}
+		previous = current
+	}
+}
@@ -1,76 +1,332 @@
 package services
 
 import (
+	"context"
 	"errors"
-	"github.com/jilani-go/glofox/internal/repository"
-	"log"
+	"log/slog"
 	"sync"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/metrics"
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/repository"
+	"github.com/jilani-go/glofox/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Errors for PromoService
 var (
 	ErrInvalidPromoCode = errors.New("invalid promo code")
+	// ErrPromoUnavailable is returned while the promo codes load on startup,
+	// or after loading them failed
+	ErrPromoUnavailable = errors.New("promo codes are not loaded yet")
 )
 
+// PromoRejection is a machine-readable reason for rejecting a promo code
+type PromoRejection string
+
+// Promo code rejection reasons
+const (
+	// RejectionInvalidFormat means the code does not have a valid length
+	RejectionInvalidFormat PromoRejection = "invalid_format"
+	// RejectionNotFound means the code is not in enough promo sources
+	RejectionNotFound PromoRejection = "not_found"
+	// RejectionNotStarted means the code's campaign has not started yet
+	RejectionNotStarted PromoRejection = "not_started"
+	// RejectionExpired means the code's campaign has ended
+	RejectionExpired PromoRejection = "expired"
+	// RejectionNoEligibleItems means the order holds none of the campaign's products
+	RejectionNoEligibleItems PromoRejection = "no_eligible_items"
+	// RejectionBelowMinimum means the order subtotal is below the campaign's minimum
+	RejectionBelowMinimum PromoRejection = "below_minimum_order_value"
+)
+
+// PromoValidation is the outcome of validating a promo code
+type PromoValidation struct {
+	Valid bool
+	// Reason says why an invalid code was rejected
+	Reason PromoRejection
+	// MatchedSources names the promo sources holding the code
+	MatchedSources []string
+	// Quorum is how the code fared against the quorum rule, nil if the
+	// sources were not checked
+	Quorum *QuorumOutcome
+	// Campaign is the campaign the code belongs to, if any
+	Campaign *models.Campaign
+}
+
+// PromoRejectedError is returned when an order's promo code is rejected
+type PromoRejectedError struct {
+	Reason PromoRejection
+}
+
+// Error implements the error interface
+func (e *PromoRejectedError) Error() string {
+	return ErrInvalidPromoCode.Error() + ": " + string(e.Reason)
+}
+
+// Unwrap makes a rejection match ErrInvalidPromoCode
+func (e *PromoRejectedError) Unwrap() error {
+	return ErrInvalidPromoCode
+}
+
 // PromoService defines the interface for promo code business logic
 type PromoService interface {
-	// ValidatePromoCode checks if a promo code is valid in a specific file
-	ValidatePromoCode(code string) (bool, error)
+	// ValidatePromoCode checks if a promo code appears in enough promo sources
+	// and, when it belongs to a campaign, that the campaign is running and the
+	// order meets its constraints. order must be priced, or nil to skip the
+	// order constraints. An empty code is valid.
+	ValidatePromoCode(ctx context.Context, code string, order *models.Order) (*PromoValidation, error)
+
+	// GetPromotion returns the discount attached to a promo code, or nil if it grants none
+	GetPromotion(ctx context.Context, code string) (*models.Promotion, error)
+
+	// GetCampaign returns the campaign a promo code belongs to, or nil if it belongs to none
+	GetCampaign(ctx context.Context, code string) (*models.Campaign, error)
+
+	// RedemptionLimits returns how often a promo code may be redeemed
+	RedemptionLimits(ctx context.Context, code string) (models.RedemptionLimits, error)
 }
 
 // PromoServiceImpl implements PromoService
 type PromoServiceImpl struct {
-	promoRepo repository.PromoRepository
+	promoRepo     repository.PromoRepository
+	promotionRepo repository.PromotionRepository
+	campaignRepo  repository.CampaignRepository
+	productRepo   repository.ProductRepository
+	quorum        PromoQuorum
+	defaultLimits models.RedemptionLimits
+	// lookupTimeout bounds the source lookups of one code, 0 means no limit
+	lookupTimeout time.Duration
 }
 
-// NewPromoService creates a new promo service
-func NewPromoService(promoRepo repository.PromoRepository) PromoService {
+// NewPromoService creates a new promo service that accepts codes meeting the quorum.
+// defaultLimits apply to codes whose promotion sets no redemption limits.
+// lookupTimeout bounds looking a code up in the promo sources.
+func NewPromoService(promoRepo repository.PromoRepository, promotionRepo repository.PromotionRepository, campaignRepo repository.CampaignRepository, productRepo repository.ProductRepository, quorum PromoQuorum, defaultLimits models.RedemptionLimits, lookupTimeout time.Duration) PromoService {
 	return &PromoServiceImpl{
-		promoRepo: promoRepo,
+		promoRepo:     promoRepo,
+		promotionRepo: promotionRepo,
+		campaignRepo:  campaignRepo,
+		productRepo:   productRepo,
+		quorum:        quorum,
+		defaultLimits: defaultLimits,
+		lookupTimeout: lookupTimeout,
 	}
 }
 
-// ValidatePromoCode checks if a promo code is valid in a specific file
-func (s *PromoServiceImpl) ValidatePromoCode(code string) (bool, error) {
+// ValidatePromoCode checks a promo code against the promo sources, then
+// against its campaign's window and order constraints
+func (s *PromoServiceImpl) ValidatePromoCode(ctx context.Context, code string, order *models.Order) (*PromoValidation, error) {
 	if code == "" {
-		return true, nil
+		return &PromoValidation{Valid: true}, nil
+	}
+
+	ctx, span := tracing.Start(ctx, "PromoService.ValidatePromoCode", attribute.Bool("order.priced", order != nil))
+	validation, err := s.validatePromoCode(ctx, code, order)
+	switch {
+	case err != nil:
+		metrics.ObservePromoValidation("error")
+	case validation.Valid:
+		metrics.ObservePromoValidation("valid")
+		span.SetAttributes(attribute.Bool("promo.valid", true))
+	default:
+		metrics.ObservePromoValidation(string(validation.Reason))
+		span.SetAttributes(attribute.Bool("promo.valid", false), attribute.String("promo.rejection", string(validation.Reason)))
+	}
+	tracing.End(span, err)
+	return validation, err
+}
+
+// validatePromoCode validates a non-empty promo code
+func (s *PromoServiceImpl) validatePromoCode(ctx context.Context, code string, order *models.Order) (*PromoValidation, error) {
+	if !models.ValidPromoCodeLength(code) {
+		return &PromoValidation{Reason: RejectionInvalidFormat}, nil
+	}
+
+	matched, quorum, err := s.checkSources(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	validation := &PromoValidation{MatchedSources: matched, Quorum: &quorum}
+	if !quorum.Satisfied {
+		validation.Reason = RejectionNotFound
+		return validation, nil
+	}
+
+	validation.Campaign, err = s.campaignRepo.FindByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if validation.Campaign != nil {
+		validation.Reason, err = s.checkCampaign(ctx, validation.Campaign, order, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
+	validation.Valid = validation.Reason == ""
+	return validation, nil
+}
+
+// checkCampaign returns why a campaign rejects an order at the given time,
+// or an empty reason if it accepts it
+func (s *PromoServiceImpl) checkCampaign(ctx context.Context, campaign *models.Campaign, order *models.Order, at time.Time) (PromoRejection, error) {
+	if !campaign.Started(at) {
+		return RejectionNotStarted, nil
+	}
+	if campaign.Ended(at) {
+		return RejectionExpired, nil
+	}
+	if order == nil {
+		return "", nil
 	}
-	if len(code) < 8 || len(code) > 10 {
-		return false, ErrInvalidPromoCode
+
+	// The minimum order value applies to the campaign's products only
+	eligible := false
+	subtotal := models.NewMoney(0, order.Subtotal.Currency)
+	for _, item := range order.Items {
+		product, err := s.productRepo.FindByID(ctx, item.ProductID)
+		if err != nil {
+			return "", err
+		}
+		if product == nil || !campaign.AppliesTo(*product) {
+			continue
+		}
+		eligible = true
+		if subtotal, err = subtotal.Add(item.LineTotal); err != nil {
+			return "", err
+		}
+	}
+	if !eligible {
+		return RejectionNoEligibleItems, nil
+	}
+	if !campaign.MeetsMinimum(subtotal) {
+		return RejectionBelowMinimum, nil
+	}
+	return "", nil
+}
+
+// checkSources looks a promo code up in every promo source, returning the
+// names of the sources holding it and how it fared against the quorum
+func (s *PromoServiceImpl) checkSources(ctx context.Context, code string) (_ []string, _ QuorumOutcome, err error) {
+	ctx, span := tracing.Start(ctx, "PromoService.checkSources")
+	defer func() { tracing.End(span, err) }()
+
+	if s.lookupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.lookupTimeout)
+		defer cancel()
+	}
+
+	// Check every source against the same version of the codes, even if a reload completes meanwhile
+	promoRepo := s.promoRepo
+	if reloadable, ok := promoRepo.(repository.ReloadablePromoRepository); ok {
+		view, release := reloadable.Acquire()
+		defer release()
+		promoRepo = view
+	}
+
+	sources := promoRepo.Sources()
+	span.SetAttributes(attribute.Int("promo.source_count", len(sources)))
+	var found []bool
+	if lookup, ok := promoRepo.(repository.PromoSourceLookup); ok {
+		// One lookup finds every source holding the code
+		found, err = lookup.LookupSources(ctx, code)
+	} else {
+		found, err = existsInSources(ctx, promoRepo, code, sources)
+	}
+	if errors.Is(err, repository.ErrPromoLoading) || errors.Is(err, repository.ErrPromoFailed) {
+		return nil, QuorumOutcome{}, ErrPromoUnavailable
+	} else if err != nil {
+		return nil, QuorumOutcome{}, err
+	}
+
+	matched := make([]string, 0, len(sources))
+	for i, exists := range found {
+		if exists {
+			matched = append(matched, sources[i])
+		}
+	}
+	slog.DebugContext(ctx, "Promo code looked up", "matched", len(matched), "sources", len(sources))
+
+	outcome := s.quorum.Evaluate(sources, found)
+	metrics.ObservePromoSources(sources, found, string(outcome.Mode), outcome.Satisfied)
+	span.SetAttributes(
+		attribute.Int("promo.matched_count", len(matched)),
+		attribute.String("promo.quorum.mode", string(outcome.Mode)),
+		attribute.Bool("promo.quorum.satisfied", outcome.Satisfied))
+	return matched, outcome, nil
+}
+
+// existsInSources checks a code against each source concurrently, in a
+// span per source. Nothing is looked up once ctx is done.
+func existsInSources(ctx context.Context, promoRepo repository.PromoRepository, code string, sources []string) ([]bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
+
 	type result struct {
 		exists bool
 		err    error
 	}
-	results := make([]result, 3)
+	results := make([]result, len(sources))
 	var wg sync.WaitGroup
 
-	// Launch goroutines to check files concurrently
-	for i, fileNum := range []int{1, 2, 3} {
+	// Launch goroutines to check sources concurrently
+	for i := range results {
 		wg.Add(1)
-		go func(i, fileNum int) {
+		go func(i int) {
 			defer wg.Done()
-			exists, err := s.promoRepo.ExistsInFile(code, fileNum)
+			ctx, span := tracing.Start(ctx, "PromoRepository.ExistsInFile",
+				attribute.Int("promo.source.number", i+1),
+				attribute.String("promo.source.name", sources[i]))
+			exists, err := promoRepo.ExistsInFile(ctx, code, i+1)
+			span.SetAttributes(attribute.Bool("promo.source.matched", exists))
+			tracing.End(span, err)
 			results[i] = result{exists, err}
-		}(i, fileNum)
+		}(i)
 	}
 
 	// Wait for all goroutines to complete
 	wg.Wait()
 
-	promoExistCount := 0
-	for _, r := range results {
+	found := make([]bool, len(results))
+	for i, r := range results {
 		if r.err != nil {
-			return false, r.err //return if we get a single error
-		}
-		if r.exists {
-			promoExistCount++
+			return nil, r.err //return if we get a single error
 		}
+		found[i] = r.exists
 	}
-	log.Println("Promo code exists in ", promoExistCount, " files.")
-	if promoExistCount >= 2 {
-		return true, nil
+	return found, nil
+}
+
+// GetPromotion returns the discount attached to a promo code, or nil if it grants none.
+// It does not check the code against the promo files, see ValidatePromoCode.
+func (s *PromoServiceImpl) GetPromotion(ctx context.Context, code string) (*models.Promotion, error) {
+	if code == "" {
+		return nil, nil
+	}
+	return s.promotionRepo.FindByCode(ctx, code)
+}
+
+// GetCampaign returns the campaign a promo code belongs to, or nil if it belongs to none.
+// It does not check the code against the promo files, see ValidatePromoCode.
+func (s *PromoServiceImpl) GetCampaign(ctx context.Context, code string) (*models.Campaign, error) {
+	if code == "" {
+		return nil, nil
 	}
+	return s.campaignRepo.FindByCode(ctx, code)
+}
 
-	return false, nil
+// RedemptionLimits returns the limits of the code's promotion, or the
+// default limits if it has none
+func (s *PromoServiceImpl) RedemptionLimits(ctx context.Context, code string) (models.RedemptionLimits, error) {
+	promotion, err := s.promotionRepo.FindByCode(ctx, code)
+	if err != nil {
+		return models.RedemptionLimits{}, err
+	}
+	if promotion != nil && !promotion.Limits.IsZero() {
+		return promotion.Limits, nil
+	}
+	return s.defaultLimits, nil
 }
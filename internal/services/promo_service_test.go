@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jilani-go/glofox/internal/models"
+	"github.com/jilani-go/glofox/internal/repository"
+)
+
+func TestCheckCampaign(t *testing.T) {
+	ctx := context.Background()
+	productRepo := repository.NewInMemoryProductRepository(false)
+	for _, product := range []models.Product{
+		{ID: "waffle", Name: "Waffle", Price: models.NewMoney(650, "USD"), Category: "Waffle"},
+		{ID: "tea", Name: "Tea", Price: models.NewMoney(250, "USD"), Category: "Drink"},
+	} {
+		if _, err := productRepo.Create(ctx, &product); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+	}
+	service := &PromoServiceImpl{productRepo: productRepo}
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// order prices the items as the pricing service would
+	order := func(items ...models.OrderItem) *models.Order {
+		subtotal := models.NewMoney(0, "USD")
+		for i := range items {
+			product, _ := productRepo.FindByID(ctx, items[i].ProductID)
+			items[i].LineTotal = models.NewMoney(product.Price.Amount*int64(items[i].Quantity), "USD")
+			subtotal.Amount += items[i].LineTotal.Amount
+		}
+		return &models.Order{Items: items, Subtotal: subtotal}
+	}
+	waffles := models.OrderItem{ProductID: "waffle", Quantity: 2}
+	tea := models.OrderItem{ProductID: "tea", Quantity: 1}
+
+	tests := []struct {
+		name     string
+		campaign models.Campaign
+		order    *models.Order
+		want     PromoRejection
+	}{
+		{name: "no constraints", campaign: models.Campaign{}, order: order(waffles)},
+		{name: "window only without an order", campaign: models.Campaign{Categories: []string{"Drink"}}},
+		{name: "not started", campaign: models.Campaign{StartsAt: now.Add(time.Hour)}, order: order(waffles), want: RejectionNotStarted},
+		{name: "ended", campaign: models.Campaign{EndsAt: now}, order: order(waffles), want: RejectionExpired},
+		{name: "eligible category", campaign: models.Campaign{Categories: []string{"Drink"}}, order: order(waffles, tea)},
+		{name: "eligible product", campaign: models.Campaign{ProductIDs: []string{"waffle"}}, order: order(waffles)},
+		{name: "no eligible items", campaign: models.Campaign{Categories: []string{"Drink"}}, order: order(waffles), want: RejectionNoEligibleItems},
+		{
+			name:     "minimum reached by the campaign's products",
+			campaign: models.Campaign{Categories: []string{"Waffle"}, MinOrderValue: models.NewMoney(1300, "USD")},
+			order:    order(waffles, tea),
+		},
+		{
+			name:     "other products do not count towards the minimum",
+			campaign: models.Campaign{Categories: []string{"Drink"}, MinOrderValue: models.NewMoney(1000, "USD")},
+			order:    order(waffles, tea),
+			want:     RejectionBelowMinimum,
+		},
+		{
+			name:     "minimum in another currency",
+			campaign: models.Campaign{MinOrderValue: models.NewMoney(100, "EUR")},
+			order:    order(waffles),
+			want:     RejectionBelowMinimum,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.checkCampaign(ctx, &tt.campaign, tt.order, now)
+			if err != nil {
+				t.Fatalf("checkCampaign() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("checkCampaign() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// otlpJSONExporter writes every batch of spans as one line of OTLP/JSON, the
+// format of the OpenTelemetry file exporter, which the collector's
+// otlpjsonfile receiver and most trace viewers can read
+type otlpJSONExporter struct {
+	mu sync.Mutex
+	w  io.WriteCloser
+}
+
+// newOTLPJSONExporter returns an exporter writing to w, closed on shutdown
+func newOTLPJSONExporter(w io.WriteCloser) *otlpJSONExporter {
+	return &otlpJSONExporter{w: w}
+}
+
+// ExportSpans writes the spans grouped by resource and instrumentation scope
+func (e *otlpJSONExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	line, err := json.Marshal(toTracesData(spans))
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.w == nil {
+		return nil
+	}
+	_, err = e.w.Write(append(line, '\n'))
+	return err
+}
+
+// Shutdown closes the output; spans exported afterwards are dropped
+func (e *otlpJSONExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.w == nil {
+		return nil
+	}
+	err := e.w.Close()
+	e.w = nil
+	return err
+}
+
+// OTLP/JSON messages, see opentelemetry/proto/trace/v1/trace.proto. IDs are
+// hex encoded and 64-bit integers are strings, as the OTLP/JSON mapping requires.
+type (
+	otlpTracesData struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+		SchemaURL  string           `json:"schemaUrl,omitempty"`
+	}
+
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+
+	otlpScopeSpans struct {
+		Scope     otlpScope  `json:"scope"`
+		Spans     []otlpSpan `json:"spans"`
+		SchemaURL string     `json:"schemaUrl,omitempty"`
+	}
+
+	otlpScope struct {
+		Name    string `json:"name"`
+		Version string `json:"version,omitempty"`
+	}
+
+	otlpSpan struct {
+		TraceID                string         `json:"traceId"`
+		SpanID                 string         `json:"spanId"`
+		TraceState             string         `json:"traceState,omitempty"`
+		ParentSpanID           string         `json:"parentSpanId,omitempty"`
+		Name                   string         `json:"name"`
+		Kind                   int            `json:"kind"`
+		StartTimeUnixNano      string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano        string         `json:"endTimeUnixNano"`
+		Attributes             []otlpKeyValue `json:"attributes,omitempty"`
+		DroppedAttributesCount int            `json:"droppedAttributesCount,omitempty"`
+		Events                 []otlpEvent    `json:"events,omitempty"`
+		DroppedEventsCount     int            `json:"droppedEventsCount,omitempty"`
+		Links                  []otlpLink     `json:"links,omitempty"`
+		DroppedLinksCount      int            `json:"droppedLinksCount,omitempty"`
+		Status                 otlpStatus     `json:"status"`
+	}
+
+	otlpEvent struct {
+		TimeUnixNano string         `json:"timeUnixNano"`
+		Name         string         `json:"name"`
+		Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	}
+
+	otlpLink struct {
+		TraceID    string         `json:"traceId"`
+		SpanID     string         `json:"spanId"`
+		TraceState string         `json:"traceState,omitempty"`
+		Attributes []otlpKeyValue `json:"attributes,omitempty"`
+	}
+
+	otlpStatus struct {
+		Message string `json:"message,omitempty"`
+		Code    int    `json:"code,omitempty"`
+	}
+
+	otlpKeyValue struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+
+	otlpAnyValue struct {
+		StringValue *string         `json:"stringValue,omitempty"`
+		BoolValue   *bool           `json:"boolValue,omitempty"`
+		IntValue    *string         `json:"intValue,omitempty"`
+		DoubleValue *float64        `json:"doubleValue,omitempty"`
+		ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+	}
+
+	otlpArrayValue struct {
+		Values []otlpAnyValue `json:"values"`
+	}
+)
+
+// OTLP status codes, which order Ok and Error unlike the API's codes
+const (
+	otlpStatusOk    = 1
+	otlpStatusError = 2
+)
+
+// toTracesData groups spans by resource and instrumentation scope
+func toTracesData(spans []sdktrace.ReadOnlySpan) otlpTracesData {
+	var data otlpTracesData
+	resources := make(map[*resource.Resource]int)
+	scopes := make(map[*resource.Resource]map[instrumentation.Scope]int)
+	for _, span := range spans {
+		res := span.Resource()
+		ri, ok := resources[res]
+		if !ok {
+			ri = len(data.ResourceSpans)
+			resources[res] = ri
+			scopes[res] = make(map[instrumentation.Scope]int)
+			data.ResourceSpans = append(data.ResourceSpans, otlpResourceSpans{
+				Resource:  otlpResource{Attributes: toKeyValues(res.Attributes())},
+				SchemaURL: res.SchemaURL(),
+			})
+		}
+
+		scope := span.InstrumentationScope()
+		si, ok := scopes[res][scope]
+		if !ok {
+			si = len(data.ResourceSpans[ri].ScopeSpans)
+			scopes[res][scope] = si
+			data.ResourceSpans[ri].ScopeSpans = append(data.ResourceSpans[ri].ScopeSpans, otlpScopeSpans{
+				Scope:     otlpScope{Name: scope.Name, Version: scope.Version},
+				SchemaURL: scope.SchemaURL,
+			})
+		}
+		scopeSpans := &data.ResourceSpans[ri].ScopeSpans[si]
+		scopeSpans.Spans = append(scopeSpans.Spans, toSpan(span))
+	}
+	return data
+}
+
+// toSpan converts a finished span
+func toSpan(span sdktrace.ReadOnlySpan) otlpSpan {
+	sc := span.SpanContext()
+	out := otlpSpan{
+		TraceID:                sc.TraceID().String(),
+		SpanID:                 sc.SpanID().String(),
+		TraceState:             sc.TraceState().String(),
+		Name:                   span.Name(),
+		Kind:                   int(span.SpanKind()),
+		StartTimeUnixNano:      unixNano(span.StartTime()),
+		EndTimeUnixNano:        unixNano(span.EndTime()),
+		Attributes:             toKeyValues(span.Attributes()),
+		DroppedAttributesCount: span.DroppedAttributes(),
+		DroppedEventsCount:     span.DroppedEvents(),
+		DroppedLinksCount:      span.DroppedLinks(),
+	}
+	if parent := span.Parent(); parent.IsValid() {
+		out.ParentSpanID = parent.SpanID().String()
+	}
+	for _, event := range span.Events() {
+		out.Events = append(out.Events, otlpEvent{
+			TimeUnixNano: unixNano(event.Time),
+			Name:         event.Name,
+			Attributes:   toKeyValues(event.Attributes),
+		})
+	}
+	for _, link := range span.Links() {
+		out.Links = append(out.Links, otlpLink{
+			TraceID:    link.SpanContext.TraceID().String(),
+			SpanID:     link.SpanContext.SpanID().String(),
+			TraceState: link.SpanContext.TraceState().String(),
+			Attributes: toKeyValues(link.Attributes),
+		})
+	}
+	switch status := span.Status(); status.Code {
+	case codes.Ok:
+		out.Status = otlpStatus{Code: otlpStatusOk}
+	case codes.Error:
+		out.Status = otlpStatus{Code: otlpStatusError, Message: status.Description}
+	}
+	return out
+}
+
+// unixNano formats a time as nanoseconds since the epoch
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// toKeyValues converts attributes
+func toKeyValues(attrs []attribute.KeyValue) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	out := make([]otlpKeyValue, len(attrs))
+	for i, attr := range attrs {
+		out[i] = otlpKeyValue{Key: string(attr.Key), Value: toAnyValue(attr.Value)}
+	}
+	return out
+}
+
+// toAnyValue converts an attribute value
+func toAnyValue(value attribute.Value) otlpAnyValue {
+	switch value.Type() {
+	case attribute.BOOL:
+		v := value.AsBool()
+		return otlpAnyValue{BoolValue: &v}
+	case attribute.INT64:
+		v := strconv.FormatInt(value.AsInt64(), 10)
+		return otlpAnyValue{IntValue: &v}
+	case attribute.FLOAT64:
+		v := value.AsFloat64()
+		return otlpAnyValue{DoubleValue: &v}
+	case attribute.BOOLSLICE:
+		return toArrayValue(value.AsBoolSlice(), attribute.BoolValue)
+	case attribute.INT64SLICE:
+		return toArrayValue(value.AsInt64Slice(), attribute.Int64Value)
+	case attribute.FLOAT64SLICE:
+		return toArrayValue(value.AsFloat64Slice(), attribute.Float64Value)
+	case attribute.STRINGSLICE:
+		return toArrayValue(value.AsStringSlice(), attribute.StringValue)
+	default:
+		v := value.Emit()
+		return otlpAnyValue{StringValue: &v}
+	}
+}
+
+// toArrayValue converts the elements of a slice attribute
+func toArrayValue[T any](values []T, toValue func(T) attribute.Value) otlpAnyValue {
+	array := &otlpArrayValue{Values: make([]otlpAnyValue, len(values))}
+	for i, v := range values {
+		array.Values[i] = toAnyValue(toValue(v))
+	}
+	return otlpAnyValue{ArrayValue: array}
+}
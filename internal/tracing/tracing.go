@@ -0,0 +1,127 @@
+// Package tracing sets up OpenTelemetry tracing for the service. Spans are
+// started with Start in the handler, service and repository layers and
+// exported as OTLP/JSON lines to stdout or a file, so traces can be read or
+// shipped without running a collector next to the service. Trace context is
+// propagated with the W3C traceparent and tracestate headers.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters
+const (
+	// ExporterNone records no spans; incoming trace context is still passed on
+	ExporterNone = "none"
+	// ExporterStdout writes spans to stdout
+	ExporterStdout = "stdout"
+	// ExporterFile appends spans to a file
+	ExporterFile = "file"
+)
+
+// ServiceName is the service.name resource attribute, unless OTEL_SERVICE_NAME is set
+const ServiceName = "glofox"
+
+// tracerName is the instrumentation scope of the service's spans
+const tracerName = "github.com/jilani-go/glofox"
+
+// Options configure tracing
+type Options struct {
+	// Exporter is ExporterNone, ExporterStdout or ExporterFile
+	Exporter string
+	// FilePath is the file ExporterFile appends to
+	FilePath string
+	// SampleRatio is the share of new traces recorded, from 0 to 1. Traces
+	// started by a caller follow the caller's sampling decision.
+	SampleRatio float64
+}
+
+// Setup installs the W3C trace context propagator and, unless the exporter
+// is ExporterNone, a tracer provider exporting spans. The returned function
+// flushes pending spans and closes the exporter.
+func Setup(ctx context.Context, options Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var w io.WriteCloser
+	switch options.Exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		w = nopCloser{os.Stdout}
+	case ExporterFile:
+		if dir := filepath.Dir(options.FilePath); dir != "" && dir != "." {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return nil, fmt.Errorf("failed to create trace file directory: %w", err)
+			}
+		}
+		file, err := os.OpenFile(options.FilePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		w = file
+	default:
+		return nil, fmt.Errorf("invalid trace exporter %q, must be %q, %q or %q", options.Exporter, ExporterNone, ExporterStdout, ExporterFile)
+	}
+
+	// Later detectors win, so OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES
+	// override the default service name
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(attribute.String("service.name", ServiceName)),
+		resource.WithFromEnv())
+	if err != nil {
+		w.Close()
+		return nil, fmt.Errorf("failed to describe trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(newOTLPJSONExporter(w)),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRatio))))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of the span in ctx, if any
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer starts the span of a request served, continuing the trace of
+// the W3C trace context in header if it carries one
+func StartServer(ctx context.Context, header http.Header, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// End records err, if any, on the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// nopCloser keeps the exporter from closing stdout
+type nopCloser struct {
+	io.Writer
+}
+
+// Close does nothing
+func (nopCloser) Close() error {
+	return nil
+}